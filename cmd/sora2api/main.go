@@ -7,9 +7,12 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 )
 
+// program 当前运行的 TUI 程序，供后台命令推送进度消息
+var program *tea.Program
+
 func main() {
-	p := tea.NewProgram(newAppModel(), tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
+	program = tea.NewProgram(newAppModel(), tea.WithAltScreen())
+	if _, err := program.Run(); err != nil {
 		fmt.Printf("启动失败: %v\n", err)
 		os.Exit(1)
 	}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -774,19 +776,65 @@ func (m taskModel) pollOnce() tea.Cmd {
 	return nil
 }
 
-// downloadToLocal 下载文件到当前目录，返回本地文件路径
+// downloadToLocal 流式下载文件到当前目录，返回本地文件路径
+// 下载中先写入 .part 文件，中断后再次下载同一任务会从已下载部分续传；
+// .part 已完整（上次写完后未及重命名）时直接重命名，与远程文件不符（416）时删除以便下次重新下载
 func downloadToLocal(ctx context.Context, c *sora.Client, fileURL, taskID, defaultExt string) (string, error) {
-	data, err := c.DownloadFile(ctx, fileURL)
+	ext := sora.ExtFromURL(fileURL, defaultExt)
+	absPath, _ := filepath.Abs(taskID + ext)
+	partPath := absPath + ".part"
+
+	f, err := os.OpenFile(partPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
 		return "", err
 	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return "", err
+	}
 
-	ext := sora.ExtFromURL(fileURL, defaultExt)
-	filename := taskID + ext
-	absPath, _ := filepath.Abs(filename)
-
-	if err := os.WriteFile(absPath, data, 0644); err != nil {
+	start := time.Now()
+	var lastReport time.Time
+	_, err = c.DownloadTo(ctx, fileURL, f, sora.DownloadOptions{
+		Offset: info.Size(),
+		OnProgress: func(p sora.DownloadProgress) {
+			if time.Since(lastReport) < 200*time.Millisecond && p.Written != p.Total {
+				return
+			}
+			lastReport = time.Now()
+			reportDownloadProgress(p, start)
+		},
+	})
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	var apiErr *sora.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		_ = os.Remove(partPath)
+		return "", fmt.Errorf("本地未完成的文件与远程不一致，已删除，请重新下载: %w", err)
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := os.Rename(partPath, absPath); err != nil {
 		return "", err
 	}
 	return absPath, nil
 }
+
+// reportDownloadProgress 将下载进度推送到任务页进度条
+func reportDownloadProgress(p sora.DownloadProgress, start time.Time) {
+	if program == nil {
+		return
+	}
+	progress := sora.Progress{
+		Status:  fmt.Sprintf("下载中 %.1f MB", float64(p.Written)/(1<<20)),
+		Elapsed: int(time.Since(start).Seconds()),
+	}
+	if p.Total > 0 {
+		progress.Percent = int(p.Written * 100 / p.Total)
+		progress.Status = fmt.Sprintf("下载中 %.1f/%.1f MB", float64(p.Written)/(1<<20), float64(p.Total)/(1<<20))
+	}
+	program.Send(taskProgressMsg{progress: progress})
+}
@@ -234,6 +234,10 @@ database:
   url: "${db_url}"
   log_level: "warn"
   auto_migrate: true
+
+log:
+  level: "info"
+  format: "text"
 YAML
         chmod 600 "$CONFIG_DIR/config.yaml"
         print_info "已创建配置: $CONFIG_DIR/config.yaml"
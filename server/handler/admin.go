@@ -1,10 +1,7 @@
 package handler
 
 import (
-	"context"
-	"fmt"
 	"net/http"
-	"time"
 
 	"github.com/DouDOU-start/go-sora2api/server/model"
 	"github.com/DouDOU-start/go-sora2api/server/service"
@@ -17,14 +14,18 @@ import (
 type AdminHandler struct {
 	db        *gorm.DB
 	manager   *service.AccountManager
+	scheduler *service.Scheduler
 	taskStore *service.TaskStore
 	settings  *service.SettingsStore
+	sentinels *service.SentinelPool
+	proxies   *service.ProxyPool
+	clients   *service.ClientProvider
 	version   string
 }
 
 // NewAdminHandler 创建管理端点
-func NewAdminHandler(db *gorm.DB, manager *service.AccountManager, taskStore *service.TaskStore, settings *service.SettingsStore, version string) *AdminHandler {
-	return &AdminHandler{db: db, manager: manager, taskStore: taskStore, settings: settings, version: version}
+func NewAdminHandler(db *gorm.DB, manager *service.AccountManager, scheduler *service.Scheduler, taskStore *service.TaskStore, settings *service.SettingsStore, sentinels *service.SentinelPool, proxies *service.ProxyPool, clients *service.ClientProvider, version string) *AdminHandler {
+	return &AdminHandler{db: db, manager: manager, scheduler: scheduler, taskStore: taskStore, settings: settings, sentinels: sentinels, proxies: proxies, clients: clients, version: version}
 }
 
 // GetSettings GET /admin/settings — 获取所有设置
@@ -34,9 +35,23 @@ func (h *AdminHandler) GetSettings(c *gin.Context) {
 	// 返回结构化的设置
 	c.JSON(http.StatusOK, gin.H{
 		model.SettingProxyURL:                 all[model.SettingProxyURL],
+		model.SettingProxyCheckInterval:       all[model.SettingProxyCheckInterval],
 		model.SettingTokenRefreshInterval:     all[model.SettingTokenRefreshInterval],
+		model.SettingTokenRefreshAhead:        all[model.SettingTokenRefreshAhead],
+		model.SettingTokenExpiringSoon:        all[model.SettingTokenExpiringSoon],
 		model.SettingCreditSyncInterval:       all[model.SettingCreditSyncInterval],
 		model.SettingSubscriptionSyncInterval: all[model.SettingSubscriptionSyncInterval],
+		model.SettingSentinelPoolSize:         all[model.SettingSentinelPoolSize],
+		model.SettingSentinelTTL:              all[model.SettingSentinelTTL],
+		model.SettingFingerprintProfiles:      all[model.SettingFingerprintProfiles],
+		model.SettingMaxUploadSize:            all[model.SettingMaxUploadSize],
+		model.SettingMediaConnectTimeout:      all[model.SettingMediaConnectTimeout],
+		model.SettingMediaReadTimeout:         all[model.SettingMediaReadTimeout],
+		model.SettingMaxDownloadSize:          all[model.SettingMaxDownloadSize],
+		model.SettingAccountRateLimit:         all[model.SettingAccountRateLimit],
+		model.SettingAccountRateBurst:         all[model.SettingAccountRateBurst],
+		model.SettingSubmitWorkers:            all[model.SettingSubmitWorkers],
+		model.SettingSubmitMaxAttempts:        all[model.SettingSubmitMaxAttempts],
 	})
 }
 
@@ -51,9 +66,31 @@ func (h *AdminHandler) UpdateSettings(c *gin.Context) {
 	// 只允许更新已知的配置项
 	allowedKeys := map[string]bool{
 		model.SettingProxyURL:                 true,
+		model.SettingProxyCheckInterval:       true,
 		model.SettingTokenRefreshInterval:     true,
+		model.SettingTokenRefreshAhead:        true,
+		model.SettingTokenExpiringSoon:        true,
 		model.SettingCreditSyncInterval:       true,
 		model.SettingSubscriptionSyncInterval: true,
+		model.SettingSentinelPoolSize:         true,
+		model.SettingSentinelTTL:              true,
+		model.SettingFingerprintProfiles:      true,
+		model.SettingMaxUploadSize:            true,
+		model.SettingMediaConnectTimeout:      true,
+		model.SettingMediaReadTimeout:         true,
+		model.SettingMaxDownloadSize:          true,
+		model.SettingAccountRateLimit:         true,
+		model.SettingAccountRateBurst:         true,
+		model.SettingSubmitWorkers:            true,
+		model.SettingSubmitMaxAttempts:        true,
+	}
+
+	// 指纹配置需通过校验才能保存
+	if v, ok := req[model.SettingFingerprintProfiles]; ok {
+		if _, err := sora.ParseFingerprintProfiles([]byte(v)); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 	}
 
 	for key, value := range req {
@@ -66,34 +103,3 @@ func (h *AdminHandler) UpdateSettings(c *gin.Context) {
 	// 返回更新后的设置
 	h.GetSettings(c)
 }
-
-// TestProxy POST /admin/proxy-test — 测试代理连通性
-func (h *AdminHandler) TestProxy(c *gin.Context) {
-	var req struct {
-		ProxyURL string `json:"proxy_url"`
-	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
-		return
-	}
-
-	client, err := sora.New(req.ProxyURL)
-	if err != nil {
-		c.JSON(http.StatusOK, gin.H{"success": false, "error": fmt.Sprintf("创建客户端失败: %v", err)})
-		return
-	}
-
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
-	defer cancel()
-
-	start := time.Now()
-	statusCode, err := client.TestConnectivity(ctx, "https://sora.chatgpt.com")
-	latency := time.Since(start).Milliseconds()
-
-	if err != nil {
-		c.JSON(http.StatusOK, gin.H{"success": false, "error": fmt.Sprintf("连接失败: %v", err), "latency": latency})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"success": true, "status_code": statusCode, "latency": latency})
-}
@@ -12,12 +12,13 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// buildAccountResponse 构建账号响应（填充分组名称、Token 掩码）
+// buildAccountResponse 构建账号响应（填充分组名称、代理名称、Token 掩码、进行中任务数）
 func (h *AdminHandler) buildAccountResponse(acc model.SoraAccount) model.AdminAccountResponse {
 	r := model.AdminAccountResponse{
 		SoraAccount: acc,
 		ATHint:      model.MaskToken(acc.AccessToken),
 		RTHint:      model.MaskToken(acc.RefreshToken),
+		InFlight:    h.scheduler.InFlight(acc.ID),
 	}
 	if acc.GroupID != nil {
 		var group model.SoraAccountGroup
@@ -25,6 +26,12 @@ func (h *AdminHandler) buildAccountResponse(acc model.SoraAccount) model.AdminAc
 			r.GroupName = group.Name
 		}
 	}
+	if acc.ProxyID != nil {
+		var proxy model.SoraProxy
+		if err := h.db.First(&proxy, *acc.ProxyID).Error; err == nil {
+			r.ProxyName = proxy.Name
+		}
+	}
 	return r
 }
 
@@ -102,8 +109,23 @@ func (h *AdminHandler) CreateAccountDirect(c *gin.Context) {
 		}
 	}
 
+	// 验证代理存在（如果指定了代理）
+	if req.ProxyID != nil {
+		var proxy model.SoraProxy
+		if err := h.db.First(&proxy, *req.ProxyID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "指定的代理不存在"})
+			return
+		}
+	}
+
+	if req.MaxConcurrent != nil && *req.MaxConcurrent < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "最大并发任务数不能小于 0"})
+		return
+	}
+
 	account := model.SoraAccount{
 		GroupID:      req.GroupID,
+		ProxyID:      req.ProxyID,
 		Name:         req.Name,
 		AccessToken:  req.AccessToken,
 		RefreshToken: req.RefreshToken,
@@ -113,6 +135,9 @@ func (h *AdminHandler) CreateAccountDirect(c *gin.Context) {
 	if req.Enabled != nil {
 		account.Enabled = *req.Enabled
 	}
+	if req.MaxConcurrent != nil {
+		account.MaxConcurrent = *req.MaxConcurrent
+	}
 
 	// 如果只提供了 RT，先刷新获取 AT
 	if account.AccessToken == "" && account.RefreshToken != "" {
@@ -122,11 +147,12 @@ func (h *AdminHandler) CreateAccountDirect(c *gin.Context) {
 		}
 	}
 
-	// 从 AT 的 JWT payload 中提取邮箱
+	// 从 AT 的 JWT payload 中提取邮箱和过期时间
 	if account.AccessToken != "" {
 		if email := model.ExtractEmailFromJWT(account.AccessToken); email != "" {
 			account.Email = email
 		}
+		account.TokenExpiresAt = model.ExtractExpiryFromJWT(account.AccessToken)
 	}
 
 	if err := h.db.Create(&account).Error; err != nil {
@@ -170,16 +196,36 @@ func (h *AdminHandler) UpdateAccountDirect(c *gin.Context) {
 		}
 	}
 
+	// 验证代理存在（如果指定了代理）
+	if req.ProxyID != nil {
+		var proxy model.SoraProxy
+		if err := h.db.First(&proxy, *req.ProxyID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "指定的代理不存在"})
+			return
+		}
+	}
+
+	if req.MaxConcurrent != nil && *req.MaxConcurrent < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "最大并发任务数不能小于 0"})
+		return
+	}
+
 	if req.Name != "" {
 		account.Name = req.Name
 	}
 	account.GroupID = req.GroupID
+	account.ProxyID = req.ProxyID
 	if req.AccessToken != "" {
 		account.AccessToken = req.AccessToken
-		// 更新 AT 时重新提取邮箱
+		// 更新 AT 时重新提取邮箱和过期时间
 		if email := model.ExtractEmailFromJWT(req.AccessToken); email != "" {
 			account.Email = email
 		}
+		account.TokenExpiresAt = model.ExtractExpiryFromJWT(req.AccessToken)
+		if account.Status == model.AccountStatusTokenExpired || account.Status == model.AccountStatusExpiringSoon {
+			account.Status = model.AccountStatusActive
+			account.LastError = ""
+		}
 	}
 	if req.RefreshToken != "" {
 		account.RefreshToken = req.RefreshToken
@@ -187,6 +233,9 @@ func (h *AdminHandler) UpdateAccountDirect(c *gin.Context) {
 	if req.Enabled != nil {
 		account.Enabled = *req.Enabled
 	}
+	if req.MaxConcurrent != nil {
+		account.MaxConcurrent = *req.MaxConcurrent
+	}
 
 	if err := h.db.Save(&account).Error; err != nil {
 		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("更新账号失败: %v", err)})
@@ -330,9 +379,10 @@ func (h *AdminHandler) BatchImportAccounts(c *gin.Context) {
 			acc.AccessToken = token
 		}
 
-		// 从 AT 提取邮箱
+		// 从 AT 提取邮箱和过期时间
 		if acc.AccessToken != "" {
 			acc.Email = model.ExtractEmailFromJWT(acc.AccessToken)
+			acc.TokenExpiresAt = model.ExtractExpiryFromJWT(acc.AccessToken)
 		}
 		item.Email = acc.Email
 
@@ -348,6 +398,11 @@ func (h *AdminHandler) BatchImportAccounts(c *gin.Context) {
 					existing.AccessToken = acc.AccessToken
 					// 保留原有 RT，不覆盖
 				}
+				existing.TokenExpiresAt = acc.TokenExpiresAt
+				if existing.Status == model.AccountStatusTokenExpired || existing.Status == model.AccountStatusExpiringSoon {
+					existing.Status = model.AccountStatusActive
+					existing.LastError = ""
+				}
 				if req.GroupID != nil {
 					existing.GroupID = req.GroupID
 				}
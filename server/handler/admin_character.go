@@ -6,7 +6,7 @@ import (
 	"strconv"
 
 	"github.com/DouDOU-start/go-sora2api/server/model"
-	"github.com/DouDOU-start/go-sora2api/sora"
+	"github.com/DouDOU-start/go-sora2api/server/service"
 	"github.com/gin-gonic/gin"
 )
 
@@ -136,8 +136,7 @@ func (h *AdminHandler) DeleteCharacterAdmin(c *gin.Context) {
 	if ch.CharacterID != "" {
 		var account model.SoraAccount
 		if err := h.db.Where("id = ?", ch.AccountID).First(&account).Error; err == nil {
-			proxyURL := h.settings.GetProxyURL()
-			client, err := sora.New(proxyURL)
+			client, err := h.clients.Get(&account)
 			if err == nil {
 				_ = client.DeleteCharacter(c.Request.Context(), account.AccessToken, ch.CharacterID)
 			}
@@ -170,8 +169,7 @@ func (h *AdminHandler) ToggleCharacterVisibility(c *gin.Context) {
 		return
 	}
 
-	proxyURL := h.settings.GetProxyURL()
-	client, err := sora.New(proxyURL)
+	client, err := h.clients.Get(&account)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建客户端失败"})
 		return
@@ -200,3 +198,28 @@ func (h *AdminHandler) ToggleCharacterVisibility(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "角色已设为" + label, "is_public": newPublic})
 }
+
+// ImportAccountCharacters POST /admin/accounts/:id/characters/import — 从账号导入角色
+// 与 Sora 上的角色列表对账：新角色导入，已有角色同步信息，上游已删除的标记为 missing
+func (h *AdminHandler) ImportAccountCharacters(c *gin.Context) {
+	accountID, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+
+	var account model.SoraAccount
+	if err := h.db.First(&account, accountID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "账号不存在"})
+		return
+	}
+
+	client, err := h.clients.Get(&account)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建客户端失败"})
+		return
+	}
+
+	result, err := service.SyncAccountCharacters(c.Request.Context(), h.db, client, &account)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("导入角色失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -28,3 +28,13 @@ func (h *AdminHandler) GetDashboard(c *gin.Context) {
 
 	c.JSON(http.StatusOK, stats)
 }
+
+// GetSentinelPoolStats GET /admin/sentinel-pool — Sentinel Token 预热池命中统计
+func (h *AdminHandler) GetSentinelPoolStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.sentinels.Stats())
+}
+
+// GetClientPoolStats GET /admin/client-pool — Sora 客户端复用统计
+func (h *AdminHandler) GetClientPoolStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.clients.Stats())
+}
@@ -39,6 +39,10 @@ func (h *AdminHandler) CreateGroup(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if req.MaxConcurrent != nil && *req.MaxConcurrent < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "最大并发任务数不能小于 0"})
+		return
+	}
 
 	group := model.SoraAccountGroup{
 		Name:        req.Name,
@@ -48,6 +52,9 @@ func (h *AdminHandler) CreateGroup(c *gin.Context) {
 	if req.Enabled != nil {
 		group.Enabled = *req.Enabled
 	}
+	if req.MaxConcurrent != nil {
+		group.MaxConcurrent = *req.MaxConcurrent
+	}
 
 	if err := h.db.Create(&group).Error; err != nil {
 		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("创建账号组失败: %v", err)})
@@ -71,12 +78,19 @@ func (h *AdminHandler) UpdateGroup(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if req.MaxConcurrent != nil && *req.MaxConcurrent < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "最大并发任务数不能小于 0"})
+		return
+	}
 
 	group.Name = req.Name
 	group.Description = req.Description
 	if req.Enabled != nil {
 		group.Enabled = *req.Enabled
 	}
+	if req.MaxConcurrent != nil {
+		group.MaxConcurrent = *req.MaxConcurrent
+	}
 
 	if err := h.db.Save(&group).Error; err != nil {
 		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("更新账号组失败: %v", err)})
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/DouDOU-start/go-sora2api/server/model"
+	"github.com/DouDOU-start/go-sora2api/server/service"
+	"github.com/DouDOU-start/go-sora2api/sora"
+	"github.com/gin-gonic/gin"
+)
+
+// ListProxies GET /admin/proxies
+func (h *AdminHandler) ListProxies(c *gin.Context) {
+	var proxies []model.SoraProxy
+	h.db.Order("id ASC").Find(&proxies)
+
+	type ProxyWithCount struct {
+		model.SoraProxy
+		GroupName    string `json:"group_name,omitempty"`
+		AccountCount int64  `json:"account_count"` // 单独绑定该代理的账号数
+	}
+
+	resp := make([]ProxyWithCount, 0, len(proxies))
+	for _, p := range proxies {
+		item := ProxyWithCount{SoraProxy: p}
+		h.db.Model(&model.SoraAccount{}).Where("proxy_id = ?", p.ID).Count(&item.AccountCount)
+		if p.GroupID != nil {
+			var group model.SoraAccountGroup
+			if err := h.db.First(&group, *p.GroupID).Error; err == nil {
+				item.GroupName = group.Name
+			}
+		}
+		resp = append(resp, item)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// CreateProxy POST /admin/proxies
+func (h *AdminHandler) CreateProxy(c *gin.Context) {
+	var req model.AdminProxyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.validateProxyRequest(c, &req) {
+		return
+	}
+
+	proxy := model.SoraProxy{
+		Name:    req.Name,
+		URL:     req.URL,
+		GroupID: req.GroupID,
+		Enabled: true,
+		Healthy: true,
+	}
+	if req.Enabled != nil {
+		proxy.Enabled = *req.Enabled
+	}
+
+	if err := h.db.Create(&proxy).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("创建代理失败: %v", err)})
+		return
+	}
+
+	// 立即检查一次，避免不可用的代理被分配给账号
+	h.proxies.Check(c.Request.Context(), &proxy)
+	h.proxies.Reload()
+	h.db.First(&proxy, proxy.ID)
+	c.JSON(http.StatusCreated, proxy)
+}
+
+// UpdateProxy PUT /admin/proxies/:id
+func (h *AdminHandler) UpdateProxy(c *gin.Context) {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	var proxy model.SoraProxy
+	if err := h.db.First(&proxy, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "代理不存在"})
+		return
+	}
+
+	var req model.AdminProxyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.validateProxyRequest(c, &req) {
+		return
+	}
+
+	urlChanged := proxy.URL != req.URL
+	proxy.Name = req.Name
+	proxy.URL = req.URL
+	proxy.GroupID = req.GroupID
+	if req.Enabled != nil {
+		proxy.Enabled = *req.Enabled
+	}
+
+	if err := h.db.Save(&proxy).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("更新代理失败: %v", err)})
+		return
+	}
+
+	if urlChanged {
+		h.proxies.Check(c.Request.Context(), &proxy)
+	}
+	h.proxies.Reload()
+	h.db.First(&proxy, proxy.ID)
+	c.JSON(http.StatusOK, proxy)
+}
+
+// DeleteProxy DELETE /admin/proxies/:id
+func (h *AdminHandler) DeleteProxy(c *gin.Context) {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+
+	// 解绑使用该代理的账号（改为按分组/默认代理分配）
+	h.db.Model(&model.SoraAccount{}).Where("proxy_id = ?", id).Update("proxy_id", nil)
+
+	if err := h.db.Delete(&model.SoraProxy{}, id).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.proxies.Reload()
+	c.Status(http.StatusNoContent)
+}
+
+// CheckProxy POST /admin/proxies/:id/test — 立即检查代理并记录结果
+func (h *AdminHandler) CheckProxy(c *gin.Context) {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	var proxy model.SoraProxy
+	if err := h.db.First(&proxy, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "代理不存在"})
+		return
+	}
+
+	result := h.proxies.Check(c.Request.Context(), &proxy)
+	h.proxies.Reload()
+	c.JSON(http.StatusOK, result)
+}
+
+// TestProxyURL POST /admin/proxies/test — 测试任意代理 URL 的连通性（不保存）
+func (h *AdminHandler) TestProxyURL(c *gin.Context) {
+	var req struct {
+		ProxyURL string `json:"proxy_url"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, service.TestProxyURL(c.Request.Context(), req.ProxyURL))
+}
+
+// validateProxyRequest 校验代理 URL 格式和分组，失败时写入 400 响应
+func (h *AdminHandler) validateProxyRequest(c *gin.Context, req *model.AdminProxyRequest) bool {
+	if sora.ParseProxy(req.URL) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "代理格式无效"})
+		return false
+	}
+	if req.GroupID != nil {
+		var group model.SoraAccountGroup
+		if err := h.db.First(&group, *req.GroupID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "指定的账号组不存在"})
+			return false
+		}
+	}
+	return true
+}
@@ -4,10 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"strconv"
 
+	"github.com/DouDOU-start/go-sora2api/server/logging"
 	"github.com/DouDOU-start/go-sora2api/server/model"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -111,18 +111,19 @@ func (h *AdminHandler) DownloadTaskContent(c *gin.Context) {
 
 	switch task.Type {
 	case "image":
-		body, contentLength, contentType, err = h.taskStore.DownloadImage(c.Request.Context(), task)
+		index, _ := strconv.Atoi(c.DefaultQuery("index", "0"))
+		body, contentLength, contentType, err = h.taskStore.DownloadImage(c.Request.Context(), task, index)
 	default:
 		body, contentLength, contentType, err = h.taskStore.DownloadVideo(c.Request.Context(), task)
 	}
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(downloadErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	defer func() {
 		if err := body.Close(); err != nil {
-			log.Printf("[admin_task] close body failed: %v", err)
+			logging.For("admin_task").Warn("关闭媒体流失败", "task_id", task.ID, "err", err)
 		}
 	}()
 
@@ -3,11 +3,11 @@ package handler
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"os/exec"
 	"time"
 
+	"github.com/DouDOU-start/go-sora2api/server/logging"
 	"github.com/gin-gonic/gin"
 )
 
@@ -27,7 +27,7 @@ func (h *AdminHandler) GetVersion(c *gin.Context) {
 	if err == nil {
 		defer func() {
 			if err := resp.Body.Close(); err != nil {
-				log.Printf("[admin_version] close response body failed: %v", err)
+				logging.For("admin_version").Warn("关闭响应体失败", "err", err)
 			}
 		}()
 		var rel githubRelease
@@ -3,103 +3,159 @@ package handler
 import (
 	"context"
 	"fmt"
-	"log"
+	"io"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/DouDOU-start/go-sora2api/server/logging"
 	"github.com/DouDOU-start/go-sora2api/server/model"
 	"github.com/DouDOU-start/go-sora2api/server/service"
 	"github.com/DouDOU-start/go-sora2api/sora"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// maxProfileImageSize 自定义角色头像的大小上限
+const maxProfileImageSize = 10 << 20
+
 // CharacterHandler /v1/characters 角色管理端点
 type CharacterHandler struct {
 	scheduler *service.Scheduler
+	pipeline  *service.CharacterPipeline
+	settings  *service.SettingsStore
 	db        *gorm.DB
 }
 
 // NewCharacterHandler 创建 CharacterHandler
-func NewCharacterHandler(scheduler *service.Scheduler, db *gorm.DB) *CharacterHandler {
-	return &CharacterHandler{scheduler: scheduler, db: db}
+func NewCharacterHandler(scheduler *service.Scheduler, pipeline *service.CharacterPipeline, settings *service.SettingsStore, db *gorm.DB) *CharacterHandler {
+	return &CharacterHandler{scheduler: scheduler, pipeline: pipeline, settings: settings, db: db}
 }
 
-// CreateCharacter POST /v1/characters — 创建角色
+// CreateCharacter POST /v1/characters — 创建角色（支持 JSON 和 multipart/form-data）
 func (h *CharacterHandler) CreateCharacter(c *gin.Context) {
 	var req model.CharacterCreateRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": &model.TaskErrorInfo{Message: fmt.Sprintf("请求参数错误: %v", err)},
-		})
-		return
-	}
-
-	// 从上下文获取分组 ID
-	var groupID *int64
-	if gid, exists := c.Get("api_key_group_id"); exists {
-		id := gid.(int64)
-		groupID = &id
+	var err error
+	if c.ContentType() == binding.MIMEMultipartPOSTForm {
+		err = c.ShouldBindWith(&req, binding.FormMultipart)
+	} else {
+		err = c.ShouldBindJSON(&req)
 	}
-
-	// 选取可用账号
-	account, err := h.scheduler.PickAccount(groupID)
 	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"error": &model.TaskErrorInfo{Message: fmt.Sprintf("无可用账号: %v", err)},
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": &model.TaskErrorInfo{Message: fmt.Sprintf("请求参数错误: %v", err)},
 		})
 		return
 	}
-
-	client, err := sora.New(h.scheduler.GetProxyURL())
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": &model.TaskErrorInfo{Message: fmt.Sprintf("创建 Sora 客户端失败: %v", err)},
+	if req.SampleEnd > 0 && req.SampleEnd <= req.SampleStart {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": &model.TaskErrorInfo{Message: "请求参数错误: sample_end 必须大于 sample_start"},
 		})
 		return
 	}
 
-	ctx := c.Request.Context()
+	groupID := apiKeyGroupID(c)
+	charID := "char_" + uuid.New().String()[:8]
 
-	// 获取视频数据，支持 URL 和 base64 data URI
-	var videoData []byte
-	if sora.IsDataURI(req.VideoURL) {
-		var parseErr error
-		videoData, _, parseErr = sora.ParseDataURI(req.VideoURL)
-		if parseErr != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": &model.TaskErrorInfo{Message: fmt.Sprintf("解析视频 base64 失败: %v", parseErr)},
+	// 选取账号并上传视频，因账号问题（Token 失效、限流）失败时换账号重试
+	// 上传期间计入账号的进行中任务数，每次尝试记录到角色的 attempts
+	var (
+		account      *model.SoraAccount
+		cameoID      string
+		profileImage []byte
+		profileURL   string
+		profileRead  bool
+		exclude      []int64
+		attempts     model.TaskAttempts
+	)
+	defer h.scheduler.Release(charID)
+	maxAttempts := h.settings.GetSubmitMaxAttempts()
+	for {
+		account, _, err = h.scheduler.PickAccountForTask(charID, groupID, nil, exclude)
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": &model.TaskErrorInfo{Message: fmt.Sprintf("无可用账号: %v", err)},
 			})
 			return
 		}
-	} else {
-		var dlErr error
-		videoData, dlErr = client.DownloadFile(ctx, req.VideoURL)
-		if dlErr != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": &model.TaskErrorInfo{Message: fmt.Sprintf("下载角色视频失败: %v", dlErr)},
+
+		client, err := h.scheduler.NewClient(account)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": &model.TaskErrorInfo{Message: fmt.Sprintf("创建 Sora 客户端失败: %v", err)},
 			})
 			return
 		}
-	}
 
-	// 上传视频获取 cameoID
-	cameoID, err := client.UploadCharacterVideo(ctx, account.AccessToken, videoData)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": &model.TaskErrorInfo{Message: fmt.Sprintf("上传角色视频失败: %v", err)},
+		ctx := service.AccountContext(c.Request.Context(), account.ID)
+
+		// 读取自定义头像（在上传视频前校验，避免产生无用的 cameo）
+		if !profileRead {
+			if profileImage, profileURL, err = readProfileImage(ctx, c, client, req.ProfileImage); err != nil {
+				return
+			}
+			profileRead = true
+		}
+
+		// 打开视频数据流，支持 URL 和 base64 data URI
+		src, err := openInputReference(ctx, c, client, req.VideoURL, "角色视频")
+		if err != nil {
+			return
+		}
+
+		// 流式上传视频获取 cameoID
+		cameoID, err = client.UploadCharacterVideoWithOptions(ctx, account.AccessToken, src, sora.CharacterVideoOptions{
+			SampleStart: req.SampleStart,
+			SampleEnd:   req.SampleEnd,
 		})
+		if closeErr := src.Close(); closeErr != nil {
+			logging.For("handler").Warn("关闭角色视频失败", "err", closeErr)
+		}
+		attempt := model.TaskAttempt{AccountID: account.ID, Email: account.Email, At: time.Now()}
+		if err != nil {
+			attempt.Error = err.Error()
+		}
+		attempts = append(attempts, attempt)
+		if err == nil {
+			break
+		}
+
+		h.scheduler.ReportSubmitError(account.ID, err)
+		exclude = append(exclude, account.ID)
+		if service.IsFailoverError(err) && len(exclude) < maxAttempts {
+			logging.For("handler").Warn("上传角色视频失败，换账号重试", "account_id", account.ID, "email", account.Email,
+				"attempt", len(exclude), "err", err)
+			continue
+		}
+		if !respondUploadTooLarge(c, err) {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": &model.TaskErrorInfo{Message: fmt.Sprintf("上传角色视频失败: %v", err)},
+			})
+		}
 		return
 	}
 
-	// 创建内部角色记录
-	charID := "char_" + uuid.New().String()[:8]
+	// 创建内部角色记录（同时保存创建选项，供后台处理及重启后恢复使用）
+	visibility := req.Visibility
+	if visibility == "" {
+		visibility = "public"
+	}
 	character := &model.SoraCharacter{
-		ID:        charID,
-		AccountID: account.ID,
-		CameoID:   cameoID,
-		Status:    model.CharacterStatusProcessing,
+		ID:                   charID,
+		AccountID:            account.ID,
+		CameoID:              cameoID,
+		Status:               model.CharacterStatusProcessing,
+		Step:                 model.CharacterStepUploaded,
+		Username:             req.Username,
+		DisplayName:          req.DisplayName,
+		ProfileURL:           profileURL,
+		ProfileImage:         profileImage,
+		Visibility:           visibility,
+		InstructionSet:       req.InstructionSet,
+		SafetyInstructionSet: req.SafetyInstructionSet,
+		Attempts:             attempts,
 	}
 
 	if err := h.db.Create(character).Error; err != nil {
@@ -109,10 +165,11 @@ func (h *CharacterHandler) CreateCharacter(c *gin.Context) {
 		return
 	}
 
-	// 启动后台异步处理（轮询 → 下载头像 → 上传 → 定稿）
-	go h.processCharacter(character, account, req.Username, req.DisplayName)
+	// 启动后台异步处理（轮询 → 下载头像 → 上传 → 定稿 → 设置可见性）
+	h.pipeline.Start(charID)
 
-	log.Printf("[handler] 角色已创建: %s → Cameo: %s（账号: %s）", charID, cameoID, account.Email)
+	logging.For("handler").Info("角色已创建", "character_id", charID, "cameo_id", cameoID, "account_id", account.ID, "email", account.Email,
+		"attempts", len(attempts))
 
 	c.JSON(http.StatusOK, model.CharacterResponse{
 		ID:        charID,
@@ -121,92 +178,49 @@ func (h *CharacterHandler) CreateCharacter(c *gin.Context) {
 	})
 }
 
-// processCharacter 后台处理角色：轮询 → 下载头像 → 上传 → 定稿
-func (h *CharacterHandler) processCharacter(char *model.SoraCharacter, account *model.SoraAccount, username, displayName string) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
-	defer cancel()
-
-	client, err := sora.New(h.scheduler.GetProxyURL())
-	if err != nil {
-		h.failCharacter(char.ID, fmt.Sprintf("创建 Sora 客户端失败: %v", err))
-		return
-	}
-
-	// 1. 轮询 cameo 状态直到完成
-	cameoStatus, err := client.PollCameoStatus(ctx, account.AccessToken, char.CameoID, 5*time.Second, 8*time.Minute, nil)
-	if err != nil {
-		h.failCharacter(char.ID, fmt.Sprintf("角色处理失败: %v", err))
-		return
-	}
-
-	// 2. 使用推荐的名称（如果请求中未指定）
-	if username == "" {
-		username = cameoStatus.UsernameHint
-	}
-	if displayName == "" {
-		displayName = cameoStatus.DisplayNameHint
-	}
-	// 确保有默认值
-	if username == "" {
-		username = "character_" + char.ID[5:]
-	}
-	if displayName == "" {
-		displayName = "Character"
+// readProfileImage 读取自定义头像：multipart 上传的 profile_image 文件优先，其次为 URL 或 base64 data URI
+// 未提供时返回 nil；失败时写入 400 响应
+func readProfileImage(ctx context.Context, c *gin.Context, client *sora.Client, ref string) ([]byte, string, error) {
+	var src io.ReadCloser
+	if fh, err := c.FormFile("profile_image"); err == nil {
+		if src, err = fh.Open(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": &model.TaskErrorInfo{Message: fmt.Sprintf("读取角色头像失败: %v", err)},
+			})
+			return nil, "", err
+		}
+		ref = ""
+	} else if ref != "" {
+		if src, err = openInputReference(ctx, c, client, ref, "角色头像"); err != nil {
+			return nil, "", err
+		}
+	} else {
+		return nil, "", nil
 	}
+	defer func() {
+		if err := src.Close(); err != nil {
+			logging.For("handler").Warn("关闭角色头像失败", "err", err)
+		}
+	}()
 
-	// 3. 下载推荐头像并上传获取 assetPointer
-	imgData, err := client.DownloadCharacterImage(ctx, cameoStatus.ProfileAssetURL)
-	if err != nil {
-		h.failCharacter(char.ID, fmt.Sprintf("下载角色头像失败: %v", err))
-		return
+	data, err := io.ReadAll(io.LimitReader(src, maxProfileImageSize+1))
+	if err == nil && len(data) > maxProfileImageSize {
+		err = fmt.Errorf("超过大小上限 %d MB", maxProfileImageSize>>20)
 	}
-
-	assetPointer, err := client.UploadCharacterImage(ctx, account.AccessToken, imgData)
-	if err != nil {
-		h.failCharacter(char.ID, fmt.Sprintf("上传角色头像失败: %v", err))
-		return
+	if err == nil && !strings.HasPrefix(http.DetectContentType(data), "image/") {
+		err = fmt.Errorf("不支持的文件类型 %s", http.DetectContentType(data))
 	}
-
-	// 4. 定稿角色
-	characterID, err := client.FinalizeCharacter(ctx, account.AccessToken, char.CameoID, username, displayName, assetPointer)
 	if err != nil {
-		h.failCharacter(char.ID, fmt.Sprintf("定稿角色失败: %v", err))
-		return
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": &model.TaskErrorInfo{Message: fmt.Sprintf("读取角色头像失败: %v", err)},
+		})
+		return nil, "", err
 	}
 
-	// 5. 自动设置角色公开
-	isPublic := false
-	if err := client.SetCharacterPublic(ctx, account.AccessToken, char.CameoID); err != nil {
-		log.Printf("[handler] 角色公开设置失败（非致命）: %s: %v", char.ID, err)
-	} else {
-		isPublic = true
+	if sora.IsDataURI(ref) {
+		ref = ""
 	}
-
-	// 6. 更新数据库（同时保存图片二进制数据，避免依赖外部临时 URL）
-	now := time.Now()
-	h.db.Model(&model.SoraCharacter{}).Where("id = ?", char.ID).Updates(map[string]interface{}{
-		"status":        model.CharacterStatusReady,
-		"character_id":  characterID,
-		"display_name":  displayName,
-		"username":      username,
-		"profile_url":   cameoStatus.ProfileAssetURL,
-		"profile_image": imgData,
-		"is_public":     isPublic,
-		"completed_at":  &now,
-	})
-
-	log.Printf("[handler] 角色处理完成: %s → Character: %s", char.ID, characterID)
-}
-
-// failCharacter 标记角色处理失败
-func (h *CharacterHandler) failCharacter(charID, errMsg string) {
-	now := time.Now()
-	h.db.Model(&model.SoraCharacter{}).Where("id = ?", charID).Updates(map[string]interface{}{
-		"status":        model.CharacterStatusFailed,
-		"error_message": errMsg,
-		"completed_at":  &now,
-	})
-	log.Printf("[handler] 角色处理失败: %s: %s", charID, errMsg)
+	return data, ref, nil
 }
 
 // GetCharacter GET /v1/characters/:id — 查询角色状态
@@ -266,7 +280,7 @@ func (h *CharacterHandler) SetPublic(c *gin.Context) {
 		return
 	}
 
-	client, err := sora.New(h.scheduler.GetProxyURL())
+	client, err := h.scheduler.NewClient(&account)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": &model.TaskErrorInfo{Message: fmt.Sprintf("创建 Sora 客户端失败: %v", err)},
@@ -300,7 +314,7 @@ func (h *CharacterHandler) DeleteCharacter(c *gin.Context) {
 	if char.CharacterID != "" {
 		var account model.SoraAccount
 		if err := h.db.Where("id = ?", char.AccountID).First(&account).Error; err == nil {
-			client, err := sora.New(h.scheduler.GetProxyURL())
+			client, err := h.scheduler.NewClient(&account)
 			if err == nil {
 				_ = client.DeleteCharacter(c.Request.Context(), account.AccessToken, char.CharacterID)
 			}
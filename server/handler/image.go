@@ -3,10 +3,11 @@ package handler
 import (
 	"fmt"
 	"io"
-	"log"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/DouDOU-start/go-sora2api/server/logging"
 	"github.com/DouDOU-start/go-sora2api/server/model"
 	"github.com/DouDOU-start/go-sora2api/server/service"
 	"github.com/DouDOU-start/go-sora2api/sora"
@@ -25,7 +26,7 @@ func NewImageHandler(scheduler *service.Scheduler, taskStore *service.TaskStore)
 	return &ImageHandler{scheduler: scheduler, taskStore: taskStore}
 }
 
-// CreateImageTask POST /v1/images — 创建图片任务
+// CreateImageTask POST /v1/images — 创建图片任务（校验后入队，由后台提交到上游）
 func (h *ImageHandler) CreateImageTask(c *gin.Context) {
 	var req model.ImageSubmitRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -42,115 +43,47 @@ func (h *ImageHandler) CreateImageTask(c *gin.Context) {
 	if req.Height <= 0 {
 		req.Height = 1024
 	}
-
-	// 从上下文获取 API Key 绑定的分组 ID
-	var groupID *int64
-	if gid, exists := c.Get("api_key_group_id"); exists {
-		id := gid.(int64)
-		groupID = &id
-	}
-
-	// 选取可用账号
-	account, err := h.scheduler.PickAccount(groupID)
-	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"error": &model.TaskErrorInfo{Message: fmt.Sprintf("无可用账号: %v", err)},
-		})
-		return
+	if req.N <= 0 {
+		req.N = 1
 	}
-
-	// 创建 Sora 客户端
-	client, err := sora.New(h.scheduler.GetProxyURL())
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": &model.TaskErrorInfo{Message: fmt.Sprintf("创建 Sora 客户端失败: %v", err)},
+	if req.N > sora.MaxImageVariants {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": &model.TaskErrorInfo{Message: fmt.Sprintf("n 取值范围为 1-%d", sora.MaxImageVariants)},
 		})
 		return
 	}
 
-	ctx := c.Request.Context()
-
-	// 生成 Sentinel Token
-	sentinel, err := client.GenerateSentinelToken(ctx, account.AccessToken)
-	if err != nil {
-		h.handleSubmitError(c, account, err)
-		return
-	}
-
-	// 处理图片引用（图生图），支持 URL 和 base64 data URI
-	var mediaID string
-	if req.InputReference != "" {
-		var imgData []byte
-		var ext string
-		if sora.IsDataURI(req.InputReference) {
-			var parseErr error
-			imgData, ext, parseErr = sora.ParseDataURI(req.InputReference)
-			if parseErr != nil {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"error": &model.TaskErrorInfo{Message: fmt.Sprintf("解析参考图片 base64 失败: %v", parseErr)},
-				})
-				return
-			}
-		} else {
-			var dlErr error
-			imgData, dlErr = client.DownloadFile(ctx, req.InputReference)
-			if dlErr != nil {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"error": &model.TaskErrorInfo{Message: fmt.Sprintf("下载参考图片失败: %v", dlErr)},
-				})
-				return
-			}
-			ext = sora.ExtFromURL(req.InputReference, ".png")
-		}
-		mediaID, err = client.UploadImage(ctx, account.AccessToken, imgData, "reference"+ext)
-		if err != nil {
-			h.handleSubmitError(c, account, err)
-			return
-		}
+	sub := model.TaskSubmission{
+		Kind:           model.SubmissionImage,
+		GroupID:        apiKeyGroupID(c),
+		InputReference: req.InputReference,
+		Width:          req.Width,
+		Height:         req.Height,
+		N:              req.N,
 	}
-
-	// 提交图片任务
-	soraTaskID, err := client.CreateImageTaskWithImage(
-		ctx, account.AccessToken, sentinel,
-		req.Prompt, req.Width, req.Height, mediaID,
-	)
-	if err != nil {
-		h.handleSubmitError(c, account, err)
+	if !checkSubmission(c, h.scheduler, &sub) {
 		return
 	}
 
-	// 创建内部任务记录
-	taskID := "task_" + uuid.New().String()[:8]
-	var apiKeyID int64
-	if kid, exists := c.Get("api_key_id"); exists {
-		apiKeyID = kid.(int64)
-	}
 	task := &model.SoraTask{
-		ID:         taskID,
-		SoraTaskID: soraTaskID,
-		AccountID:  account.ID,
-		APIKeyID:   apiKeyID,
+		ID:         "task_" + uuid.New().String()[:8],
+		APIKeyID:   apiKeyID(c),
 		Type:       "image",
 		Model:      "sora-image",
 		Prompt:     req.Prompt,
-		Status:     model.TaskStatusQueued,
+		Submission: sub,
 	}
-
-	if err := h.taskStore.Create(task); err != nil {
+	if err := h.taskStore.Submit(task); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": &model.TaskErrorInfo{Message: fmt.Sprintf("保存任务记录失败: %v", err)},
 		})
 		return
 	}
 
-	// 启动后台轮询（TaskStore 根据 Type="image" 自动走 pollImageTask）
-	h.taskStore.StartPolling(task, account)
-
-	log.Printf("[handler] 图片任务已创建: %s → Sora: %s（账号: %s）",
-		taskID, soraTaskID, account.Email)
+	logging.For("handler").Info("图片任务已入队", "task_id", task.ID, "n", req.N)
 
 	c.JSON(http.StatusOK, model.ImageTaskResponse{
-		ID:        taskID,
+		ID:        task.ID,
 		Object:    "image",
 		Status:    model.TaskStatusQueued,
 		Progress:  0,
@@ -180,6 +113,13 @@ func (h *ImageHandler) GetImageTaskStatus(c *gin.Context) {
 		CreatedAt: task.CreatedAt.Unix(),
 		ImageURL:  task.ImageURL,
 	}
+	for i, img := range task.ImageList() {
+		resp.Images = append(resp.Images, model.ImageVariant{
+			Index:        i,
+			URL:          img.URL,
+			GenerationID: img.GenerationID,
+		})
+	}
 
 	if task.Status == model.TaskStatusFailed && task.ErrorMessage != "" {
 		resp.Error = &model.TaskErrorInfo{Message: task.ErrorMessage}
@@ -188,10 +128,22 @@ func (h *ImageHandler) GetImageTaskStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
-// DownloadImage GET /v1/images/:id/content — 下载图片
+// DownloadImage GET /v1/images/:id/content?index=N — 下载图片（index 默认 0）
 func (h *ImageHandler) DownloadImage(c *gin.Context) {
 	taskID := c.Param("id")
 
+	index := 0
+	if v := c.Query("index"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": &model.TaskErrorInfo{Message: "index 必须为非负整数"},
+			})
+			return
+		}
+		index = n
+	}
+
 	task, err := h.taskStore.Get(taskID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
@@ -207,23 +159,30 @@ func (h *ImageHandler) DownloadImage(c *gin.Context) {
 		return
 	}
 
-	if task.ImageURL == "" {
+	images := task.ImageList()
+	if len(images) == 0 {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": &model.TaskErrorInfo{Message: "图片 URL 为空"},
 		})
 		return
 	}
+	if index >= len(images) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": &model.TaskErrorInfo{Message: fmt.Sprintf("图片索引 %d 不存在（共 %d 张）", index, len(images))},
+		})
+		return
+	}
 
-	body, contentLength, contentType, err := h.taskStore.DownloadImage(c.Request.Context(), task)
+	body, contentLength, contentType, err := h.taskStore.DownloadImage(c.Request.Context(), task, index)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(downloadErrorStatus(err, http.StatusInternalServerError), gin.H{
 			"error": &model.TaskErrorInfo{Message: err.Error()},
 		})
 		return
 	}
 	defer func() {
 		if err := body.Close(); err != nil {
-			log.Printf("[image] close body failed: %v", err)
+			logging.For("image").Warn("关闭媒体流失败", "task_id", task.ID, "err", err)
 		}
 	}()
 
@@ -239,16 +198,3 @@ func (h *ImageHandler) DownloadImage(c *gin.Context) {
 		return err != nil
 	})
 }
-
-// handleSubmitError 处理提交任务时的错误
-func (h *ImageHandler) handleSubmitError(c *gin.Context, account *model.SoraAccount, err error) {
-	errMsg := err.Error()
-	if contains401(errMsg) {
-		h.scheduler.MarkAccountError(account.ID, model.AccountStatusTokenExpired, errMsg)
-	} else if containsRateLimit(errMsg) {
-		h.scheduler.MarkRateLimited(account.ID, 300)
-	}
-	c.JSON(http.StatusInternalServerError, gin.H{
-		"error": &model.TaskErrorInfo{Message: fmt.Sprintf("提交 Sora 任务失败: %v", err)},
-	})
-}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -15,12 +16,13 @@ import (
 type PostHandler struct {
 	scheduler *service.Scheduler
 	taskStore *service.TaskStore
+	sentinels *service.SentinelPool
 	db        *gorm.DB
 }
 
 // NewPostHandler 创建 PostHandler
-func NewPostHandler(scheduler *service.Scheduler, taskStore *service.TaskStore, db *gorm.DB) *PostHandler {
-	return &PostHandler{scheduler: scheduler, taskStore: taskStore, db: db}
+func NewPostHandler(scheduler *service.Scheduler, taskStore *service.TaskStore, sentinels *service.SentinelPool, db *gorm.DB) *PostHandler {
+	return &PostHandler{scheduler: scheduler, taskStore: taskStore, sentinels: sentinels, db: db}
 }
 
 // PublishPost POST /v1/posts — 发布视频帖子
@@ -65,7 +67,7 @@ func (h *PostHandler) PublishPost(c *gin.Context) {
 		return
 	}
 
-	client, err := sora.New(h.scheduler.GetProxyURL())
+	client, err := h.scheduler.NewClient(&account)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": &model.TaskErrorInfo{Message: fmt.Sprintf("创建 Sora 客户端失败: %v", err)},
@@ -73,7 +75,7 @@ func (h *PostHandler) PublishPost(c *gin.Context) {
 		return
 	}
 
-	ctx := c.Request.Context()
+	ctx := service.AccountContext(c.Request.Context(), account.ID)
 
 	// 获取 generationID
 	generationID, err := client.GetGenerationID(ctx, account.AccessToken, task.SoraTaskID)
@@ -84,8 +86,8 @@ func (h *PostHandler) PublishPost(c *gin.Context) {
 		return
 	}
 
-	// 生成 Sentinel Token
-	sentinel, err := client.GenerateSentinelToken(ctx, account.AccessToken)
+	// 获取 Sentinel Token（优先取预热池）
+	sentinel, err := h.sentinels.Get(ctx, client, &account)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": &model.TaskErrorInfo{Message: fmt.Sprintf("生成 Sentinel Token 失败: %v", err)},
@@ -96,6 +98,9 @@ func (h *PostHandler) PublishPost(c *gin.Context) {
 	// 发布
 	postID, err := client.PublishVideo(ctx, account.AccessToken, sentinel, generationID)
 	if err != nil {
+		if errors.Is(err, sora.ErrSentinelRejected) {
+			h.sentinels.Invalidate(account.ID)
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": &model.TaskErrorInfo{Message: fmt.Sprintf("发布视频失败: %v", err)},
 		})
@@ -124,7 +129,7 @@ func (h *PostHandler) DeletePost(c *gin.Context) {
 		return
 	}
 
-	client, err := sora.New(h.scheduler.GetProxyURL())
+	client, err := h.scheduler.NewClient(account)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": &model.TaskErrorInfo{Message: fmt.Sprintf("创建 Sora 客户端失败: %v", err)},
@@ -167,7 +172,7 @@ func (h *PostHandler) GetWatermarkFreeURL(c *gin.Context) {
 		return
 	}
 
-	client, err := sora.New(h.scheduler.GetProxyURL())
+	client, err := h.scheduler.NewClient(account)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": &model.TaskErrorInfo{Message: fmt.Sprintf("创建 Sora 客户端失败: %v", err)},
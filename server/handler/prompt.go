@@ -6,7 +6,6 @@ import (
 
 	"github.com/DouDOU-start/go-sora2api/server/model"
 	"github.com/DouDOU-start/go-sora2api/server/service"
-	"github.com/DouDOU-start/go-sora2api/sora"
 	"github.com/gin-gonic/gin"
 )
 
@@ -53,7 +52,7 @@ func (h *PromptHandler) EnhancePrompt(c *gin.Context) {
 		return
 	}
 
-	client, err := sora.New(h.scheduler.GetProxyURL())
+	client, err := h.scheduler.NewClient(account)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": &model.TaskErrorInfo{Message: fmt.Sprintf("创建 Sora 客户端失败: %v", err)},
@@ -11,15 +11,19 @@ import (
 
 // RouterConfig 路由配置
 type RouterConfig struct {
-	DB        *gorm.DB
-	Scheduler *service.Scheduler
-	TaskStore *service.TaskStore
-	Manager   *service.AccountManager
-	Settings  *service.SettingsStore
-	JWTSecret string
-	AdminUser string
-	AdminPass string
-	Version   string
+	DB         *gorm.DB
+	Scheduler  *service.Scheduler
+	TaskStore  *service.TaskStore
+	Characters *service.CharacterPipeline
+	Manager    *service.AccountManager
+	Settings   *service.SettingsStore
+	Sentinels  *service.SentinelPool
+	Proxies    *service.ProxyPool
+	Clients    *service.ClientProvider
+	JWTSecret  string
+	AdminUser  string
+	AdminPass  string
+	Version    string
 }
 
 // SetupRouter 注册所有路由
@@ -38,9 +42,9 @@ func SetupRouter(cfg *RouterConfig) *gin.Engine {
 	// API 端点（API Key 认证，从数据库查询）
 	videoHandler := NewVideoHandler(cfg.Scheduler, cfg.TaskStore)
 	imageHandler := NewImageHandler(cfg.Scheduler, cfg.TaskStore)
-	characterHandler := NewCharacterHandler(cfg.Scheduler, cfg.DB)
+	characterHandler := NewCharacterHandler(cfg.Scheduler, cfg.Characters, cfg.Settings, cfg.DB)
 	promptHandler := NewPromptHandler(cfg.Scheduler)
-	postHandler := NewPostHandler(cfg.Scheduler, cfg.TaskStore, cfg.DB)
+	postHandler := NewPostHandler(cfg.Scheduler, cfg.TaskStore, cfg.Sentinels, cfg.DB)
 
 	api := r.Group("/v1", APIKeyAuthMiddleware(cfg.DB))
 	{
@@ -50,6 +54,7 @@ func SetupRouter(cfg *RouterConfig) *gin.Engine {
 		api.POST("/videos/storyboard", videoHandler.StoryboardTask)
 		api.GET("/videos/:id", videoHandler.GetTaskStatus)
 		api.GET("/videos/:id/content", videoHandler.DownloadVideo)
+		api.GET("/videos/:id/thumbnail", videoHandler.DownloadThumbnail)
 
 		// 图片任务
 		api.POST("/images", imageHandler.CreateImageTask)
@@ -74,7 +79,7 @@ func SetupRouter(cfg *RouterConfig) *gin.Engine {
 	}
 
 	// 管理端点（JWT 认证）
-	adminHandler := NewAdminHandler(cfg.DB, cfg.Manager, cfg.TaskStore, cfg.Settings, cfg.Version)
+	adminHandler := NewAdminHandler(cfg.DB, cfg.Manager, cfg.Scheduler, cfg.TaskStore, cfg.Settings, cfg.Sentinels, cfg.Proxies, cfg.Clients, cfg.Version)
 	admin := r.Group("/admin", AdminAuthMiddleware(cfg.JWTSecret))
 	{
 		// ── 所有已登录用户（admin + viewer）可访问 ──
@@ -96,11 +101,12 @@ func SetupRouter(cfg *RouterConfig) *gin.Engine {
 		adminOnly := admin.Group("", AdminOnlyMiddleware())
 
 		adminOnly.GET("/dashboard", adminHandler.GetDashboard)
+		adminOnly.GET("/sentinel-pool", adminHandler.GetSentinelPoolStats)
+		adminOnly.GET("/client-pool", adminHandler.GetClientPoolStats)
 
 		// 系统设置
 		adminOnly.GET("/settings", adminHandler.GetSettings)
 		adminOnly.PUT("/settings", adminHandler.UpdateSettings)
-		adminOnly.POST("/proxy-test", adminHandler.TestProxy)
 
 		// 版本管理
 		adminOnly.GET("/version", adminHandler.GetVersion)
@@ -119,6 +125,14 @@ func SetupRouter(cfg *RouterConfig) *gin.Engine {
 		adminOnly.PUT("/groups/:id", adminHandler.UpdateGroup)
 		adminOnly.DELETE("/groups/:id", adminHandler.DeleteGroup)
 
+		// 代理池管理
+		adminOnly.GET("/proxies", adminHandler.ListProxies)
+		adminOnly.POST("/proxies", adminHandler.CreateProxy)
+		adminOnly.POST("/proxies/test", adminHandler.TestProxyURL)
+		adminOnly.PUT("/proxies/:id", adminHandler.UpdateProxy)
+		adminOnly.DELETE("/proxies/:id", adminHandler.DeleteProxy)
+		adminOnly.POST("/proxies/:id/test", adminHandler.CheckProxy)
+
 		// 账号管理
 		adminOnly.GET("/accounts", adminHandler.ListAllAccounts)
 		adminOnly.POST("/accounts/batch", adminHandler.BatchImportAccounts)
@@ -128,6 +142,7 @@ func SetupRouter(cfg *RouterConfig) *gin.Engine {
 		adminOnly.POST("/accounts/:id/refresh", adminHandler.RefreshAccountTokenDirect)
 		adminOnly.GET("/accounts/:id/status", adminHandler.GetAccountStatusDirect)
 		adminOnly.GET("/accounts/:id/tokens", adminHandler.RevealAccountTokens)
+		adminOnly.POST("/accounts/:id/characters/import", adminHandler.ImportAccountCharacters)
 
 		// 角色管理（写操作仅管理员）
 		adminOnly.POST("/characters/:id/visibility", adminHandler.ToggleCharacterVisibility)
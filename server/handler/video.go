@@ -2,13 +2,13 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
-	"strings"
 	"time"
 
+	"github.com/DouDOU-start/go-sora2api/server/logging"
 	"github.com/DouDOU-start/go-sora2api/server/model"
 	"github.com/DouDOU-start/go-sora2api/server/service"
 	"github.com/DouDOU-start/go-sora2api/sora"
@@ -45,38 +45,12 @@ func (h *VideoHandler) CreateTask(c *gin.Context) {
 		return
 	}
 
-	account, client, sentinel, err := h.prepare(c)
-	if err != nil {
-		return // prepare 已写入响应
-	}
-
-	ctx := c.Request.Context()
-
-	prompt := req.Prompt
-	styleID := req.Style
-	if styleID == "" {
-		prompt, styleID = sora.ExtractStyle(req.Prompt)
-	}
-
-	mediaID, err := h.resolveInputReference(ctx, c, client, account, req.InputReference)
-	if err != nil {
-		return
-	}
-
-	log.Printf("[handler] 创建视频: model=%s, orientation=%s, nFrames=%d, size=%s, style=%s, mediaID=%s, 账号=%s",
-		params.Model, params.Orientation, params.NFrames, params.Size, styleID, mediaID, account.Email)
-
-	soraTaskID, err := client.CreateVideoTaskWithOptions(
-		ctx, account.AccessToken, sentinel,
-		prompt, params.Orientation, params.NFrames,
-		params.Model, params.Size, mediaID, styleID,
-	)
-	if err != nil {
-		h.handleSubmitError(c, account, err)
-		return
-	}
-
-	h.finishTask(c, soraTaskID, account, req.Model, req.Prompt, params)
+	h.enqueue(c, req.Model, req.Prompt, params, model.TaskSubmission{
+		Kind:           model.SubmissionVideo,
+		Characters:     req.Characters,
+		Style:          req.Style,
+		InputReference: req.InputReference,
+	})
 }
 
 // RemixTask POST /v1/videos/remix — Remix 视频
@@ -105,29 +79,12 @@ func (h *VideoHandler) RemixTask(c *gin.Context) {
 		return
 	}
 
-	account, _, sentinel, err := h.prepare(c)
-	if err != nil {
-		return
-	}
-
-	ctx := c.Request.Context()
-	prompt := req.Prompt
-	styleID := req.Style
-	if styleID == "" {
-		prompt, styleID = sora.ExtractStyle(req.Prompt)
-	}
-
-	client, _ := sora.New(h.scheduler.GetProxyURL())
-	soraTaskID, err := client.RemixVideo(
-		ctx, account.AccessToken, sentinel,
-		remixTargetID, prompt, params.Orientation, params.NFrames, styleID,
-	)
-	if err != nil {
-		h.handleSubmitError(c, account, err)
-		return
-	}
-
-	h.finishTask(c, soraTaskID, account, req.Model, req.Prompt, params)
+	h.enqueue(c, req.Model, req.Prompt, params, model.TaskSubmission{
+		Kind:          model.SubmissionRemix,
+		Characters:    req.Characters,
+		Style:         req.Style,
+		RemixTargetID: remixTargetID,
+	})
 }
 
 // StoryboardTask POST /v1/videos/storyboard — 分镜视频
@@ -148,144 +105,147 @@ func (h *VideoHandler) StoryboardTask(c *gin.Context) {
 		return
 	}
 
-	account, client, sentinel, err := h.prepare(c)
-	if err != nil {
+	h.enqueue(c, req.Model, req.Prompt, params, model.TaskSubmission{
+		Kind:           model.SubmissionStoryboard,
+		Characters:     req.Characters,
+		Style:          req.Style,
+		InputReference: req.InputReference,
+	})
+}
+
+// enqueue 公共收尾逻辑：校验角色和参考图、保存任务并加入提交队列、返回响应
+// 选账号、Sentinel、上传参考图和提交上游由后台 worker 完成
+func (h *VideoHandler) enqueue(c *gin.Context, modelName, prompt string, params *model.ModelParams, sub model.TaskSubmission) {
+	sub.GroupID = apiKeyGroupID(c)
+	if !checkSubmission(c, h.scheduler, &sub) {
 		return
 	}
 
-	ctx := c.Request.Context()
-	prompt := req.Prompt
-	styleID := req.Style
-	if styleID == "" {
-		prompt, styleID = sora.ExtractStyle(req.Prompt)
+	task := &model.SoraTask{
+		ID:         "task_" + uuid.New().String()[:8],
+		APIKeyID:   apiKeyID(c),
+		Type:       "video",
+		Model:      modelName,
+		Prompt:     prompt,
+		Submission: sub,
 	}
-
-	mediaID, err := h.resolveInputReference(ctx, c, client, account, req.InputReference)
-	if err != nil {
+	if err := h.taskStore.Submit(task); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": &model.TaskErrorInfo{Message: fmt.Sprintf("保存任务记录失败: %v", err)},
+		})
 		return
 	}
 
-	soraTaskID, err := client.CreateStoryboardTask(
-		ctx, account.AccessToken, sentinel,
-		prompt, params.Orientation, params.NFrames, mediaID, styleID,
-	)
-	if err != nil {
-		h.handleSubmitError(c, account, err)
-		return
-	}
+	logging.For("handler").Info("任务已入队", "task_id", task.ID, "kind", sub.Kind, "model", modelName)
 
-	h.finishTask(c, soraTaskID, account, req.Model, req.Prompt, params)
+	c.JSON(http.StatusOK, model.VideoTaskResponse{
+		ID:        task.ID,
+		Object:    "video",
+		Model:     modelName,
+		Status:    model.TaskStatusQueued,
+		Progress:  0,
+		CreatedAt: time.Now().Unix(),
+		Size:      model.SizeToResolution(params.Size, params.Orientation),
+	})
 }
 
-// prepare 公共准备逻辑：获取分组、选账号、创建客户端、生成 sentinel
-func (h *VideoHandler) prepare(c *gin.Context) (account *model.SoraAccount, client *sora.Client, sentinel string, err error) {
-	var groupID *int64
+// apiKeyGroupID 返回请求 API Key 绑定的分组 ID，未绑定时返回 nil
+func apiKeyGroupID(c *gin.Context) *int64 {
 	if gid, exists := c.Get("api_key_group_id"); exists {
 		id := gid.(int64)
-		groupID = &id
+		return &id
 	}
+	return nil
+}
 
-	account, err = h.scheduler.PickAccount(groupID)
-	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"error": &model.TaskErrorInfo{Message: fmt.Sprintf("无可用账号: %v", err)},
-		})
-		return
+// apiKeyID 返回请求使用的 API Key ID，未知时返回 0
+func apiKeyID(c *gin.Context) int64 {
+	if kid, exists := c.Get("api_key_id"); exists {
+		return kid.(int64)
 	}
+	return 0
+}
 
-	client, err = sora.New(h.scheduler.GetProxyURL())
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": &model.TaskErrorInfo{Message: fmt.Sprintf("创建 Sora 客户端失败: %v", err)},
+// checkSubmission 入队前校验角色引用和 base64 参考图，失败时写入响应并返回 false
+func checkSubmission(c *gin.Context, scheduler *service.Scheduler, sub *model.TaskSubmission) bool {
+	if err := scheduler.CheckCharacters(sub.GroupID, sub.Characters); err != nil {
+		status := http.StatusServiceUnavailable
+		msg := fmt.Sprintf("无可用账号: %v", err)
+		switch {
+		case errors.Is(err, service.ErrCharacterNotFound), errors.Is(err, service.ErrCharacterNotReady),
+			errors.Is(err, service.ErrCharacterConflict):
+			status, msg = http.StatusBadRequest, err.Error()
+		case errors.Is(err, service.ErrCharacterForbidden):
+			status, msg = http.StatusForbidden, err.Error()
+		}
+		c.JSON(status, gin.H{
+			"error": &model.TaskErrorInfo{Message: msg},
 		})
-		return
+		return false
 	}
 
-	sentinel, err = client.GenerateSentinelToken(c.Request.Context(), account.AccessToken)
-	if err != nil {
-		h.handleSubmitError(c, account, err)
-		return
+	if sora.IsDataURI(sub.InputReference) {
+		if err := checkDataURI(sub.InputReference); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": &model.TaskErrorInfo{Message: fmt.Sprintf("解析参考图片 base64 失败: %v", err)},
+			})
+			return false
+		}
 	}
-
-	return
+	return true
 }
 
-// resolveInputReference 处理参考图输入（URL 或 base64 data URI），返回 mediaID
-func (h *VideoHandler) resolveInputReference(ctx context.Context, c *gin.Context, client *sora.Client, account *model.SoraAccount, inputRef string) (string, error) {
-	if inputRef == "" {
-		return "", nil
+// checkDataURI 完整解码一遍 data URI（结果丢弃），使无效的 base64 在入队前就被拒绝
+func checkDataURI(ref string) error {
+	r, err := sora.OpenDataURI(ref)
+	if err != nil {
+		return err
 	}
+	_, err = io.Copy(io.Discard, r)
+	return err
+}
 
-	var imgData []byte
-	var ext string
-	if sora.IsDataURI(inputRef) {
-		var parseErr error
-		imgData, ext, parseErr = sora.ParseDataURI(inputRef)
-		if parseErr != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": &model.TaskErrorInfo{Message: fmt.Sprintf("解析参考图片 base64 失败: %v", parseErr)},
-			})
-			return "", parseErr
-		}
-	} else {
-		var dlErr error
-		imgData, dlErr = client.DownloadFile(ctx, inputRef)
-		if dlErr != nil {
+// openInputReference 打开用户提供的素材（URL 或 base64 data URI）数据流，失败时写入 400 响应
+// label 用于错误提示（如“参考图片”“角色视频”），调用方负责关闭返回值
+func openInputReference(ctx context.Context, c *gin.Context, client *sora.Client, ref, label string) (io.ReadCloser, error) {
+	if sora.IsDataURI(ref) {
+		r, err := sora.OpenDataURI(ref)
+		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
-				"error": &model.TaskErrorInfo{Message: fmt.Sprintf("下载参考图片失败: %v", dlErr)},
+				"error": &model.TaskErrorInfo{Message: fmt.Sprintf("解析%s base64 失败: %v", label, err)},
 			})
-			return "", dlErr
+			return nil, err
 		}
-		ext = sora.ExtFromURL(inputRef, ".png")
+		return io.NopCloser(r), nil
 	}
 
-	mediaID, err := client.UploadImage(ctx, account.AccessToken, imgData, "reference"+ext)
+	rc, err := client.OpenMedia(ctx, ref)
 	if err != nil {
-		h.handleSubmitError(c, account, err)
-		return "", err
+		c.JSON(downloadErrorStatus(err, http.StatusBadRequest), gin.H{
+			"error": &model.TaskErrorInfo{Message: fmt.Sprintf("下载%s失败: %v", label, err)},
+		})
+		return nil, err
 	}
-	return mediaID, nil
+	return rc, nil
 }
 
-// finishTask 公共收尾逻辑：创建任务记录、启动轮询、返回响应
-func (h *VideoHandler) finishTask(c *gin.Context, soraTaskID string, account *model.SoraAccount, modelName, prompt string, params *model.ModelParams) {
-	taskID := "task_" + uuid.New().String()[:8]
-	var apiKeyID int64
-	if kid, exists := c.Get("api_key_id"); exists {
-		apiKeyID = kid.(int64)
-	}
-	task := &model.SoraTask{
-		ID:         taskID,
-		SoraTaskID: soraTaskID,
-		AccountID:  account.ID,
-		APIKeyID:   apiKeyID,
-		Type:       "video",
-		Model:      modelName,
-		Prompt:     prompt,
-		Status:     model.TaskStatusQueued,
+// respondUploadTooLarge 上传超过大小上限时返回 413，返回是否已写入响应
+func respondUploadTooLarge(c *gin.Context, err error) bool {
+	if !errors.Is(err, sora.ErrUploadTooLarge) {
+		return false
 	}
+	c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+		"error": &model.TaskErrorInfo{Message: err.Error()},
+	})
+	return true
+}
 
-	if err := h.taskStore.Create(task); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": &model.TaskErrorInfo{Message: fmt.Sprintf("保存任务记录失败: %v", err)},
-		})
-		return
+// downloadErrorStatus 下载媒体失败时的响应状态码：超过下载大小上限返回 413，其余返回 fallback
+func downloadErrorStatus(err error, fallback int) int {
+	if errors.Is(err, sora.ErrMediaTooLarge) {
+		return http.StatusRequestEntityTooLarge
 	}
-
-	h.taskStore.StartPolling(task, account)
-
-	log.Printf("[handler] 任务已创建: %s → Sora: %s（账号: %s, 模型: %s）",
-		taskID, soraTaskID, account.Email, modelName)
-
-	c.JSON(http.StatusOK, model.VideoTaskResponse{
-		ID:        taskID,
-		Object:    "video",
-		Model:     modelName,
-		Status:    model.TaskStatusQueued,
-		Progress:  0,
-		CreatedAt: time.Now().Unix(),
-		Size:      model.SizeToResolution(params.Size, params.Orientation),
-	})
+	return fallback
 }
 
 // GetTaskStatus GET /v1/videos/:id — 查询任务状态
@@ -314,6 +274,10 @@ func (h *VideoHandler) GetTaskStatus(c *gin.Context) {
 		resp.Size = model.SizeToResolution(params.Size, params.Orientation)
 	}
 
+	if task.Status == model.TaskStatusCompleted && !task.Encodings.IsZero() {
+		resp.Encodings = &task.Encodings
+	}
+
 	if task.Status == model.TaskStatusFailed && task.ErrorMessage != "" {
 		resp.Error = &model.TaskErrorInfo{Message: task.ErrorMessage}
 	}
@@ -323,6 +287,16 @@ func (h *VideoHandler) GetTaskStatus(c *gin.Context) {
 
 // DownloadVideo GET /v1/videos/:id/content — 下载视频
 func (h *VideoHandler) DownloadVideo(c *gin.Context) {
+	h.serveMedia(c, h.taskStore.DownloadVideo)
+}
+
+// DownloadThumbnail GET /v1/videos/:id/thumbnail — 下载视频封面缩略图
+func (h *VideoHandler) DownloadThumbnail(c *gin.Context) {
+	h.serveMedia(c, h.taskStore.DownloadThumbnail)
+}
+
+// serveMedia 校验任务已完成后流式转发 download 返回的媒体内容
+func (h *VideoHandler) serveMedia(c *gin.Context, download func(context.Context, *model.SoraTask) (io.ReadCloser, int64, string, error)) {
 	taskID := c.Param("id")
 
 	task, err := h.taskStore.Get(taskID)
@@ -340,16 +314,16 @@ func (h *VideoHandler) DownloadVideo(c *gin.Context) {
 		return
 	}
 
-	body, contentLength, contentType, err := h.taskStore.DownloadVideo(c.Request.Context(), task)
+	body, contentLength, contentType, err := download(c.Request.Context(), task)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(downloadErrorStatus(err, http.StatusInternalServerError), gin.H{
 			"error": &model.TaskErrorInfo{Message: err.Error()},
 		})
 		return
 	}
 	defer func() {
 		if err := body.Close(); err != nil {
-			log.Printf("[video] close body failed: %v", err)
+			logging.For("video").Warn("关闭媒体流失败", "task_id", task.ID, "err", err)
 		}
 	}()
 
@@ -366,26 +340,3 @@ func (h *VideoHandler) DownloadVideo(c *gin.Context) {
 		return err != nil
 	})
 }
-
-// handleSubmitError 处理提交任务时的错误
-func (h *VideoHandler) handleSubmitError(c *gin.Context, account *model.SoraAccount, err error) {
-	errMsg := err.Error()
-	if contains401(errMsg) {
-		h.scheduler.MarkAccountError(account.ID, model.AccountStatusTokenExpired, errMsg)
-	} else if containsRateLimit(errMsg) {
-		h.scheduler.MarkRateLimited(account.ID, 300)
-	}
-	c.JSON(http.StatusInternalServerError, gin.H{
-		"error": &model.TaskErrorInfo{Message: fmt.Sprintf("提交 Sora 任务失败: %v", err)},
-	})
-}
-
-// ---- 公共错误判断函数 ----
-
-func contains401(msg string) bool {
-	return strings.Contains(msg, "401") || strings.Contains(msg, "Unauthorized")
-}
-
-func containsRateLimit(msg string) bool {
-	return strings.Contains(msg, "429") || strings.Contains(msg, "rate limit")
-}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DouDOU-start/go-sora2api/server/service"
+	"github.com/gin-gonic/gin"
+)
+
+func TestCheckDataURI(t *testing.T) {
+	tests := []struct {
+		name string
+		ref  string
+		ok   bool
+	}{
+		{"有效", "data:image/png;base64,iVBORw0KGgo=", true},
+		{"非法字符", "data:image/png;base64,iVBOR!!!w0KGgo=", false},
+		{"长度不完整", "data:image/png;base64,iVBORw0KGgo", false},
+		{"缺少 base64 前缀", "data:image/png,iVBORw0KGgo=", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkDataURI(tt.ref); (err == nil) != tt.ok {
+				t.Errorf("checkDataURI = %v, want ok=%v", err, tt.ok)
+			}
+		})
+	}
+}
+
+// TestInvalidDataURIRejected 参考图 base64 无效时同步返回 400，不入队
+func TestInvalidDataURIRejected(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	scheduler := service.NewScheduler(nil, nil)
+	video := NewVideoHandler(scheduler, nil)
+	image := NewImageHandler(scheduler, nil)
+
+	r := gin.New()
+	r.POST("/v1/videos", video.CreateTask)
+	r.POST("/v1/videos/storyboard", video.StoryboardTask)
+	r.POST("/v1/images", image.CreateImageTask)
+
+	const badRef = "data:image/png;base64,iVBOR!!!w0KGgo="
+	tests := []struct {
+		path string
+		body string
+	}{
+		{"/v1/videos", `{"model":"sora-2-landscape-10s","prompt":"p","input_reference":"` + badRef + `"}`},
+		{"/v1/videos/storyboard", `{"model":"sora-2-landscape-10s","prompt":"[5s]p","input_reference":"` + badRef + `"}`},
+		{"/v1/images", `{"prompt":"p","input_reference":"` + badRef + `"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("状态码 = %d, want 400（body: %s）", w.Code, w.Body.String())
+			}
+			var resp struct {
+				Error struct {
+					Message string `json:"message"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || !strings.Contains(resp.Error.Message, "base64") {
+				t.Errorf("错误信息 = %q (%v)", resp.Error.Message, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package logging
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/DouDOU-start/go-sora2api/server/config"
+)
+
+// Setup 按配置设置 slog 默认 logger（标准库 log 的输出也经由该 logger）
+func Setup(cfg config.LogConfig) {
+	slog.SetDefault(New(os.Stderr, cfg))
+}
+
+// New 创建写入 w 的 logger，级别为 debug/info/warn/error（默认 info），格式为 text/json（默认 text）
+func New(w io.Writer, cfg config.LogConfig) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: ParseLevel(cfg.Level)}
+	if strings.EqualFold(cfg.Format, "json") {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// ParseLevel 解析日志级别，无法识别时返回 info
+func ParseLevel(s string) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// For 返回带组件名的 logger（每次调用读取当前默认 logger，使 Setup 之后的配置生效）
+func For(component string) *slog.Logger {
+	return slog.Default().With("component", component)
+}
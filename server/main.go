@@ -17,6 +17,7 @@ import (
 
 	"github.com/DouDOU-start/go-sora2api/server/config"
 	"github.com/DouDOU-start/go-sora2api/server/handler"
+	"github.com/DouDOU-start/go-sora2api/server/logging"
 	"github.com/DouDOU-start/go-sora2api/server/model"
 	"github.com/DouDOU-start/go-sora2api/server/service"
 	_ "github.com/jackc/pgx/v5/stdlib"
@@ -54,6 +55,15 @@ func main() {
 		cfg.Database.URL = dbURL
 	}
 
+	// 环境变量覆盖日志配置
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		cfg.Log.Level = v
+	}
+	if v := os.Getenv("LOG_FORMAT"); v != "" {
+		cfg.Log.Format = v
+	}
+	logging.Setup(cfg.Log)
+
 	// 初始化数据库
 	db, err := initDB(cfg.Database)
 	if err != nil {
@@ -62,7 +72,7 @@ func main() {
 
 	// 自动迁移（可通过配置 auto_migrate: false 关闭）
 	if cfg.Database.AutoMigrate == nil || *cfg.Database.AutoMigrate {
-		if err := db.AutoMigrate(&model.SoraAccountGroup{}, &model.SoraAccount{}, &model.SoraTask{}, &model.SoraSetting{}, &model.SoraAPIKey{}, &model.SoraCharacter{}); err != nil {
+		if err := db.AutoMigrate(&model.SoraAccountGroup{}, &model.SoraProxy{}, &model.SoraAccount{}, &model.SoraTask{}, &model.SoraSetting{}, &model.SoraAPIKey{}, &model.SoraCharacter{}); err != nil {
 			log.Fatalf("[main] 数据库迁移失败: %v", err)
 		}
 	} else {
@@ -73,9 +83,23 @@ func main() {
 	settings := service.NewSettingsStore(db)
 	defaults := map[string]string{
 		model.SettingProxyURL:                 "",
+		model.SettingProxyCheckInterval:       "5m",
 		model.SettingTokenRefreshInterval:     "30m",
+		model.SettingTokenRefreshAhead:        "1h",
+		model.SettingTokenExpiringSoon:        "24h",
 		model.SettingCreditSyncInterval:       "10m",
 		model.SettingSubscriptionSyncInterval: "6h",
+		model.SettingSentinelPoolSize:         "2",
+		model.SettingSentinelTTL:              "5m",
+		model.SettingFingerprintProfiles:      "",
+		model.SettingMaxUploadSize:            "100",
+		model.SettingMediaConnectTimeout:      "30s",
+		model.SettingMediaReadTimeout:         "60s",
+		model.SettingMaxDownloadSize:          "500",
+		model.SettingAccountRateLimit:         "0",
+		model.SettingAccountRateBurst:         "5",
+		model.SettingSubmitWorkers:            "4",
+		model.SettingSubmitMaxAttempts:        "3",
 	}
 	settings.InitDefaults(defaults)
 
@@ -83,29 +107,42 @@ func main() {
 	migrateAPIKeys(db)
 
 	// 创建组件
-	scheduler := service.NewScheduler(db, settings)
-	manager := service.NewAccountManager(db, settings)
-	taskStore := service.NewTaskStore(db, scheduler)
+	proxies := service.NewProxyPool(db, settings)
+	clients := service.NewClientProvider(settings, proxies)
+	scheduler := service.NewScheduler(db, clients)
+	manager := service.NewAccountManager(db, settings, clients)
+	sentinels := service.NewSentinelPool(db, scheduler, settings)
+	taskStore := service.NewTaskStore(db, scheduler, sentinels, settings)
+	characters := service.NewCharacterPipeline(db, scheduler)
 
 	// 启动后台同步
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	proxies.Start(ctx)
+	clients.Start(ctx)
 	manager.Start(ctx)
+	sentinels.Start(ctx)
 
-	// 恢复进行中的任务
+	// 恢复进行中的任务和角色处理
+	taskStore.Start(ctx)
 	taskStore.RecoverInProgressTasks()
+	characters.RecoverInProgress(ctx)
 
 	// 设置路由
 	r := handler.SetupRouter(&handler.RouterConfig{
-		DB:        db,
-		Scheduler: scheduler,
-		TaskStore: taskStore,
-		Manager:   manager,
-		Settings:  settings,
-		JWTSecret: cfg.Server.JWTSecret,
-		AdminUser: cfg.Server.AdminUser,
-		AdminPass: cfg.Server.AdminPassword,
-		Version:   version,
+		DB:         db,
+		Scheduler:  scheduler,
+		TaskStore:  taskStore,
+		Characters: characters,
+		Manager:    manager,
+		Settings:   settings,
+		Sentinels:  sentinels,
+		Proxies:    proxies,
+		Clients:    clients,
+		JWTSecret:  cfg.Server.JWTSecret,
+		AdminUser:  cfg.Server.AdminUser,
+		AdminPass:  cfg.Server.AdminPassword,
+		Version:    version,
 	})
 
 	// 前端静态文件（SPA）
@@ -1,37 +1,62 @@
 package model
 
-import "time"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
 
 // ---- 数据库模型 ----
 
 // SoraAccountGroup 账号组
 type SoraAccountGroup struct {
-	ID          int64     `json:"id" gorm:"primaryKey;autoIncrement"`
-	Name        string    `json:"name" gorm:"size:128;not null;uniqueIndex"`
-	Description string    `json:"description" gorm:"size:512"`
-	Enabled     bool      `json:"enabled" gorm:"not null;default:true"`
-	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	ID            int64     `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name          string    `json:"name" gorm:"size:128;not null;uniqueIndex"`
+	Description   string    `json:"description" gorm:"size:512"`
+	Enabled       bool      `json:"enabled" gorm:"not null;default:true"`
+	MaxConcurrent int       `json:"max_concurrent" gorm:"not null;default:0"` // 组内账号默认的最大并发任务数，0 为不限制
+	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 }
 
 func (SoraAccountGroup) TableName() string { return "sora_account_groups" }
 
+// SoraProxy 代理（可绑定到账号组，或被账号单独绑定）
+type SoraProxy struct {
+	ID          int64      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name        string     `json:"name" gorm:"size:128;not null"`
+	URL         string     `json:"url" gorm:"size:1024;not null"` // 支持 %s 占位符，按账号替换为固定的 session ID
+	GroupID     *int64     `json:"group_id" gorm:"index"`         // 分组代理供该分组账号使用；为空时为默认代理，未分组账号及分组内无健康代理的账号使用
+	Enabled     bool       `json:"enabled" gorm:"not null;default:true"`
+	Healthy     bool       `json:"healthy" gorm:"not null;default:true"`
+	LatencyMs   int64      `json:"latency_ms" gorm:"default:0"` // 最近一次健康检查的延迟
+	LastError   string     `json:"last_error" gorm:"type:text"`
+	LastCheckAt *time.Time `json:"last_check_at"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (SoraProxy) TableName() string { return "sora_proxies" }
+
 // SoraAccount Sora 账号
 type SoraAccount struct {
 	ID                int64      `json:"id" gorm:"primaryKey;autoIncrement"`
 	GroupID           *int64     `json:"group_id" gorm:"index"`
+	ProxyID           *int64     `json:"proxy_id" gorm:"index"` // 绑定的代理，不健康时改用分组/默认代理，都不可用时仍使用绑定代理（选取顺序见 service.ProxyPool）
 	Name              string     `json:"name" gorm:"size:128"`
-	Email             string     `json:"email" gorm:"size:256"`            // 从 JWT 自动提取
-	AccessToken       string     `json:"-" gorm:"type:text;not null"`      // 不对外暴露
-	RefreshToken      string     `json:"-" gorm:"type:text"`               // 不对外暴露
-	TokenExpiresAt    *time.Time `json:"token_expires_at"`
+	Email             string     `json:"email" gorm:"size:256"`       // 从 JWT 自动提取
+	AccessToken       string     `json:"-" gorm:"type:text;not null"` // 不对外暴露
+	RefreshToken      string     `json:"-" gorm:"type:text"`          // 不对外暴露
+	TokenExpiresAt    *time.Time `json:"token_expires_at"`            // Access Token 过期时间（从 JWT exp 解析）
 	PlanTitle         string     `json:"plan_title" gorm:"size:64"`
 	PlanExpiresAt     *time.Time `json:"plan_expires_at"`
 	RemainingCount    int        `json:"remaining_count" gorm:"default:-1"` // -1=未知
 	RateLimitReached  bool       `json:"rate_limit_reached" gorm:"default:false"`
 	RateLimitResetsAt *time.Time `json:"rate_limit_resets_at"`
 	Enabled           bool       `json:"enabled" gorm:"not null;default:true"`
-	Status            string     `json:"status" gorm:"size:32;default:active"` // active/token_expired/quota_exhausted
+	Status            string     `json:"status" gorm:"size:32;default:active"`     // active/expiring_soon/token_expired/quota_exhausted
+	MaxConcurrent     int        `json:"max_concurrent" gorm:"not null;default:0"` // 最大并发任务数，0 为使用分组默认值
 	LastUsedAt        *time.Time `json:"last_used_at"`
 	LastError         string     `json:"last_error" gorm:"type:text"`
 	LastSyncAt        *time.Time `json:"last_sync_at"`
@@ -41,39 +66,256 @@ type SoraAccount struct {
 
 func (SoraAccount) TableName() string { return "sora_accounts" }
 
+// Schedulable 账号状态是否允许调度（expiring_soon 仍可用，但优先级低于 active）
+func (a *SoraAccount) Schedulable() bool {
+	return a.Status == AccountStatusActive || a.Status == AccountStatusExpiringSoon
+}
+
 // SoraTask 内部任务记录
 type SoraTask struct {
-	ID           string     `json:"id" gorm:"primaryKey;size:64"`
-	SoraTaskID   string     `json:"sora_task_id" gorm:"size:128;not null;index"`
-	AccountID    int64      `json:"account_id" gorm:"not null;index"`
-	APIKeyID     int64      `json:"api_key_id" gorm:"index;default:0"` // 创建该任务的 API Key ID（0 表示未知）
-	Type         string     `json:"type" gorm:"size:32;not null;default:video"` // video/image
-	Model        string     `json:"model" gorm:"size:128"`
-	Prompt       string     `json:"prompt" gorm:"type:text"`
-	Status       string     `json:"status" gorm:"size:32;not null;index;default:queued"` // queued/in_progress/completed/failed
-	Progress     int        `json:"progress" gorm:"default:0"`
-	ErrorMessage string     `json:"error_message,omitempty" gorm:"type:text"`
-	DownloadURL  string     `json:"-" gorm:"size:1024"`                  // 完成后的下载链接（内部使用）
-	ImageURL     string     `json:"image_url,omitempty" gorm:"size:1024"` // 图片任务结果
-	CreatedAt    time.Time  `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt    time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
-	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	ID           string         `json:"id" gorm:"primaryKey;size:64"`
+	SoraTaskID   string         `json:"sora_task_id" gorm:"size:128;not null;index"`
+	AccountID    int64          `json:"account_id" gorm:"not null;index"`
+	APIKeyID     int64          `json:"api_key_id" gorm:"index;default:0"`          // 创建该任务的 API Key ID（0 表示未知）
+	Type         string         `json:"type" gorm:"size:32;not null;default:video"` // video/image
+	Model        string         `json:"model" gorm:"size:128"`
+	Prompt       string         `json:"prompt" gorm:"type:text"`
+	Status       string         `json:"status" gorm:"size:32;not null;index;default:queued"` // queued/submitting/in_progress/completed/failed
+	Progress     int            `json:"progress" gorm:"default:0"`
+	ErrorMessage string         `json:"error_message,omitempty" gorm:"type:text"`
+	DownloadURL  string         `json:"-" gorm:"size:1024"`                   // 完成后的下载链接（内部使用）
+	ImageURL     string         `json:"image_url,omitempty" gorm:"size:1024"` // 图片任务结果（第一张）
+	Images       TaskImages     `json:"images,omitempty" gorm:"type:text"`    // 图片任务全部结果（多张）
+	Encodings    VideoEncodings `json:"-" gorm:"type:text"`                   // 视频任务的编码链接与元数据
+	Submission   TaskSubmission `json:"-" gorm:"type:text"`                   // 排队中任务的提交参数（提交到上游后清空）
+	Attempts     TaskAttempts   `json:"attempts,omitempty" gorm:"type:text"`  // 提交尝试记录（使用的账号与失败原因）
+	CreatedAt    time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	CompletedAt  *time.Time     `json:"completed_at,omitempty"`
 }
 
 func (SoraTask) TableName() string { return "sora_tasks" }
 
+// TaskImage 图片任务的单个生成结果
+type TaskImage struct {
+	URL          string `json:"url"`
+	GenerationID string `json:"generation_id,omitempty"`
+}
+
+// TaskImages 图片结果列表，以 JSON 文本存储
+type TaskImages []TaskImage
+
+// ImageList 返回图片结果列表（兼容只记录了 ImageURL 的旧任务）
+func (t *SoraTask) ImageList() TaskImages {
+	if len(t.Images) > 0 {
+		return t.Images
+	}
+	if t.ImageURL != "" {
+		return TaskImages{{URL: t.ImageURL}}
+	}
+	return nil
+}
+
+// Value 实现 driver.Valuer
+func (t TaskImages) Value() (driver.Value, error) {
+	if len(t) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan 实现 sql.Scanner
+func (t *TaskImages) Scan(src interface{}) error {
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		return fmt.Errorf("TaskImages: 不支持的类型 %T", src)
+	}
+	if len(b) == 0 {
+		*t = nil
+		return nil
+	}
+	return json.Unmarshal(b, t)
+}
+
+// VideoEncodings 视频任务的各编码链接（上游签名链接，会过期）与元数据，以 JSON 文本存储
+type VideoEncodings struct {
+	Source    string  `json:"source,omitempty"`    // 原始清晰度
+	MD        string  `json:"md,omitempty"`        // 中等清晰度
+	LD        string  `json:"ld,omitempty"`        // 低清晰度
+	Thumbnail string  `json:"thumbnail,omitempty"` // 封面缩略图
+	GIF       string  `json:"gif,omitempty"`       // GIF 预览
+	Width     int     `json:"width,omitempty"`
+	Height    int     `json:"height,omitempty"`
+	Duration  float64 `json:"duration,omitempty"` // 时长（秒）
+}
+
+// IsZero 是否未记录任何编码信息
+func (e VideoEncodings) IsZero() bool {
+	return e == VideoEncodings{}
+}
+
+// Value 实现 driver.Valuer
+func (e VideoEncodings) Value() (driver.Value, error) {
+	if e.IsZero() {
+		return "", nil
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan 实现 sql.Scanner
+func (e *VideoEncodings) Scan(src interface{}) error {
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+		*e = VideoEncodings{}
+		return nil
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		return fmt.Errorf("VideoEncodings: 不支持的类型 %T", src)
+	}
+	if len(b) == 0 {
+		*e = VideoEncodings{}
+		return nil
+	}
+	return json.Unmarshal(b, e)
+}
+
+// TaskSubmission 排队任务提交到上游所需的参数，以 JSON 文本存储（模型和提示词取自任务本身）
+type TaskSubmission struct {
+	Kind           string   `json:"kind"`                      // video/remix/storyboard/image
+	GroupID        *int64   `json:"group_id,omitempty"`        // API Key 绑定的分组，选取账号时使用
+	Characters     []string `json:"characters,omitempty"`      // 角色引用
+	Style          string   `json:"style,omitempty"`           // 风格 ID，为空时从提示词中提取
+	InputReference string   `json:"input_reference,omitempty"` // 参考图（URL 或 base64 data URI）
+	RemixTargetID  string   `json:"remix_target_id,omitempty"` // Remix 目标
+	Width          int      `json:"width,omitempty"`           // 图片宽度
+	Height         int      `json:"height,omitempty"`          // 图片高度
+	N              int      `json:"n,omitempty"`               // 图片数量
+}
+
+// 提交类型
+const (
+	SubmissionVideo      = "video"
+	SubmissionRemix      = "remix"
+	SubmissionStoryboard = "storyboard"
+	SubmissionImage      = "image"
+)
+
+// IsZero 是否没有待提交的参数
+func (s TaskSubmission) IsZero() bool {
+	return s.Kind == ""
+}
+
+// Value 实现 driver.Valuer
+func (s TaskSubmission) Value() (driver.Value, error) {
+	if s.IsZero() {
+		return "", nil
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan 实现 sql.Scanner
+func (s *TaskSubmission) Scan(src interface{}) error {
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+		*s = TaskSubmission{}
+		return nil
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		return fmt.Errorf("TaskSubmission: 不支持的类型 %T", src)
+	}
+	if len(b) == 0 {
+		*s = TaskSubmission{}
+		return nil
+	}
+	return json.Unmarshal(b, s)
+}
+
+// TaskAttempt 一次向上游提交任务的尝试
+type TaskAttempt struct {
+	AccountID int64     `json:"account_id"`
+	Email     string    `json:"email,omitempty"`
+	Error     string    `json:"error,omitempty"` // 失败原因，为空表示提交成功
+	At        time.Time `json:"at"`
+}
+
+// TaskAttempts 提交尝试列表，以 JSON 文本存储
+type TaskAttempts []TaskAttempt
+
+// Value 实现 driver.Valuer
+func (a TaskAttempts) Value() (driver.Value, error) {
+	if len(a) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan 实现 sql.Scanner
+func (a *TaskAttempts) Scan(src interface{}) error {
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		return fmt.Errorf("TaskAttempts: 不支持的类型 %T", src)
+	}
+	if len(b) == 0 {
+		*a = nil
+		return nil
+	}
+	return json.Unmarshal(b, a)
+}
+
 // ---- 状态常量 ----
 
 // 账号状态
 const (
 	AccountStatusActive         = "active"
+	AccountStatusExpiringSoon   = "expiring_soon" // 仅有 AT 且即将过期（调度时降低优先级）
 	AccountStatusTokenExpired   = "token_expired"
 	AccountStatusQuotaExhausted = "quota_exhausted"
 )
 
 // 任务状态
 const (
-	TaskStatusQueued     = "queued"
+	TaskStatusQueued     = "queued"     // 已入队，等待选取账号并提交到上游
+	TaskStatusSubmitting = "submitting" // 正在向上游发起创建请求（可能已送达），尚未得到上游任务 ID
 	TaskStatusInProgress = "in_progress"
 	TaskStatusCompleted  = "completed"
 	TaskStatusFailed     = "failed"
@@ -81,20 +323,26 @@ const (
 
 // SoraCharacter 角色记录
 type SoraCharacter struct {
-	ID           string     `json:"id" gorm:"primaryKey;size:64"`                              // 内部 ID: char_xxxxxxxx
-	AccountID    int64      `json:"account_id" gorm:"not null;index"`
-	CameoID      string     `json:"cameo_id" gorm:"size:128;index"`                            // Sora cameo ID
-	CharacterID  string     `json:"character_id" gorm:"size:128;index"`                        // 定稿后的 character ID
-	Status       string     `json:"status" gorm:"size:32;not null;default:processing"`         // processing/ready/failed
-	DisplayName  string     `json:"display_name" gorm:"size:128"`
-	Username     string     `json:"username" gorm:"size:128"`
-	ProfileURL   string     `json:"profile_url" gorm:"size:1024"`
-	ProfileImage []byte     `json:"-" gorm:"type:bytea"`                        // 头像图片二进制数据（不对外暴露）
-	IsPublic     bool       `json:"is_public" gorm:"default:false"`             // 是否公开
-	ErrorMessage string     `json:"error_message,omitempty" gorm:"type:text"`
-	CreatedAt    time.Time  `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt    time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
-	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	ID                   string       `json:"id" gorm:"primaryKey;size:64"` // 内部 ID: char_xxxxxxxx
+	AccountID            int64        `json:"account_id" gorm:"not null;index"`
+	CameoID              string       `json:"cameo_id" gorm:"size:128;index"`                    // Sora cameo ID
+	CharacterID          string       `json:"character_id" gorm:"size:128;index"`                // 定稿后的 character ID
+	Status               string       `json:"status" gorm:"size:32;not null;default:processing"` // processing/ready/failed/missing
+	DisplayName          string       `json:"display_name" gorm:"size:128"`
+	Username             string       `json:"username" gorm:"size:128"`
+	ProfileURL           string       `json:"profile_url" gorm:"size:1024"`
+	ProfileImage         []byte       `json:"-" gorm:"type:bytea"`                 // 头像图片二进制数据（不对外暴露）
+	IsPublic             bool         `json:"is_public" gorm:"default:false"`      // 是否公开
+	Step                 string       `json:"step,omitempty" gorm:"size:32"`       // 处理流程最后完成的步骤，重启后从下一步继续
+	Visibility           string       `json:"visibility,omitempty" gorm:"size:16"` // 创建时指定的初始可见性 public/private
+	InstructionSet       string       `json:"instruction_set,omitempty" gorm:"type:text"`
+	SafetyInstructionSet string       `json:"safety_instruction_set,omitempty" gorm:"type:text"`
+	ProfileAssetPointer  string       `json:"-" gorm:"size:256"` // 头像上传后的 asset pointer（定稿使用）
+	ErrorMessage         string       `json:"error_message,omitempty" gorm:"type:text"`
+	Attempts             TaskAttempts `json:"attempts,omitempty" gorm:"type:text"` // 上传角色视频的尝试记录（使用的账号与失败原因）
+	CreatedAt            time.Time    `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt            time.Time    `json:"updated_at" gorm:"autoUpdateTime"`
+	CompletedAt          *time.Time   `json:"completed_at,omitempty"`
 }
 
 func (SoraCharacter) TableName() string { return "sora_characters" }
@@ -104,6 +352,17 @@ const (
 	CharacterStatusProcessing = "processing"
 	CharacterStatusReady      = "ready"
 	CharacterStatusFailed     = "failed"
+	CharacterStatusMissing    = "missing" // 已在上游删除（导入同步时发现）
+)
+
+// 角色处理步骤（记录在 SoraCharacter.Step，表示最后完成的步骤）
+const (
+	CharacterStepUploaded         = "uploaded"          // 视频已上传，等待 cameo 处理
+	CharacterStepCameoReady       = "cameo_ready"       // cameo 处理完成，已确定名称和推荐头像
+	CharacterStepAvatarDownloaded = "avatar_downloaded" // 头像已保存到 profile_image
+	CharacterStepAvatarUploaded   = "avatar_uploaded"   // 头像已上传，已获得 asset pointer
+	CharacterStepFinalized        = "finalized"         // 已定稿，已获得 character ID
+	CharacterStepVisibilitySet    = "visibility_set"    // 已设置初始可见性（处理完成）
 )
 
 // SoraAPIKey API 密钥（独立管理，可绑定分组）
@@ -132,8 +391,22 @@ func (SoraSetting) TableName() string { return "sora_settings" }
 
 // 配置项 Key 常量
 const (
-	SettingProxyURL                 = "proxy_url"                  // 字符串
-	SettingTokenRefreshInterval     = "token_refresh_interval"     // Duration 字符串
+	SettingProxyURL                 = "proxy_url"                  // 字符串，代理池中无可用代理时使用的全局代理
+	SettingProxyCheckInterval       = "proxy_check_interval"       // Duration 字符串，代理池健康检查间隔
+	SettingTokenRefreshInterval     = "token_refresh_interval"     // Duration 字符串，无法解析过期时间的 Token 的刷新间隔
+	SettingTokenRefreshAhead        = "token_refresh_ahead"        // Duration 字符串，在 AT 过期前多久刷新（另加随机抖动）
+	SettingTokenExpiringSoon        = "token_expiring_soon"        // Duration 字符串，仅有 AT 的账号在过期前多久标记为 expiring_soon
 	SettingCreditSyncInterval       = "credit_sync_interval"       // Duration 字符串
 	SettingSubscriptionSyncInterval = "subscription_sync_interval" // Duration 字符串
+	SettingSentinelPoolSize         = "sentinel_pool_size"         // 整数字符串，每个账号预热的 Sentinel Token 数，0 为关闭
+	SettingSentinelTTL              = "sentinel_ttl"               // Duration 字符串，预热 Token 的有效期
+	SettingFingerprintProfiles      = "fingerprint_profiles"       // JSON 字符串，PoW 指纹 profile 列表，空为内置默认
+	SettingMaxUploadSize            = "max_upload_size"            // 整数字符串，单个上传文件大小上限（MB），0 为不限制
+	SettingMediaConnectTimeout      = "media_connect_timeout"      // Duration 字符串，媒体下载等待响应头的超时
+	SettingMediaReadTimeout         = "media_read_timeout"         // Duration 字符串，媒体下载两次读到数据之间的最大间隔
+	SettingMaxDownloadSize          = "max_download_size"          // 整数字符串，单个媒体文件下载大小上限（MB），0 为不限制
+	SettingAccountRateLimit         = "account_rate_limit"         // 浮点数字符串，单个账号每秒最多请求上游的次数，0 为不限制
+	SettingAccountRateBurst         = "account_rate_burst"         // 整数字符串，单个账号允许的突发请求数
+	SettingSubmitWorkers            = "submit_workers"             // 整数字符串，后台同时向上游提交任务的数量
+	SettingSubmitMaxAttempts        = "submit_max_attempts"        // 整数字符串，提交因账号问题失败时最多尝试的账号数
 )
@@ -4,6 +4,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"strings"
+	"time"
 )
 
 // ---- API 请求/响应 ----
@@ -13,36 +14,40 @@ type VideoSubmitRequest struct {
 	Model          string `json:"model" binding:"required"`
 	Prompt         string `json:"prompt" binding:"required"`
 	Duration       int    `json:"duration"`
-	InputReference string `json:"input_reference,omitempty"` // 图生视频参考图（URL 或 base64 data URI）
-	Style          string `json:"style,omitempty"`           // 视频风格（如 anime, retro 等）
+	InputReference string   `json:"input_reference,omitempty"` // 图生视频参考图（URL 或 base64 data URI）
+	Style          string   `json:"style,omitempty"`           // 视频风格（如 anime, retro 等）
+	Characters     []string `json:"characters,omitempty"`      // 出镜角色（char_xxx 或用户名），任务会路由到角色所属账号
 }
 
 // RemixSubmitRequest Remix 视频请求
 type RemixSubmitRequest struct {
 	Model       string `json:"model" binding:"required"`
 	Prompt      string `json:"prompt" binding:"required"`
-	RemixTarget string `json:"remix_target" binding:"required"` // Sora 分享链接或 s_xxx 格式 ID
-	Style       string `json:"style,omitempty"`
+	RemixTarget string   `json:"remix_target" binding:"required"` // Sora 分享链接或 s_xxx 格式 ID
+	Style       string   `json:"style,omitempty"`
+	Characters  []string `json:"characters,omitempty"` // 出镜角色（char_xxx 或用户名）
 }
 
 // StoryboardSubmitRequest 分镜视频请求
 type StoryboardSubmitRequest struct {
 	Model          string `json:"model" binding:"required"`
 	Prompt         string `json:"prompt" binding:"required"`         // 分镜格式: [5.0s]场景1 [5.0s]场景2
-	InputReference string `json:"input_reference,omitempty"`         // 参考图（URL 或 base64 data URI）
-	Style          string `json:"style,omitempty"`
+	InputReference string   `json:"input_reference,omitempty"`         // 参考图（URL 或 base64 data URI）
+	Style          string   `json:"style,omitempty"`
+	Characters     []string `json:"characters,omitempty"` // 出镜角色（char_xxx 或用户名）
 }
 
 // VideoTaskResponse 任务响应（兼容 K8Ray Creator 的 SoraTaskResponse）
 type VideoTaskResponse struct {
-	ID        string         `json:"id"`
-	Object    string         `json:"object"`
-	Model     string         `json:"model"`
-	Status    string         `json:"status"`
-	Progress  int            `json:"progress"`
-	CreatedAt int64          `json:"created_at"`
-	Size      string         `json:"size,omitempty"`
-	Error     *TaskErrorInfo `json:"error,omitempty"`
+	ID        string          `json:"id"`
+	Object    string          `json:"object"`
+	Model     string          `json:"model"`
+	Status    string          `json:"status"`
+	Progress  int             `json:"progress"`
+	CreatedAt int64           `json:"created_at"`
+	Size      string          `json:"size,omitempty"`
+	Encodings *VideoEncodings `json:"encodings,omitempty"` // 已完成视频的各编码链接与元数据
+	Error     *TaskErrorInfo  `json:"error,omitempty"`
 }
 
 // TaskErrorInfo 任务错误信息
@@ -58,6 +63,7 @@ type ImageSubmitRequest struct {
 	Width          int    `json:"width"`                       // 默认 1792
 	Height         int    `json:"height"`                      // 默认 1024
 	InputReference string `json:"input_reference,omitempty"`   // 图生图参考图（URL 或 base64 data URI）
+	N              int    `json:"n,omitempty"`                 // 生成数量 1-4，默认 1
 }
 
 // ImageTaskResponse 图片任务响应
@@ -70,16 +76,30 @@ type ImageTaskResponse struct {
 	Width     int            `json:"width,omitempty"`
 	Height    int            `json:"height,omitempty"`
 	ImageURL  string         `json:"image_url,omitempty"`
+	Images    []ImageVariant `json:"images,omitempty"`
 	Error     *TaskErrorInfo `json:"error,omitempty"`
 }
 
+// ImageVariant 图片任务的单张结果，index 用于 /v1/images/:id/content?index=N
+type ImageVariant struct {
+	Index        int    `json:"index"`
+	URL          string `json:"url"`
+	GenerationID string `json:"generation_id,omitempty"`
+}
+
 // ---- 角色管理 ----
 
-// CharacterCreateRequest 创建角色请求
+// CharacterCreateRequest 创建角色请求（JSON 或 multipart/form-data）
 type CharacterCreateRequest struct {
-	VideoURL    string `json:"video_url" binding:"required"` // 角色视频（URL 或 base64 data URI）
-	Username    string `json:"username,omitempty"`            // 可选，不传则使用推荐值
-	DisplayName string `json:"display_name,omitempty"`       // 可选，不传则使用推荐值
+	VideoURL             string  `json:"video_url" form:"video_url" binding:"required"`                                   // 角色视频（URL 或 base64 data URI）
+	Username             string  `json:"username,omitempty" form:"username"`                                              // 可选，不传则使用推荐值
+	DisplayName          string  `json:"display_name,omitempty" form:"display_name"`                                      // 可选，不传则使用推荐值
+	SampleStart          float64 `json:"sample_start,omitempty" form:"sample_start" binding:"gte=0"`                      // 采样窗口起点（秒），默认 0
+	SampleEnd            float64 `json:"sample_end,omitempty" form:"sample_end" binding:"gte=0"`                          // 采样窗口终点（秒），默认起点 + 3
+	InstructionSet       string  `json:"instruction_set,omitempty" form:"instruction_set"`                                // 可选，角色指令
+	SafetyInstructionSet string  `json:"safety_instruction_set,omitempty" form:"safety_instruction_set"`                  // 可选，安全指令
+	ProfileImage         string  `json:"profile_image,omitempty" form:"profile_image"`                                    // 可选，自定义头像（URL 或 base64 data URI，multipart 时可直接上传同名文件）
+	Visibility           string  `json:"visibility,omitempty" form:"visibility" binding:"omitempty,oneof=public private"` // 初始可见性，默认 public
 }
 
 // CharacterResponse 角色响应
@@ -137,18 +157,37 @@ type WatermarkFreeResponse struct {
 
 // AdminGroupRequest 账号组创建/编辑请求
 type AdminGroupRequest struct {
-	Name        string `json:"name" binding:"required"`
-	Description string `json:"description"`
-	Enabled     *bool  `json:"enabled"`
+	Name          string `json:"name" binding:"required"`
+	Description   string `json:"description"`
+	Enabled       *bool  `json:"enabled"`
+	MaxConcurrent *int   `json:"max_concurrent"` // 组内账号默认的最大并发任务数，0 为不限制
+}
+
+// AdminProxyRequest 代理创建/编辑请求
+type AdminProxyRequest struct {
+	Name    string `json:"name" binding:"required"`
+	URL     string `json:"url" binding:"required"`
+	GroupID *int64 `json:"group_id"`
+	Enabled *bool  `json:"enabled"`
+}
+
+// ProxyTestResponse 代理连通性测试结果
+type ProxyTestResponse struct {
+	Success    bool   `json:"success"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Latency    int64  `json:"latency"` // 毫秒
+	Error      string `json:"error,omitempty"`
 }
 
 // AdminAccountRequest 账号创建/编辑请求
 type AdminAccountRequest struct {
-	Name         string `json:"name"`
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
-	GroupID      *int64 `json:"group_id"`
-	Enabled      *bool  `json:"enabled"`
+	Name          string `json:"name"`
+	AccessToken   string `json:"access_token"`
+	RefreshToken  string `json:"refresh_token"`
+	GroupID       *int64 `json:"group_id"`
+	ProxyID       *int64 `json:"proxy_id"`
+	Enabled       *bool  `json:"enabled"`
+	MaxConcurrent *int   `json:"max_concurrent"` // 最大并发任务数，0 为使用分组默认值
 }
 
 // AdminAccountResponse 账号响应（含 Token 掩码）
@@ -157,6 +196,8 @@ type AdminAccountResponse struct {
 	ATHint    string `json:"at_hint"`              // AT 掩码
 	RTHint    string `json:"rt_hint"`              // RT 掩码
 	GroupName string `json:"group_name,omitempty"` // 所属分组名称
+	ProxyName string `json:"proxy_name,omitempty"` // 绑定的代理名称
+	InFlight  int    `json:"in_flight"`            // 进行中的任务数
 }
 
 // DashboardStats 概览统计
@@ -175,6 +216,35 @@ type DashboardStats struct {
 	FailedCharacters     int64 `json:"failed_characters"`
 }
 
+// SentinelPoolStats Sentinel Token 预热池统计
+type SentinelPoolStats struct {
+	PoolSize       int     `json:"pool_size"`       // 每个账号的目标预热数量，0 表示已关闭
+	TTL            string  `json:"ttl"`             // 预热 Token 有效期
+	Pooled         int     `json:"pooled"`          // 当前池中可用 Token 总数
+	Accounts       int     `json:"accounts"`        // 持有预热 Token 的账号数
+	Hits           int64   `json:"hits"`            // 命中次数（直接取用预热 Token）
+	Misses         int64   `json:"misses"`          // 未命中次数（回退为内联生成）
+	HitRate        float64 `json:"hit_rate"`        // 命中率（0-1）
+	Refills        int64   `json:"refills"`         // 后台预热成功的 Token 数
+	RefillFailures int64   `json:"refill_failures"` // 后台预热失败次数
+	Expired        int64   `json:"expired"`         // 过期、账号不可用或 AT 变更而丢弃的 Token 数
+	Invalidated    int64   `json:"invalidated"`     // 因上游拒绝而作废的 Token 数
+}
+
+// ClientPoolStats Sora 客户端缓存统计
+type ClientPoolStats struct {
+	Clients        int     `json:"clients"`         // 当前缓存的客户端数
+	IdleTTL        string  `json:"idle_ttl"`        // 空闲释放时长
+	Hits           int64   `json:"hits"`            // 复用已有客户端次数
+	Misses         int64   `json:"misses"`          // 新建客户端次数
+	HitRate        float64 `json:"hit_rate"`        // 复用率（0-1）
+	Evictions      int64   `json:"evictions"`       // 因空闲被释放的客户端数
+	ColdRequests   int64   `json:"cold_requests"`   // 新建客户端的首个请求数（需建连和 TLS 握手）
+	ColdAvgMs      float64 `json:"cold_avg_ms"`     // 首个请求平均耗时（毫秒）
+	ReusedRequests int64   `json:"reused_requests"` // 复用连接的请求数
+	ReusedAvgMs    float64 `json:"reused_avg_ms"`   // 复用请求平均耗时（毫秒）
+}
+
 // AdminAPIKeyRequest API Key 创建/编辑请求
 type AdminAPIKeyRequest struct {
 	Name    string `json:"name" binding:"required"`
@@ -188,6 +258,7 @@ type AdminAPIKeyResponse struct {
 	SoraAPIKey
 	KeyHint   string `json:"key_hint"`             // Key 掩码
 	GroupName string `json:"group_name,omitempty"` // 所属分组名称
+	ProxyName string `json:"proxy_name,omitempty"` // 绑定的代理名称
 }
 
 // AdminBatchImportRequest 批量导入账号请求
@@ -219,22 +290,21 @@ type AdminCharacterResponse struct {
 	AccountEmail string `json:"account_email,omitempty"` // 关联账号邮箱
 }
 
+// CharacterSyncResult 从账号导入角色的同步结果
+type CharacterSyncResult struct {
+	Total    int `json:"total"`    // 上游角色数
+	Imported int `json:"imported"` // 新导入的角色数
+	Updated  int `json:"updated"`  // 已存在并同步了信息的角色数
+	Missing  int `json:"missing"`  // 上游已删除、标记为 missing 的角色数
+}
+
 // ---- 工具函数 ----
 
 // ExtractEmailFromJWT 从 JWT Access Token 的 payload 中提取邮箱
 // JWT 格式为 header.payload.signature，payload 是 base64url 编码的 JSON
 func ExtractEmailFromJWT(token string) string {
-	parts := strings.SplitN(token, ".", 3)
-	if len(parts) < 2 {
-		return ""
-	}
-	// base64url 解码 payload
-	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
-	if err != nil {
-		return ""
-	}
-	var claims map[string]interface{}
-	if err := json.Unmarshal(payload, &claims); err != nil {
+	claims := decodeJWTClaims(token)
+	if claims == nil {
 		return ""
 	}
 	// 尝试常见的邮箱字段名
@@ -254,6 +324,34 @@ func ExtractEmailFromJWT(token string) string {
 	return ""
 }
 
+// ExtractExpiryFromJWT 从 JWT Access Token 的 exp 字段解析过期时间，无法解析时返回 nil
+func ExtractExpiryFromJWT(token string) *time.Time {
+	exp, ok := decodeJWTClaims(token)["exp"].(float64)
+	if !ok || exp <= 0 {
+		return nil
+	}
+	t := time.Unix(int64(exp), 0)
+	return &t
+}
+
+// decodeJWTClaims 解码 JWT payload（不校验签名），格式错误时返回 nil
+func decodeJWTClaims(token string) map[string]interface{} {
+	parts := strings.SplitN(token, ".", 3)
+	if len(parts) < 2 {
+		return nil
+	}
+	// base64url 解码 payload
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil
+	}
+	return claims
+}
+
 // MaskToken 生成 Token 掩码
 func MaskToken(token string) string {
 	if len(token) <= 8 {
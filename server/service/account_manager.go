@@ -2,9 +2,12 @@ package service
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"hash/fnv"
+	"sync"
 	"time"
 
+	"github.com/DouDOU-start/go-sora2api/server/logging"
 	"github.com/DouDOU-start/go-sora2api/server/model"
 	"github.com/DouDOU-start/go-sora2api/sora"
 	"gorm.io/gorm"
@@ -12,25 +15,44 @@ import (
 
 // SyncConfig 同步配置
 type SyncConfig struct {
-	TokenRefreshInterval     time.Duration
+	TokenRefreshInterval     time.Duration // 无法解析过期时间的 Token 的刷新间隔
+	TokenRefreshAhead        time.Duration // 在 AT 过期前多久刷新
+	TokenExpiringSoon        time.Duration // 仅有 AT 的账号在过期前多久标记为 expiring_soon
 	CreditSyncInterval       time.Duration
 	SubscriptionSyncInterval time.Duration
 }
 
+// Token 过期检查相关参数
+const (
+	tokenCheckInterval     = time.Minute     // 检查周期
+	tokenRefreshRetryDelay = 5 * time.Minute // 自动刷新失败后的重试间隔
+)
+
 // AccountManager 账号池管理（Token 刷新、配额同步、订阅同步）
 type AccountManager struct {
 	db       *gorm.DB
 	settings *SettingsStore
+	clients  *ClientProvider
+
+	mu          sync.Mutex
+	refreshedAt map[int64]time.Time // 最近一次刷新成功的时间（用于无法解析过期时间的 Token）
+	retryAt     map[int64]time.Time // 刷新失败后下次允许重试的时间
 }
 
 // NewAccountManager 创建账号管理器
-func NewAccountManager(db *gorm.DB, settings *SettingsStore) *AccountManager {
-	return &AccountManager{db: db, settings: settings}
+func NewAccountManager(db *gorm.DB, settings *SettingsStore, clients *ClientProvider) *AccountManager {
+	return &AccountManager{
+		db:          db,
+		settings:    settings,
+		clients:     clients,
+		refreshedAt: make(map[int64]time.Time),
+		retryAt:     make(map[int64]time.Time),
+	}
 }
 
-// proxyURL 获取当前代理 URL
-func (am *AccountManager) proxyURL() string {
-	return am.settings.GetProxyURL()
+// newClient 返回账号使用的 Sora 客户端（由客户端缓存复用）
+func (am *AccountManager) newClient(account *model.SoraAccount) (*sora.Client, error) {
+	return am.clients.Get(account)
 }
 
 // Start 启动后台同步任务
@@ -39,56 +61,133 @@ func (am *AccountManager) Start(ctx context.Context) {
 	go am.tokenRefreshLoop(ctx)
 	go am.creditSyncLoop(ctx)
 	go am.subscriptionSyncLoop(ctx)
-	log.Printf("[account_manager] 后台同步已启动（Token: %v, 配额: %v, 订阅: %v）",
-		cfg.TokenRefreshInterval, cfg.CreditSyncInterval, cfg.SubscriptionSyncInterval)
+	logging.For("account_manager").Info("后台同步已启动",
+		"token_refresh_ahead", cfg.TokenRefreshAhead, "credit_sync_interval", cfg.CreditSyncInterval, "subscription_sync_interval", cfg.SubscriptionSyncInterval)
 }
 
-// tokenRefreshLoop Token 刷新循环
+// tokenRefreshLoop Token 刷新循环：定期检查各账号的过期时间，逐个刷新临近过期的账号
 func (am *AccountManager) tokenRefreshLoop(ctx context.Context) {
-	am.refreshAllTokens(ctx)
+	ticker := time.NewTicker(tokenCheckInterval)
+	defer ticker.Stop()
 
 	for {
-		cfg := am.settings.GetSyncConfig()
+		am.checkTokens(ctx)
 		select {
 		case <-ctx.Done():
 			return
-		case <-time.After(cfg.TokenRefreshInterval):
-			am.refreshAllTokens(ctx)
+		case <-ticker.C:
 		}
 	}
 }
 
-// refreshAllTokens 刷新所有有 RT 的账号
-func (am *AccountManager) refreshAllTokens(ctx context.Context) {
+// checkTokens 检查所有启用账号的 Token
+// 有 RT 的账号在临近过期时刷新；仅有 AT 的账号无法续期，临近过期标记 expiring_soon，过期后标记 token_expired
+func (am *AccountManager) checkTokens(ctx context.Context) {
 	var accounts []model.SoraAccount
-	if err := am.db.Where("enabled = ? AND refresh_token != ''", true).Find(&accounts).Error; err != nil {
-		log.Printf("[token_refresh] 查询账号失败: %v", err)
-		return
-	}
-
-	if len(accounts) == 0 {
+	if err := am.db.Where("enabled = ?", true).Find(&accounts).Error; err != nil {
+		logging.For("token_refresh").Error("查询账号失败", "err", err)
 		return
 	}
 
-	log.Printf("[token_refresh] 开始刷新 %d 个账号的 Token", len(accounts))
+	cfg := am.settings.GetSyncConfig()
+	now := time.Now()
 	success, fail := 0, 0
 
 	for i := range accounts {
 		acc := &accounts[i]
-		if err := am.refreshAccountToken(ctx, acc); err != nil {
+
+		// 补全历史账号的过期时间
+		if acc.TokenExpiresAt == nil {
+			if exp := model.ExtractExpiryFromJWT(acc.AccessToken); exp != nil {
+				acc.TokenExpiresAt = exp
+				am.db.Model(&model.SoraAccount{}).Where("id = ?", acc.ID).Update("token_expires_at", exp)
+			}
+		}
+
+		if acc.RefreshToken == "" {
+			am.checkAccessTokenOnly(acc, now, cfg.TokenExpiringSoon)
+			continue
+		}
+		if !am.refreshDue(acc, now, cfg) {
+			continue
+		}
+		if err := am.refreshAccountToken(AccountContext(ctx, acc.ID), acc); err != nil {
 			fail++
-			log.Printf("[token_refresh] 账号 %s 刷新失败: %v", acc.Email, err)
+			am.mu.Lock()
+			am.retryAt[acc.ID] = now.Add(tokenRefreshRetryDelay)
+			am.mu.Unlock()
+			logging.For("token_refresh").Warn("刷新 Token 失败", "account_id", acc.ID, "email", acc.Email, "err", err)
 		} else {
 			success++
 		}
 	}
 
-	log.Printf("[token_refresh] 刷新完成：成功 %d，失败 %d", success, fail)
+	if success+fail > 0 {
+		logging.For("token_refresh").Info("刷新完成", "success", success, "failed", fail)
+	}
+}
+
+// refreshDue 判断有 RT 的账号是否需要刷新
+//   - 上次刷新失败时等待重试间隔
+//   - 已被标记 token_expired（如提交时上游返回 401）：立即刷新
+//   - 已知过期时间：到达 exp - TokenRefreshAhead - 抖动 时刷新
+//   - 未知过期时间：距上次刷新超过 TokenRefreshInterval 时刷新
+func (am *AccountManager) refreshDue(acc *model.SoraAccount, now time.Time, cfg *SyncConfig) bool {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if t, ok := am.retryAt[acc.ID]; ok && now.Before(t) {
+		return false
+	}
+	if acc.Status == model.AccountStatusTokenExpired {
+		return true
+	}
+	if acc.TokenExpiresAt != nil {
+		ahead := cfg.TokenRefreshAhead + refreshJitter(acc.ID, *acc.TokenExpiresAt, cfg.TokenRefreshAhead)
+		return !now.Before(acc.TokenExpiresAt.Add(-ahead))
+	}
+	last, ok := am.refreshedAt[acc.ID]
+	return !ok || now.Sub(last) >= cfg.TokenRefreshInterval
+}
+
+// refreshJitter 刷新时间的抖动（0 ~ ahead/4），按账号和过期时间打散，避免同批导入的账号集中刷新
+func refreshJitter(accountID int64, exp time.Time, ahead time.Duration) time.Duration {
+	window := uint64(ahead / 4)
+	if window == 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	_, _ = fmt.Fprintf(h, "%d:%d", accountID, exp.Unix())
+	return time.Duration(h.Sum64() % window)
+}
+
+// checkAccessTokenOnly 更新仅有 AT 的账号的过期状态
+func (am *AccountManager) checkAccessTokenOnly(acc *model.SoraAccount, now time.Time, window time.Duration) {
+	if acc.TokenExpiresAt == nil {
+		return
+	}
+
+	switch {
+	case !now.Before(*acc.TokenExpiresAt):
+		if acc.Status != model.AccountStatusTokenExpired {
+			am.markError(acc.ID, model.AccountStatusTokenExpired, "Access Token 已过期且没有 Refresh Token")
+			logging.For("token_refresh").Warn("Access Token 已过期", "account_id", acc.ID, "email", acc.Email)
+		}
+	case acc.TokenExpiresAt.Sub(now) <= window:
+		if acc.Status == model.AccountStatusActive {
+			am.db.Model(&model.SoraAccount{}).Where("id = ?", acc.ID).Update("status", model.AccountStatusExpiringSoon)
+			logging.For("token_refresh").Warn("Access Token 即将过期且没有 Refresh Token",
+				"account_id", acc.ID, "email", acc.Email, "expires_at", acc.TokenExpiresAt.Format("2006-01-02 15:04"))
+		}
+	case acc.Status == model.AccountStatusExpiringSoon:
+		// AT 已被替换为新的 Token
+		am.db.Model(&model.SoraAccount{}).Where("id = ?", acc.ID).Update("status", model.AccountStatusActive)
+	}
 }
 
 // refreshAccountToken 刷新单个账号的 Token
 func (am *AccountManager) refreshAccountToken(ctx context.Context, acc *model.SoraAccount) error {
-	client, err := sora.New(am.proxyURL())
+	client, err := am.newClient(acc)
 	if err != nil {
 		am.markError(acc.ID, model.AccountStatusTokenExpired, err.Error())
 		return err
@@ -96,18 +195,30 @@ func (am *AccountManager) refreshAccountToken(ctx context.Context, acc *model.So
 
 	newAT, newRT, err := client.RefreshAccessToken(ctx, acc.RefreshToken, "")
 	if err != nil {
-		am.markError(acc.ID, model.AccountStatusTokenExpired, err.Error())
+		// 当前 AT 仍未过期时保持可用，仅记录错误
+		if acc.TokenExpiresAt != nil && time.Now().Before(*acc.TokenExpiresAt) {
+			am.db.Model(&model.SoraAccount{}).Where("id = ?", acc.ID).Update("last_error", err.Error())
+		} else {
+			am.markError(acc.ID, model.AccountStatusTokenExpired, err.Error())
+		}
 		return err
 	}
 
 	// 回写到内存对象，确保调用方能拿到最新的 Token
 	acc.AccessToken = newAT
 	acc.RefreshToken = newRT
+	acc.TokenExpiresAt = model.ExtractExpiryFromJWT(newAT)
+
+	am.mu.Lock()
+	am.refreshedAt[acc.ID] = time.Now()
+	delete(am.retryAt, acc.ID)
+	am.mu.Unlock()
 
 	updates := map[string]interface{}{
-		"access_token":  newAT,
-		"refresh_token": newRT,
-		"last_sync_at":  time.Now(),
+		"access_token":     newAT,
+		"refresh_token":    newRT,
+		"token_expires_at": acc.TokenExpiresAt,
+		"last_sync_at":     time.Now(),
 	}
 
 	// 从新 AT 提取邮箱（如果之前未获取到）
@@ -118,7 +229,7 @@ func (am *AccountManager) refreshAccountToken(ctx context.Context, acc *model.So
 		}
 	}
 
-	if acc.Status == model.AccountStatusTokenExpired {
+	if acc.Status == model.AccountStatusTokenExpired || acc.Status == model.AccountStatusExpiringSoon {
 		updates["status"] = model.AccountStatusActive
 		updates["last_error"] = ""
 	}
@@ -150,26 +261,26 @@ func (am *AccountManager) creditSyncLoop(ctx context.Context) {
 func (am *AccountManager) syncAllCredits(ctx context.Context) {
 	var accounts []model.SoraAccount
 	if err := am.db.Where("enabled = ? AND status IN ?", true,
-		[]string{model.AccountStatusActive, model.AccountStatusQuotaExhausted}).Find(&accounts).Error; err != nil {
-		log.Printf("[credit_sync] 查询账号失败: %v", err)
+		[]string{model.AccountStatusActive, model.AccountStatusExpiringSoon, model.AccountStatusQuotaExhausted}).Find(&accounts).Error; err != nil {
+		logging.For("credit_sync").Error("查询账号失败", "err", err)
 		return
 	}
 
 	for i := range accounts {
-		am.syncAccountCredit(ctx, &accounts[i])
+		am.syncAccountCredit(AccountContext(ctx, accounts[i].ID), &accounts[i])
 	}
 }
 
 // syncAccountCredit 同步单个账号配额
 func (am *AccountManager) syncAccountCredit(ctx context.Context, acc *model.SoraAccount) {
-	client, err := sora.New(am.proxyURL())
+	client, err := am.newClient(acc)
 	if err != nil {
 		return
 	}
 
 	balance, err := client.GetCreditBalance(ctx, acc.AccessToken)
 	if err != nil {
-		log.Printf("[credit_sync] 账号 %s 配额查询失败: %v", acc.Email, err)
+		logging.For("credit_sync").Warn("配额查询失败", "account_id", acc.ID, "email", acc.Email, "err", err)
 		return
 	}
 
@@ -203,7 +314,7 @@ func (am *AccountManager) syncAccountCredit(ctx context.Context, acc *model.Sora
 	} else if acc.Status == model.AccountStatusQuotaExhausted && balance.RemainingCount != 0 {
 		updates["status"] = model.AccountStatusActive
 		updates["last_error"] = ""
-		log.Printf("[credit_sync] 账号 %s 额度已恢复，重新启用", acc.Email)
+		logging.For("credit_sync").Info("额度已恢复，重新启用", "account_id", acc.ID, "email", acc.Email)
 	}
 
 	am.db.Model(&model.SoraAccount{}).Where("id = ?", acc.ID).Updates(updates)
@@ -233,25 +344,25 @@ func (am *AccountManager) subscriptionSyncLoop(ctx context.Context) {
 func (am *AccountManager) syncAllSubscriptions(ctx context.Context) {
 	var accounts []model.SoraAccount
 	if err := am.db.Where("enabled = ?", true).Find(&accounts).Error; err != nil {
-		log.Printf("[sub_sync] 查询账号失败: %v", err)
+		logging.For("sub_sync").Error("查询账号失败", "err", err)
 		return
 	}
 
 	for i := range accounts {
-		am.syncAccountSubscription(ctx, &accounts[i])
+		am.syncAccountSubscription(AccountContext(ctx, accounts[i].ID), &accounts[i])
 	}
 }
 
 // syncAccountSubscription 同步单个账号订阅信息
 func (am *AccountManager) syncAccountSubscription(ctx context.Context, acc *model.SoraAccount) {
-	client, err := sora.New(am.proxyURL())
+	client, err := am.newClient(acc)
 	if err != nil {
 		return
 	}
 
 	info, err := client.GetSubscriptionInfo(ctx, acc.AccessToken)
 	if err != nil {
-		log.Printf("[sub_sync] 账号 %s 订阅查询失败: %v", acc.Email, err)
+		logging.For("sub_sync").Warn("订阅查询失败", "account_id", acc.ID, "email", acc.Email, "err", err)
 		return
 	}
 
@@ -262,7 +373,7 @@ func (am *AccountManager) syncAccountSubscription(ctx context.Context, acc *mode
 		expiresAt := time.Unix(info.EndTs, 0)
 		updates["plan_expires_at"] = expiresAt
 		if expiresAt.Before(time.Now()) {
-			log.Printf("[sub_sync] ⚠ 账号 %s 订阅已过期: %s（%s）", acc.Email, info.PlanTitle, expiresAt.Format("2006-01-02"))
+			logging.For("sub_sync").Warn("订阅已过期", "account_id", acc.ID, "email", acc.Email, "plan", info.PlanTitle, "expired_at", expiresAt.Format("2006-01-02"))
 		}
 	}
 
@@ -271,7 +382,7 @@ func (am *AccountManager) syncAccountSubscription(ctx context.Context, acc *mode
 
 // SyncSingleAccountCredit 手动同步单个账号配额（管理端点使用）
 func (am *AccountManager) SyncSingleAccountCredit(ctx context.Context, acc *model.SoraAccount) error {
-	client, err := sora.New(am.proxyURL())
+	client, err := am.newClient(acc)
 	if err != nil {
 		return err
 	}
@@ -315,7 +426,7 @@ func (am *AccountManager) SyncSingleAccountCredit(ctx context.Context, acc *mode
 
 // SyncSingleAccountSubscription 手动同步单个账号订阅（管理端点使用）
 func (am *AccountManager) SyncSingleAccountSubscription(ctx context.Context, acc *model.SoraAccount) error {
-	client, err := sora.New(am.proxyURL())
+	client, err := am.newClient(acc)
 	if err != nil {
 		return err
 	}
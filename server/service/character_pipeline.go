@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/DouDOU-start/go-sora2api/server/logging"
+	"github.com/DouDOU-start/go-sora2api/server/model"
+	"github.com/DouDOU-start/go-sora2api/sora"
+	"gorm.io/gorm"
+)
+
+// characterStepAttempts 每个步骤的最大尝试次数
+const characterStepAttempts = 3
+
+// characterStep 角色处理流程中的一个步骤
+type characterStep struct {
+	done     string        // 完成后记录到 SoraCharacter.Step 的值
+	label    string        // 用于日志和错误信息
+	timeout  time.Duration // 单次尝试的超时时间
+	optional bool          // 全部尝试失败时是否跳过（不标记角色失败）
+	run      func(p *CharacterPipeline, ctx context.Context, job *characterJob) (map[string]interface{}, error)
+}
+
+// characterSteps 角色处理流程：轮询 cameo → 下载头像 → 上传头像 → 定稿 → 设置可见性
+// 每步成功后将返回的字段与步骤名一起写回数据库，重启后从下一步继续
+var characterSteps = []characterStep{
+	{done: model.CharacterStepCameoReady, label: "角色处理", timeout: 9 * time.Minute, run: (*CharacterPipeline).pollCameo},
+	{done: model.CharacterStepAvatarDownloaded, label: "下载角色头像", timeout: 2 * time.Minute, run: (*CharacterPipeline).downloadAvatar},
+	{done: model.CharacterStepAvatarUploaded, label: "上传角色头像", timeout: 2 * time.Minute, run: (*CharacterPipeline).uploadAvatar},
+	{done: model.CharacterStepFinalized, label: "定稿角色", timeout: 2 * time.Minute, run: (*CharacterPipeline).finalize},
+	{done: model.CharacterStepVisibilitySet, label: "设置角色可见性", timeout: time.Minute, optional: true, run: (*CharacterPipeline).setVisibility},
+}
+
+// characterJob 一次步骤执行所需的上下文
+type characterJob struct {
+	char    *model.SoraCharacter
+	account *model.SoraAccount
+	client  *sora.Client
+}
+
+// CharacterPipeline 角色后台处理流程（状态持久化在 sora_characters，服务重启后可恢复）
+type CharacterPipeline struct {
+	db        *gorm.DB
+	scheduler *Scheduler
+	ctx       context.Context // RecoverInProgress 传入的后台 context，取消时中断步骤和重试等待
+	running   sync.Map        // charID → struct{}
+}
+
+// NewCharacterPipeline 创建角色处理流程
+func NewCharacterPipeline(db *gorm.DB, scheduler *Scheduler) *CharacterPipeline {
+	return &CharacterPipeline{db: db, scheduler: scheduler, ctx: context.Background()}
+}
+
+// Start 在后台从角色最后完成的步骤继续处理（同一角色不会重复启动）
+func (p *CharacterPipeline) Start(charID string) {
+	if _, loaded := p.running.LoadOrStore(charID, struct{}{}); loaded {
+		return
+	}
+	go func() {
+		defer p.running.Delete(charID)
+		p.run(charID)
+	}()
+}
+
+// RecoverInProgress 服务重启后恢复处理中的角色（需在接收请求前调用）
+// ctx 取消时正在执行的角色停在当前步骤，保持 processing 状态，下次启动时继续
+func (p *CharacterPipeline) RecoverInProgress(ctx context.Context) {
+	p.ctx = ctx
+
+	var ids []string
+	if err := p.db.Model(&model.SoraCharacter{}).
+		Where("status = ?", model.CharacterStatusProcessing).
+		Pluck("id", &ids).Error; err != nil {
+		logging.For("character").Error("查询处理中角色失败", "err", err)
+		return
+	}
+
+	for _, id := range ids {
+		p.Start(id)
+	}
+	if len(ids) > 0 {
+		logging.For("character").Info("已恢复处理中的角色", "count", len(ids))
+	}
+}
+
+// run 依次执行尚未完成的步骤，直到完成或失败
+func (p *CharacterPipeline) run(charID string) {
+	for {
+		var char model.SoraCharacter
+		if err := p.db.Where("id = ?", charID).First(&char).Error; err != nil {
+			logging.For("character").Error("加载角色失败", "character_id", charID, "err", err)
+			return
+		}
+		if char.Status != model.CharacterStatusProcessing {
+			return
+		}
+
+		step := nextCharacterStep(char.Step)
+		if step == nil {
+			p.complete(&char)
+			return
+		}
+
+		updates, err := p.runStep(&char, step)
+		if p.ctx.Err() != nil {
+			logging.For("character").Info("服务关闭，角色处理暂停", "character_id", charID, "step", char.Step)
+			return
+		}
+		if err != nil {
+			if !step.optional {
+				p.fail(charID, fmt.Sprintf("%s失败: %v", step.label, err))
+				return
+			}
+			logging.For("character").Warn(step.label+"失败（非致命）", "character_id", charID, "err", err)
+			updates = map[string]interface{}{}
+		}
+
+		updates["step"] = step.done
+		if err := p.db.Model(&model.SoraCharacter{}).Where("id = ?", charID).Updates(updates).Error; err != nil {
+			// 保持 processing 会使角色既不被同步也不再被处理，直接标记失败
+			p.fail(charID, fmt.Sprintf("保存%s结果失败: %v", step.label, err))
+			return
+		}
+	}
+}
+
+// nextCharacterStep 返回最后完成步骤之后的下一步，已全部完成时返回 nil
+func nextCharacterStep(last string) *characterStep {
+	if last == "" || last == model.CharacterStepUploaded {
+		return &characterSteps[0]
+	}
+	for i := range characterSteps {
+		if characterSteps[i].done == last && i+1 < len(characterSteps) {
+			return &characterSteps[i+1]
+		}
+	}
+	return nil
+}
+
+// runStep 执行单个步骤，失败时退避重试
+func (p *CharacterPipeline) runStep(char *model.SoraCharacter, step *characterStep) (map[string]interface{}, error) {
+	var lastErr error
+	for attempt := 1; attempt <= characterStepAttempts; attempt++ {
+		if attempt > 1 {
+			logging.For("character").Warn(step.label+"失败，重试", "character_id", char.ID, "account_id", char.AccountID, "retry", attempt-1, "err", lastErr)
+			select {
+			case <-p.ctx.Done():
+				return nil, p.ctx.Err()
+			case <-time.After(time.Duration(attempt-1) * 5 * time.Second):
+			}
+		}
+
+		job, err := p.newJob(char)
+		if err != nil {
+			return nil, err
+		}
+		ctx, cancel := context.WithTimeout(AccountContext(p.ctx, char.AccountID), step.timeout)
+		updates, err := step.run(p, ctx, job)
+		cancel()
+		if err == nil {
+			return updates, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// newJob 加载角色所属账号（每次重新读取以使用最新 Token）并创建 Sora 客户端
+func (p *CharacterPipeline) newJob(char *model.SoraCharacter) (*characterJob, error) {
+	var account model.SoraAccount
+	if err := p.db.Where("id = ?", char.AccountID).First(&account).Error; err != nil {
+		return nil, fmt.Errorf("找不到关联账号: %w", err)
+	}
+	client, err := p.scheduler.NewClient(&account)
+	if err != nil {
+		return nil, fmt.Errorf("创建 Sora 客户端失败: %w", err)
+	}
+	return &characterJob{char: char, account: &account, client: client}, nil
+}
+
+// pollCameo 轮询 cameo 状态直到完成，确定用户名、显示名和推荐头像
+func (p *CharacterPipeline) pollCameo(ctx context.Context, job *characterJob) (map[string]interface{}, error) {
+	char := job.char
+	status, err := job.client.PollCameoStatus(ctx, job.account.AccessToken, char.CameoID, 5*time.Second, 8*time.Minute, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// 未指定时使用推荐名称，仍为空时使用默认值
+	username, displayName := char.Username, char.DisplayName
+	if username == "" {
+		username = status.UsernameHint
+	}
+	if displayName == "" {
+		displayName = status.DisplayNameHint
+	}
+	if username == "" {
+		username = "character_" + char.ID[5:]
+	}
+	if displayName == "" {
+		displayName = "Character"
+	}
+
+	updates := map[string]interface{}{
+		"username":     username,
+		"display_name": displayName,
+	}
+	// 未指定自定义头像时使用推荐头像
+	if len(char.ProfileImage) == 0 {
+		updates["profile_url"] = status.ProfileAssetURL
+	}
+	return updates, nil
+}
+
+// downloadAvatar 下载推荐头像保存到数据库（避免依赖外部临时 URL），已有自定义头像时跳过
+func (p *CharacterPipeline) downloadAvatar(ctx context.Context, job *characterJob) (map[string]interface{}, error) {
+	if len(job.char.ProfileImage) > 0 {
+		return map[string]interface{}{}, nil
+	}
+	data, err := job.client.DownloadCharacterImage(ctx, job.char.ProfileURL)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"profile_image": data}, nil
+}
+
+// uploadAvatar 上传头像获取 asset pointer
+func (p *CharacterPipeline) uploadAvatar(ctx context.Context, job *characterJob) (map[string]interface{}, error) {
+	assetPointer, err := job.client.UploadCharacterImage(ctx, job.account.AccessToken, job.char.ProfileImage)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"profile_asset_pointer": assetPointer}, nil
+}
+
+// finalize 定稿角色
+func (p *CharacterPipeline) finalize(ctx context.Context, job *characterJob) (map[string]interface{}, error) {
+	char := job.char
+	characterID, err := job.client.FinalizeCharacterWithOptions(ctx, job.account.AccessToken,
+		char.CameoID, char.Username, char.DisplayName, char.ProfileAssetPointer,
+		sora.CharacterFinalizeOptions{
+			InstructionSet:       char.InstructionSet,
+			SafetyInstructionSet: char.SafetyInstructionSet,
+		})
+	if err != nil {
+		// 上次定稿可能已在上游成功但未写回（如写库前服务退出），按 cameo 查找已有角色
+		existing, findErr := findCharacterByCameo(ctx, job.client, job.account.AccessToken, char.CameoID)
+		if findErr != nil || existing == nil {
+			return nil, err
+		}
+		characterID = existing.CharacterID
+	}
+	return map[string]interface{}{"character_id": characterID}, nil
+}
+
+// setVisibility 设置初始可见性（未指定时为 public）
+func (p *CharacterPipeline) setVisibility(ctx context.Context, job *characterJob) (map[string]interface{}, error) {
+	visibility := job.char.Visibility
+	if visibility == "" {
+		visibility = "public"
+	}
+	if err := job.client.SetCharacterVisibility(ctx, job.account.AccessToken, job.char.CameoID, visibility); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"is_public": visibility == "public"}, nil
+}
+
+// complete 标记角色就绪
+func (p *CharacterPipeline) complete(char *model.SoraCharacter) {
+	now := time.Now()
+	p.db.Model(&model.SoraCharacter{}).Where("id = ?", char.ID).Updates(map[string]interface{}{
+		"status":       model.CharacterStatusReady,
+		"completed_at": &now,
+	})
+	logging.For("character").Info("角色处理完成", "character_id", char.ID, "account_id", char.AccountID, "sora_character_id", char.CharacterID)
+}
+
+// fail 标记角色处理失败
+func (p *CharacterPipeline) fail(charID, errMsg string) {
+	now := time.Now()
+	p.db.Model(&model.SoraCharacter{}).Where("id = ?", charID).Updates(map[string]interface{}{
+		"status":        model.CharacterStatusFailed,
+		"error_message": errMsg,
+		"completed_at":  &now,
+	})
+	logging.For("character").Warn("角色处理失败", "character_id", charID, "err", errMsg)
+}
+
+// findCharacterByCameo 在账号角色列表中查找 cameo 对应的已定稿角色，未找到时返回 nil, nil
+func findCharacterByCameo(ctx context.Context, client *sora.Client, accessToken, cameoID string) (*sora.Character, error) {
+	chars, err := listAllCharacters(ctx, client, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	for i := range chars {
+		if chars[i].CameoID == cameoID {
+			return &chars[i], nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/DouDOU-start/go-sora2api/server/logging"
+	"github.com/DouDOU-start/go-sora2api/server/model"
+	"github.com/DouDOU-start/go-sora2api/sora"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// characterSyncMaxPages 同步角色时最多翻页数
+const characterSyncMaxPages = 50
+
+// SyncAccountCharacters 将账号在 Sora 上的角色同步到 sora_characters
+// 新角色导入为 ready，已有角色更新名称、头像和可见性，上游已删除的角色标记为 missing
+// 仍在创建流水线中（processing）的角色由流水线负责定稿，同步时跳过
+func SyncAccountCharacters(ctx context.Context, db *gorm.DB, client *sora.Client, account *model.SoraAccount) (*model.CharacterSyncResult, error) {
+	upstream, err := listAllCharacters(ctx, client, account.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing []model.SoraCharacter
+	if err := db.Omit("profile_image").
+		Where("account_id = ? AND character_id <> ?", account.ID, "").
+		Find(&existing).Error; err != nil {
+		return nil, fmt.Errorf("查询本地角色失败: %w", err)
+	}
+	local := make(map[string]*model.SoraCharacter, len(existing))
+	for i := range existing {
+		local[existing[i].CharacterID] = &existing[i]
+	}
+
+	result := &model.CharacterSyncResult{Total: len(upstream)}
+	seen := make(map[string]bool, len(upstream))
+	for _, ch := range upstream {
+		seen[ch.CharacterID] = true
+
+		if row, ok := local[ch.CharacterID]; ok {
+			if row.Status == model.CharacterStatusProcessing {
+				continue
+			}
+			res := db.Model(&model.SoraCharacter{}).Where("id = ? AND status <> ?", row.ID, model.CharacterStatusProcessing).Updates(map[string]interface{}{
+				"status":       model.CharacterStatusReady,
+				"cameo_id":     ch.CameoID,
+				"display_name": ch.DisplayName,
+				"username":     ch.Username,
+				"profile_url":  ch.ProfileURL,
+				"is_public":    ch.IsPublic(),
+			})
+			if res.Error != nil {
+				return nil, fmt.Errorf("更新角色 %s 失败: %w", ch.CharacterID, res.Error)
+			}
+			// 查询后才进入流水线的角色不会被更新
+			if res.RowsAffected > 0 {
+				result.Updated++
+			}
+			continue
+		}
+
+		// 头像下载失败不影响导入，仅缺少本地图片
+		var image []byte
+		if ch.ProfileURL != "" {
+			if image, err = client.DownloadCharacterImage(ctx, ch.ProfileURL); err != nil {
+				logging.For("character_sync").Warn("角色头像下载失败", "account_id", account.ID, "sora_character_id", ch.CharacterID, "err", err)
+			}
+		}
+		now := time.Now()
+		row := &model.SoraCharacter{
+			ID:           "char_" + uuid.New().String()[:8],
+			AccountID:    account.ID,
+			CameoID:      ch.CameoID,
+			CharacterID:  ch.CharacterID,
+			Status:       model.CharacterStatusReady,
+			DisplayName:  ch.DisplayName,
+			Username:     ch.Username,
+			ProfileURL:   ch.ProfileURL,
+			ProfileImage: image,
+			IsPublic:     ch.IsPublic(),
+			CompletedAt:  &now,
+		}
+		if err := db.Create(row).Error; err != nil {
+			return nil, fmt.Errorf("保存角色 %s 失败: %w", ch.CharacterID, err)
+		}
+		result.Imported++
+	}
+
+	// 本地已定稿但上游不存在的角色
+	for _, row := range existing {
+		if seen[row.CharacterID] || row.Status == model.CharacterStatusMissing || row.Status == model.CharacterStatusProcessing {
+			continue
+		}
+		res := db.Model(&model.SoraCharacter{}).Where("id = ? AND status <> ?", row.ID, model.CharacterStatusProcessing).
+			Update("status", model.CharacterStatusMissing)
+		if res.Error != nil {
+			return nil, fmt.Errorf("标记角色 %s 缺失失败: %w", row.CharacterID, res.Error)
+		}
+		if res.RowsAffected > 0 {
+			result.Missing++
+		}
+	}
+
+	logging.For("character_sync").Info("角色同步完成", "account_id", account.ID, "email", account.Email,
+		"total", result.Total, "imported", result.Imported, "updated", result.Updated, "missing", result.Missing)
+	return result, nil
+}
+
+// listAllCharacters 翻页获取账号下的全部角色
+func listAllCharacters(ctx context.Context, client *sora.Client, accessToken string) ([]sora.Character, error) {
+	var all []sora.Character
+	var opts sora.ListOptions
+	for page := 0; page < characterSyncMaxPages; page++ {
+		result, err := client.ListCharacters(ctx, accessToken, opts)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, result.Characters...)
+		if result.NextCursor == "" {
+			return all, nil
+		}
+		opts.Cursor = result.NextCursor
+	}
+	return nil, fmt.Errorf("获取角色列表失败: 超过 %d 页", characterSyncMaxPages)
+}
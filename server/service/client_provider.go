@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DouDOU-start/go-sora2api/server/logging"
+	"github.com/DouDOU-start/go-sora2api/server/model"
+	"github.com/DouDOU-start/go-sora2api/sora"
+	http "github.com/bogdanfinn/fhttp"
+)
+
+// 客户端缓存相关参数
+const (
+	clientIdleTTL       = 10 * time.Minute // 空闲超过该时长的客户端被释放
+	clientEvictInterval = time.Minute      // 空闲检查周期
+)
+
+// clientKey 客户端缓存键：代理（含 session）、指纹 profile、上传上限和下载选项相同的请求共用一个客户端
+type clientKey struct {
+	proxyURL      string
+	fingerprint   string
+	maxUploadSize int64
+	media         sora.MediaOptions
+}
+
+// cachedClient 缓存的客户端
+type cachedClient struct {
+	client   *sora.Client
+	lastUsed atomic.Int64 // UnixNano
+}
+
+// ClientProvider 复用 Sora 客户端（及其底层 TLS 连接），避免每次请求重新建连和握手
+type ClientProvider struct {
+	settings   *SettingsStore
+	proxies    *ProxyPool
+	clientOpts []sora.Option     // 创建 Sora 客户端时附加的选项（如指向测试桩）
+	limiter    *sora.RateLimiter // 所有客户端共享的按账号限流器
+
+	mu      sync.Mutex
+	clients map[clientKey]*cachedClient
+
+	hits      atomic.Int64
+	misses    atomic.Int64
+	evictions atomic.Int64
+	cold      requestStats // 新建客户端的首个请求（需建连和 TLS 握手）
+	reused    requestStats // 复用客户端的后续请求
+}
+
+// requestStats 请求次数与累计耗时
+type requestStats struct {
+	count   atomic.Int64
+	totalNs atomic.Int64
+}
+
+// avgMs 平均耗时（毫秒）
+func (s *requestStats) avgMs() float64 {
+	n := s.count.Load()
+	if n == 0 {
+		return 0
+	}
+	return float64(s.totalNs.Load()) / float64(n) / float64(time.Millisecond)
+}
+
+// NewClientProvider 创建客户端缓存
+func NewClientProvider(settings *SettingsStore, proxies *ProxyPool) *ClientProvider {
+	return &ClientProvider{
+		settings: settings,
+		proxies:  proxies,
+		limiter:  sora.NewRateLimiter(settings.GetAccountRateLimit()),
+		clients:  make(map[clientKey]*cachedClient),
+	}
+}
+
+// SetClientOptions 设置创建 Sora 客户端时附加的选项（需在处理请求前调用）
+func (p *ClientProvider) SetClientOptions(opts ...sora.Option) {
+	p.clientOpts = opts
+}
+
+// Start 启动后台空闲客户端回收
+func (p *ClientProvider) Start(ctx context.Context) {
+	go p.evictLoop(ctx)
+}
+
+// Get 返回账号使用的 Sora 客户端（按账号选取代理 session 和指纹 profile），
+// account 为 nil 时使用默认代理和默认指纹选取
+func (p *ClientProvider) Get(account *model.SoraAccount) (*sora.Client, error) {
+	var key string
+	if account != nil {
+		key = strconv.FormatInt(account.ID, 10)
+	}
+	// 限流设置可能已修改，已缓存的客户端共享同一限流器，随时生效
+	p.limiter.SetLimit(p.settings.GetAccountRateLimit())
+
+	fingerprint := p.settings.FingerprintFor(key)
+	fpKey, err := json.Marshal(fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	ck := clientKey{
+		proxyURL:      p.proxies.ProxyURLFor(account),
+		fingerprint:   string(fpKey),
+		maxUploadSize: p.settings.GetMaxUploadSize(),
+		media:         p.settings.GetMediaOptions(),
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if cached, ok := p.clients[ck]; ok {
+		cached.lastUsed.Store(time.Now().UnixNano())
+		p.hits.Add(1)
+		return cached.client, nil
+	}
+
+	opts := append([]sora.Option{
+		sora.WithFingerprint(fingerprint),
+		sora.WithMaxUploadSize(ck.maxUploadSize),
+		sora.WithMediaOptions(ck.media),
+		sora.WithRateLimiter(p.limiter),
+		sora.WithLogger(logging.For("sora")),
+		sora.WithHooks(sora.Hooks{OnResponse: logUpstream}),
+		sora.WithDoerWrapper(p.meter),
+	}, p.clientOpts...)
+	client, err := sora.New(ck.proxyURL, opts...)
+	if err != nil {
+		return nil, err
+	}
+	cached := &cachedClient{client: client}
+	cached.lastUsed.Store(time.Now().UnixNano())
+	p.clients[ck] = cached
+	p.misses.Add(1)
+	return client, nil
+}
+
+// Stats 返回客户端缓存统计
+func (p *ClientProvider) Stats() model.ClientPoolStats {
+	p.mu.Lock()
+	size := len(p.clients)
+	p.mu.Unlock()
+
+	stats := model.ClientPoolStats{
+		Clients:        size,
+		IdleTTL:        clientIdleTTL.String(),
+		Hits:           p.hits.Load(),
+		Misses:         p.misses.Load(),
+		Evictions:      p.evictions.Load(),
+		ColdRequests:   p.cold.count.Load(),
+		ColdAvgMs:      p.cold.avgMs(),
+		ReusedRequests: p.reused.count.Load(),
+		ReusedAvgMs:    p.reused.avgMs(),
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+// evictLoop 定期释放空闲客户端
+func (p *ClientProvider) evictLoop(ctx context.Context) {
+	ticker := time.NewTicker(clientEvictInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.evictIdle()
+		}
+	}
+}
+
+// evictIdle 释放空闲超时的客户端并关闭其空闲连接
+func (p *ClientProvider) evictIdle() {
+	cutoff := time.Now().Add(-clientIdleTTL).UnixNano()
+
+	var idle []*sora.Client
+	p.mu.Lock()
+	for k, cached := range p.clients {
+		if cached.lastUsed.Load() < cutoff {
+			idle = append(idle, cached.client)
+			delete(p.clients, k)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, client := range idle {
+		client.CloseIdleConnections()
+	}
+	if len(idle) > 0 {
+		p.evictions.Add(int64(len(idle)))
+		logging.For("client_pool").Info("已释放空闲客户端", "count", len(idle))
+	}
+}
+
+// AccountContext 在 ctx 中附加账号 ID，上游请求日志的 account 字段使用该值（否则为 token 摘要）
+func AccountContext(ctx context.Context, accountID int64) context.Context {
+	return sora.WithAccountHint(ctx, strconv.FormatInt(accountID, 10))
+}
+
+// logUpstream 记录上游请求：失败和 5xx 为 warn，其余为 debug（LOG_LEVEL=debug 时可见）
+func logUpstream(ctx context.Context, info sora.ResponseInfo) {
+	level := slog.LevelDebug
+	if info.Err != nil || info.Status >= 500 {
+		level = slog.LevelWarn
+	}
+	attrs := []any{
+		"method", info.Method,
+		"path", info.Path,
+		"status", info.Status,
+		"latency_ms", info.Latency.Milliseconds(),
+		"attempt", info.Attempt,
+		"account", info.Account,
+	}
+	if info.Err != nil {
+		attrs = append(attrs, "err", redactURLError(info.Err))
+	}
+	logging.For("upstream").Log(ctx, level, "上游请求", attrs...)
+}
+
+// redactURLError 返回可写入日志的错误文本
+// 传输层错误（*url.Error）包含完整请求 URL（签名的媒体/CDN 参数、游标和 ID），只保留操作和底层错误，路径已单独记录
+func redactURLError(err error) string {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Op + ": " + urlErr.Err.Error()
+	}
+	return err.Error()
+}
+
+// meter 包装客户端的 HTTP 执行器，分别统计首个请求和复用请求的耗时
+func (p *ClientProvider) meter(d sora.Doer) sora.Doer {
+	return &meteredDoer{Doer: d, provider: p}
+}
+
+// meteredDoer 统计请求耗时的 HTTP 执行器
+type meteredDoer struct {
+	sora.Doer
+	provider *ClientProvider
+	used     atomic.Bool
+}
+
+// Do 执行请求并记录耗时（首个请求计入新建连接，其余计入复用）
+func (m *meteredDoer) Do(req *http.Request) (*http.Response, error) {
+	stats := &m.provider.reused
+	if !m.used.Swap(true) {
+		stats = &m.provider.cold
+	}
+	start := time.Now()
+	resp, err := m.Doer.Do(req)
+	if err == nil {
+		stats.count.Add(1)
+		stats.totalNs.Add(int64(time.Since(start)))
+	}
+	return resp, err
+}
+
+// CloseIdleConnections 转发到底层执行器
+func (m *meteredDoer) CloseIdleConnections() {
+	if closer, ok := m.Doer.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRedactURLError(t *testing.T) {
+	const secret = "https://videos.example/az/files/abc.mp4?se=2026&sig=SECRET&cursor=CURSOR"
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"url.Error", &url.Error{Op: "Get", URL: secret, Err: context.DeadlineExceeded}, "Get: context deadline exceeded"},
+		{"包装的 url.Error", fmt.Errorf("请求失败: %w", &url.Error{Op: "Post", URL: secret, Err: errors.New("EOF")}), "Post: EOF"},
+		{"普通错误原样保留", errors.New("HTTP 503"), "HTTP 503"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := redactURLError(tt.err)
+			if got != tt.want {
+				t.Errorf("redactURLError = %q, want %q", got, tt.want)
+			}
+			if strings.Contains(got, "SECRET") || strings.Contains(got, "CURSOR") {
+				t.Errorf("日志文本仍包含查询参数: %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/DouDOU-start/go-sora2api/server/logging"
+	"github.com/DouDOU-start/go-sora2api/server/model"
+	"github.com/DouDOU-start/go-sora2api/sora"
+	"gorm.io/gorm"
+)
+
+// 代理健康检查相关参数
+const (
+	proxyCheckTarget  = "https://sora.chatgpt.com"
+	proxyCheckTimeout = 10 * time.Second
+)
+
+// ProxyPool 代理池（内存快照 + 定期健康检查）
+//
+// 账号使用的代理按以下顺序选取：
+//  1. 账号绑定的代理（健康时）
+//  2. 账号所在分组的健康代理
+//  3. 未分组的健康默认代理
+//  4. 账号绑定的代理（即使不健康）
+//  5. 全局代理设置
+//
+// 2、3 在候选代理中按账号 ID 做最高随机权重哈希，代理增减或故障时只影响相关账号
+type ProxyPool struct {
+	db       *gorm.DB
+	settings *SettingsStore
+
+	mu      sync.RWMutex
+	proxies []model.SoraProxy // 已启用的代理
+}
+
+// NewProxyPool 创建代理池并加载代理列表
+func NewProxyPool(db *gorm.DB, settings *SettingsStore) *ProxyPool {
+	p := &ProxyPool{db: db, settings: settings}
+	p.Reload()
+	return p
+}
+
+// Start 启动后台健康检查
+func (p *ProxyPool) Start(ctx context.Context) {
+	go p.checkLoop(ctx)
+	logging.For("proxy_pool").Info("健康检查已启动", "interval", p.settings.GetProxyCheckInterval())
+}
+
+// Reload 从数据库重新加载已启用的代理（代理增删改后调用）
+func (p *ProxyPool) Reload() {
+	var proxies []model.SoraProxy
+	if err := p.db.Where("enabled = ?", true).Order("id ASC").Find(&proxies).Error; err != nil {
+		logging.For("proxy_pool").Error("加载代理失败", "err", err)
+		return
+	}
+	p.mu.Lock()
+	p.proxies = proxies
+	p.mu.Unlock()
+}
+
+// ProxyURLFor 返回账号应使用的代理 URL（%s 占位符已替换为账号固定的 session ID）
+// account 为 nil 时使用默认代理或全局代理，session ID 随机
+func (p *ProxyPool) ProxyURLFor(account *model.SoraAccount) string {
+	var accountID int64
+	var sessionID string
+	if account != nil {
+		accountID = account.ID
+		sessionID = stickySessionID(account.ID)
+	}
+	if proxy := p.pick(account, accountID); proxy != nil {
+		return sora.ParseProxyWithSession(proxy.URL, sessionID)
+	}
+	return sora.ParseProxyWithSession(p.settings.GetProxyURL(), sessionID)
+}
+
+// pick 按选取顺序返回账号应使用的代理，无可用代理时返回 nil
+func (p *ProxyPool) pick(account *model.SoraAccount, accountID int64) *model.SoraProxy {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var bound *model.SoraProxy
+	var grouped, defaults []*model.SoraProxy
+	for i := range p.proxies {
+		proxy := &p.proxies[i]
+		if account != nil && account.ProxyID != nil && proxy.ID == *account.ProxyID {
+			bound = proxy
+		}
+		if !proxy.Healthy {
+			continue
+		}
+		switch {
+		case proxy.GroupID == nil:
+			defaults = append(defaults, proxy)
+		case account != nil && account.GroupID != nil && *proxy.GroupID == *account.GroupID:
+			grouped = append(grouped, proxy)
+		}
+	}
+
+	if bound != nil && bound.Healthy {
+		return bound
+	}
+	if proxy := pickByHash(grouped, accountID); proxy != nil {
+		return proxy
+	}
+	if proxy := pickByHash(defaults, accountID); proxy != nil {
+		return proxy
+	}
+	return bound
+}
+
+// pickByHash 最高随机权重哈希：同一账号在候选集合不变时总是选中同一代理
+func pickByHash(candidates []*model.SoraProxy, accountID int64) *model.SoraProxy {
+	var best *model.SoraProxy
+	var bestScore uint64
+	for _, proxy := range candidates {
+		h := fnv.New64a()
+		_, _ = fmt.Fprintf(h, "%d:%d", accountID, proxy.ID)
+		if score := mix64(h.Sum64()); best == nil || score > bestScore {
+			best, bestScore = proxy, score
+		}
+	}
+	return best
+}
+
+// mix64 对 FNV 结果再做一次雪崩混淆（splitmix64 终结步骤）
+// FNV 对只差末尾几个字节的短输入高位变化不充分，直接比较会使代理分配明显不均
+func mix64(v uint64) uint64 {
+	v ^= v >> 30
+	v *= 0xbf58476d1ce4e5b9
+	v ^= v >> 27
+	v *= 0x94d049bb133111eb
+	v ^= v >> 31
+	return v
+}
+
+// stickySessionID 由账号 ID 生成固定的 8 位 session ID，使同一账号在代理商处保持同一出口 IP
+func stickySessionID(accountID int64) string {
+	const chars = "abcdefghijklmnopqrstuvwxyz0123456789"
+	h := fnv.New64a()
+	_, _ = h.Write([]byte("sora-account-" + strconv.FormatInt(accountID, 10)))
+	v := h.Sum64()
+	b := make([]byte, 8)
+	for i := range b {
+		b[i] = chars[v%uint64(len(chars))]
+		v /= uint64(len(chars))
+	}
+	return string(b)
+}
+
+// checkLoop 按设置的间隔检查所有已启用代理
+func (p *ProxyPool) checkLoop(ctx context.Context) {
+	for {
+		p.CheckAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.settings.GetProxyCheckInterval()):
+		}
+	}
+}
+
+// CheckAll 并发检查所有已启用代理并刷新快照
+func (p *ProxyPool) CheckAll(ctx context.Context) {
+	var proxies []model.SoraProxy
+	if err := p.db.Where("enabled = ?", true).Find(&proxies).Error; err != nil {
+		logging.For("proxy_pool").Error("加载代理失败", "err", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := range proxies {
+		wg.Add(1)
+		go func(proxy *model.SoraProxy) {
+			defer wg.Done()
+			p.Check(ctx, proxy)
+		}(&proxies[i])
+	}
+	wg.Wait()
+	p.Reload()
+}
+
+// Check 检查单个代理并记录结果（健康状态变化时输出日志）
+func (p *ProxyPool) Check(ctx context.Context, proxy *model.SoraProxy) model.ProxyTestResponse {
+	result := TestProxyURL(ctx, proxy.URL)
+
+	now := time.Now()
+	if err := p.db.Model(&model.SoraProxy{}).Where("id = ?", proxy.ID).Updates(map[string]interface{}{
+		"healthy":       result.Success,
+		"latency_ms":    result.Latency,
+		"last_error":    result.Error,
+		"last_check_at": &now,
+	}).Error; err != nil {
+		logging.For("proxy_pool").Error("保存代理检查结果失败", "proxy_id", proxy.ID, "err", err)
+	}
+
+	if proxy.Healthy && !result.Success {
+		logging.For("proxy_pool").Warn("代理不可用，已切换到其他代理", "proxy_id", proxy.ID, "proxy", proxy.Name, "err", result.Error)
+	} else if !proxy.Healthy && result.Success {
+		logging.For("proxy_pool").Info("代理已恢复", "proxy_id", proxy.ID, "proxy", proxy.Name, "latency_ms", result.Latency)
+	}
+	return result
+}
+
+// TestProxyURL 通过代理访问 Sora 测试连通性（%s 占位符使用随机 session ID）
+func TestProxyURL(ctx context.Context, proxyURL string) model.ProxyTestResponse {
+	client, err := sora.New(proxyURL)
+	if err != nil {
+		return model.ProxyTestResponse{Error: fmt.Sprintf("创建客户端失败: %v", err)}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, proxyCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	statusCode, err := client.TestConnectivity(ctx, proxyCheckTarget)
+	latency := time.Since(start).Milliseconds()
+	if err != nil {
+		return model.ProxyTestResponse{Error: fmt.Sprintf("连接失败: %v", err), Latency: latency}
+	}
+	return model.ProxyTestResponse{Success: true, StatusCode: statusCode, Latency: latency}
+}
@@ -2,65 +2,347 @@ package service
 
 import (
 	"errors"
-	"log"
+	"fmt"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/DouDOU-start/go-sora2api/server/logging"
 	"github.com/DouDOU-start/go-sora2api/server/model"
+	"github.com/DouDOU-start/go-sora2api/sora"
 	"gorm.io/gorm"
 )
 
 var ErrNoAvailableAccount = errors.New("没有可用的 Sora 账号")
 
+// ErrSentinelUnavailable 获取 Sentinel Token 失败
+var ErrSentinelUnavailable = errors.New("获取 Sentinel Token 失败")
+
+// 角色路由相关错误
+var (
+	ErrCharacterNotFound  = errors.New("角色不存在")
+	ErrCharacterNotReady  = errors.New("角色尚未就绪")
+	ErrCharacterConflict  = errors.New("所选角色属于不同账号，无法在同一任务中使用")
+	ErrCharacterForbidden = errors.New("角色所属账号不在当前 API Key 分组内")
+)
+
+// ErrAccountsBusy 可用账号的并发任务数均已达上限
+var ErrAccountsBusy = errors.New("账号并发任务数已满")
+
+// defaultRateLimitCooldown 上游未给出重置时间时的默认限流冷却时长（秒）
+const defaultRateLimitCooldown = 300
+
 // Scheduler 账号调度器
 type Scheduler struct {
-	db       *gorm.DB
-	mu       sync.Mutex
-	settings *SettingsStore
+	db      *gorm.DB
+	mu      sync.Mutex
+	clients *ClientProvider
+
+	inflight map[string]int64 // taskID → accountID，已分配账号且尚未结束的任务
+	load     map[int64]int    // accountID → 进行中的任务数
 }
 
 // NewScheduler 创建调度器
-func NewScheduler(db *gorm.DB, settings *SettingsStore) *Scheduler {
-	return &Scheduler{db: db, settings: settings}
+func NewScheduler(db *gorm.DB, clients *ClientProvider) *Scheduler {
+	return &Scheduler{
+		db:       db,
+		clients:  clients,
+		inflight: make(map[string]int64),
+		load:     make(map[int64]int),
+	}
 }
 
-// PickAccount 选取一个可用账号（最久未用优先），groupID 不为 nil 时仅从该分组选取
+// PickAccount 选取一个可用账号（进行中任务最少优先），groupID 不为 nil 时仅从该分组选取
 //
 // 筛选条件：
-//   - enabled=true 且 status=active
+//   - enabled=true 且 status 为 active 或 expiring_soon
 //   - remaining_count != 0（-1=未知视为可用，0=额度用完排除）
 //   - rate_limit_reached=false 或 rate_limit_resets_at < now()（限流已解除）
 //   - 若指定 groupID，则仅选取该分组的账号
+//   - 进行中任务数未达到账号的最大并发数（账号未设置时使用分组默认值）
 //
-// 排序：last_used_at ASC NULLS FIRST
-func (s *Scheduler) PickAccount(groupID *int64) (*model.SoraAccount, error) {
+// 排序：进行中任务数 ASC，其次 expiring_soon 排在 active 之后，再次 last_used_at ASC NULLS FIRST
+// exclude 中的账号不参与选取（提交失败换账号重试时使用）
+func (s *Scheduler) PickAccount(groupID *int64, exclude ...int64) (*model.SoraAccount, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	now := time.Now()
-	var account model.SoraAccount
+	account, err := s.pickLocked(groupID, exclude)
+	if err != nil {
+		return nil, err
+	}
+	s.db.Model(account).Update("last_used_at", time.Now())
+	return account, nil
+}
 
+// PickAccountForTask 为任务选取账号并计入账号的进行中任务数，任务结束或换账号前需调用 Release
+// refs 非空时解析角色引用（内部 char_xxx ID 或用户名）并使用角色所属账号，所有角色必须已就绪且属于同一账号
+func (s *Scheduler) PickAccountForTask(taskID string, groupID *int64, refs []string, exclude []int64) (*model.SoraAccount, []model.SoraCharacter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		account *model.SoraAccount
+		chars   []model.SoraCharacter
+		err     error
+	)
+	if len(refs) == 0 {
+		account, err = s.pickLocked(groupID, exclude)
+	} else {
+		account, chars, err = s.pickCharacterAccountLocked(groupID, refs)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.trackLocked(taskID, account.ID)
+	s.db.Model(account).Update("last_used_at", time.Now())
+	return account, chars, nil
+}
+
+// pickLocked 按筛选条件和负载选取账号（调用方持有 s.mu）
+func (s *Scheduler) pickLocked(groupID *int64, exclude []int64) (*model.SoraAccount, error) {
 	q := s.db.
-		Where("enabled = ? AND status = ?", true, model.AccountStatusActive).
+		Where("enabled = ? AND status IN ?", true, []string{model.AccountStatusActive, model.AccountStatusExpiringSoon}).
 		Where("remaining_count != 0"). // -1(未知) 或 >0 均可用
-		Where("rate_limit_reached = ? OR rate_limit_resets_at < ?", false, now)
+		Where("rate_limit_reached = ? OR rate_limit_resets_at < ?", false, time.Now())
 
 	if groupID != nil {
 		q = q.Where("group_id = ?", *groupID)
 	}
+	if len(exclude) > 0 {
+		q = q.Where("id NOT IN ?", exclude)
+	}
+
+	var candidates []model.SoraAccount
+	if err := q.Order(fmt.Sprintf("status = '%s'", model.AccountStatusExpiringSoon)).
+		Order("last_used_at ASC NULLS FIRST").
+		Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, ErrNoAvailableAccount
+	}
 
-	err := q.Order("last_used_at ASC NULLS FIRST").First(&account).Error
+	limits, err := s.concurrencyLimits(candidates)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, ErrNoAvailableAccount
+		return nil, err
+	}
+
+	best := s.leastLoadedLocked(candidates, limits)
+	if best < 0 {
+		return nil, ErrAccountsBusy
+	}
+	return &candidates[best], nil
+}
+
+// leastLoadedLocked 返回进行中任务数最少且未达并发上限的候选下标，全部已满时返回 -1（调用方持有 s.mu）
+// 候选已按优先级排序，负载相同时保持该顺序
+func (s *Scheduler) leastLoadedLocked(candidates []model.SoraAccount, limits map[int64]int) int {
+	best := -1
+	for i := range candidates {
+		load := s.load[candidates[i].ID]
+		if limit := limits[candidates[i].ID]; limit > 0 && load >= limit {
+			continue
 		}
+		if best < 0 || load < s.load[candidates[best].ID] {
+			best = i
+		}
+	}
+	return best
+}
+
+// pickCharacterAccountLocked 选取角色所属账号，账号不可调度或并发已满时返回错误（调用方持有 s.mu）
+func (s *Scheduler) pickCharacterAccountLocked(groupID *int64, refs []string) (*model.SoraAccount, []model.SoraCharacter, error) {
+	account, chars, err := s.characterAccount(groupID, refs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	if !account.Enabled || !account.Schedulable() || account.RemainingCount == 0 ||
+		(account.RateLimitReached && (account.RateLimitResetsAt == nil || !account.RateLimitResetsAt.Before(now))) {
+		return nil, nil, fmt.Errorf("%w: 角色所属账号 %s 当前不可用", ErrNoAvailableAccount, account.Email)
+	}
+
+	limits, err := s.concurrencyLimits([]model.SoraAccount{*account})
+	if err != nil {
+		return nil, nil, err
+	}
+	if limit := limits[account.ID]; limit > 0 && s.load[account.ID] >= limit {
+		return nil, nil, fmt.Errorf("%w: 角色所属账号 %s", ErrAccountsBusy, account.Email)
+	}
+	return account, chars, nil
+}
+
+// concurrencyLimits 返回账号的最大并发任务数（账号未设置时使用分组默认值，0 为不限制）
+func (s *Scheduler) concurrencyLimits(accounts []model.SoraAccount) (map[int64]int, error) {
+	limits := make(map[int64]int, len(accounts))
+	var groupIDs []int64
+	for _, a := range accounts {
+		limits[a.ID] = a.MaxConcurrent
+		if a.MaxConcurrent <= 0 && a.GroupID != nil {
+			groupIDs = append(groupIDs, *a.GroupID)
+		}
+	}
+	if len(groupIDs) == 0 {
+		return limits, nil
+	}
+
+	var groups []model.SoraAccountGroup
+	if err := s.db.Where("id IN ?", groupIDs).Find(&groups).Error; err != nil {
 		return nil, err
 	}
+	groupLimits := make(map[int64]int, len(groups))
+	for _, g := range groups {
+		groupLimits[g.ID] = g.MaxConcurrent
+	}
+	for _, a := range accounts {
+		if a.MaxConcurrent <= 0 && a.GroupID != nil {
+			limits[a.ID] = groupLimits[*a.GroupID]
+		}
+	}
+	return limits, nil
+}
+
+// trackLocked 记录任务占用账号（同一任务重复记录时先释放原账号）
+func (s *Scheduler) trackLocked(taskID string, accountID int64) {
+	s.releaseLocked(taskID)
+	s.inflight[taskID] = accountID
+	s.load[accountID]++
+}
+
+// Release 释放任务占用的账号并发（任务结束、提交失败换账号时调用），未记录的任务忽略
+func (s *Scheduler) Release(taskID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.releaseLocked(taskID)
+}
 
-	// 更新最后使用时间
-	s.db.Model(&account).Update("last_used_at", now)
+func (s *Scheduler) releaseLocked(taskID string) {
+	accountID, ok := s.inflight[taskID]
+	if !ok {
+		return
+	}
+	delete(s.inflight, taskID)
+	if s.load[accountID]--; s.load[accountID] <= 0 {
+		delete(s.load, accountID)
+	}
+}
 
-	return &account, nil
+// InFlight 返回账号进行中的任务数
+func (s *Scheduler) InFlight(accountID int64) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load[accountID]
+}
+
+// ReconcileInFlight 根据 sora_tasks 中提交中和进行中的任务重建各账号的进行中任务数（服务启动时调用）
+func (s *Scheduler) ReconcileInFlight() error {
+	var tasks []model.SoraTask
+	if err := s.db.Select("id", "account_id").
+		Where("status IN ? AND account_id > 0", []string{model.TaskStatusSubmitting, model.TaskStatusInProgress}).
+		Find(&tasks).Error; err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inflight = make(map[string]int64, len(tasks))
+	s.load = make(map[int64]int)
+	for _, t := range tasks {
+		s.trackLocked(t.ID, t.AccountID)
+	}
+	logging.For("scheduler").Info("已重建账号进行中任务数", "tasks", len(tasks), "accounts", len(s.load))
+	return nil
+}
+
+// CheckCharacters 校验角色引用能否在 groupID 分组下使用（不检查所属账号当前是否可调度），refs 为空时返回 nil
+func (s *Scheduler) CheckCharacters(groupID *int64, refs []string) error {
+	if len(refs) == 0 {
+		return nil
+	}
+	_, _, err := s.characterAccount(groupID, refs)
+	return err
+}
+
+// characterAccount 解析角色引用并加载所属账号，要求角色属于同一账号且账号在 groupID 分组内
+func (s *Scheduler) characterAccount(groupID *int64, refs []string) (*model.SoraAccount, []model.SoraCharacter, error) {
+	chars, err := s.resolveCharacters(refs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	accountID := chars[0].AccountID
+	for _, ch := range chars[1:] {
+		if ch.AccountID != accountID {
+			return nil, nil, ErrCharacterConflict
+		}
+	}
+
+	var account model.SoraAccount
+	if err := s.db.Where("id = ?", accountID).First(&account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrNoAvailableAccount
+		}
+		return nil, nil, err
+	}
+	if groupID != nil && (account.GroupID == nil || *account.GroupID != *groupID) {
+		return nil, nil, ErrCharacterForbidden
+	}
+	return &account, chars, nil
+}
+
+// resolveCharacters 将角色引用解析为已就绪的角色记录（保持输入顺序，去重）
+func (s *Scheduler) resolveCharacters(refs []string) ([]model.SoraCharacter, error) {
+	seen := make(map[string]bool, len(refs))
+	chars := make([]model.SoraCharacter, 0, len(refs))
+	for _, ref := range refs {
+		ref = strings.TrimPrefix(strings.TrimSpace(ref), "@")
+		if ref == "" {
+			continue
+		}
+
+		var ch model.SoraCharacter
+		q := s.db.Where("id = ?", ref)
+		if !strings.HasPrefix(ref, "char_") {
+			q = s.db.Where("username = ?", ref).Order("created_at DESC")
+		}
+		if err := q.First(&ch).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("%w: %s", ErrCharacterNotFound, ref)
+			}
+			return nil, err
+		}
+		if ch.Status != model.CharacterStatusReady || ch.CharacterID == "" {
+			return nil, fmt.Errorf("%w: %s（当前状态: %s）", ErrCharacterNotReady, ref, ch.Status)
+		}
+		if seen[ch.ID] {
+			continue
+		}
+		seen[ch.ID] = true
+		chars = append(chars, ch)
+	}
+	if len(chars) == 0 {
+		return nil, fmt.Errorf("%w: 未指定有效的角色", ErrCharacterNotFound)
+	}
+	return chars, nil
+}
+
+// ReportSubmitError 根据提交时的上游错误更新账号状态：Token 失效标记 token_expired，限流标记冷却时间
+func (s *Scheduler) ReportSubmitError(accountID int64, err error) {
+	switch {
+	case errors.Is(err, sora.ErrUnauthorized):
+		s.MarkAccountError(accountID, model.AccountStatusTokenExpired, err.Error())
+	case errors.Is(err, sora.ErrRateLimited):
+		s.MarkRateLimited(accountID, sora.RetryAfterSeconds(err))
+	}
+}
+
+// IsFailoverError 判断提交错误是否由账号本身导致（Token 失效、限流、Sentinel 被拒或获取失败），可换账号重试
+func IsFailoverError(err error) bool {
+	return errors.Is(err, sora.ErrUnauthorized) || errors.Is(err, sora.ErrRateLimited) ||
+		errors.Is(err, sora.ErrSentinelRejected) || errors.Is(err, ErrSentinelUnavailable)
 }
 
 // MarkAccountError 标记账号错误状态
@@ -70,23 +352,27 @@ func (s *Scheduler) MarkAccountError(accountID int64, status, lastError string)
 			"status":     status,
 			"last_error": lastError,
 		}).Error; err != nil {
-		log.Printf("[scheduler] 更新账号 %d 状态失败: %v", accountID, err)
+		logging.For("scheduler").Error("更新账号状态失败", "account_id", accountID, "err", err)
 	}
 }
 
-// MarkRateLimited 标记账号限流
+// MarkRateLimited 标记账号限流，resetsInSec 为上游给出的重置时间（<=0 时使用默认冷却时长）
 func (s *Scheduler) MarkRateLimited(accountID int64, resetsInSec int) {
+	if resetsInSec <= 0 {
+		resetsInSec = defaultRateLimitCooldown
+	}
 	resetsAt := time.Now().Add(time.Duration(resetsInSec) * time.Second)
 	if err := s.db.Model(&model.SoraAccount{}).Where("id = ?", accountID).
 		Updates(map[string]interface{}{
 			"rate_limit_reached":  true,
 			"rate_limit_resets_at": resetsAt,
 		}).Error; err != nil {
-		log.Printf("[scheduler] 标记账号 %d 限流失败: %v", accountID, err)
+		logging.For("scheduler").Error("标记账号限流失败", "account_id", accountID, "err", err)
 	}
 }
 
-// GetProxyURL 返回全局代理 URL（动态从设置读取）
-func (s *Scheduler) GetProxyURL() string {
-	return s.settings.GetProxyURL()
+// NewClient 返回账号使用的 Sora 客户端（由客户端缓存复用，按账号选取代理 session 和指纹 profile）
+// account 为 nil 时使用默认选取
+func (s *Scheduler) NewClient(account *model.SoraAccount) (*sora.Client, error) {
+	return s.clients.Get(account)
 }
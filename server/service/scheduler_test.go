@@ -0,0 +1,130 @@
+package service
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/DouDOU-start/go-sora2api/server/model"
+)
+
+func newTestScheduler() *Scheduler {
+	return NewScheduler(nil, nil)
+}
+
+func TestSchedulerInFlight(t *testing.T) {
+	s := newTestScheduler()
+
+	s.mu.Lock()
+	s.trackLocked("t1", 1)
+	s.trackLocked("t2", 1)
+	s.trackLocked("t3", 2)
+	s.mu.Unlock()
+	if got := s.InFlight(1); got != 2 {
+		t.Fatalf("账号 1 进行中 = %d, want 2", got)
+	}
+
+	// 换账号重新记录时释放原账号
+	s.mu.Lock()
+	s.trackLocked("t2", 2)
+	s.mu.Unlock()
+	if a, b := s.InFlight(1), s.InFlight(2); a != 1 || b != 2 {
+		t.Fatalf("换账号后进行中 = %d/%d, want 1/2", a, b)
+	}
+
+	// Release 幂等，未记录的任务忽略
+	s.Release("t1")
+	s.Release("t1")
+	s.Release("unknown")
+	if got := s.InFlight(1); got != 0 {
+		t.Fatalf("释放后账号 1 进行中 = %d, want 0", got)
+	}
+	if _, ok := s.load[1]; ok {
+		t.Error("负载归零的账号应从 load 中删除")
+	}
+
+	s.Release("t2")
+	s.Release("t3")
+	if len(s.inflight) != 0 || len(s.load) != 0 {
+		t.Errorf("全部释放后 inflight=%v load=%v", s.inflight, s.load)
+	}
+}
+
+func TestSchedulerLeastLoaded(t *testing.T) {
+	candidates := []model.SoraAccount{{ID: 1}, {ID: 2}, {ID: 3}}
+
+	tests := []struct {
+		name   string
+		load   map[int64]int
+		limits map[int64]int
+		want   int64 // 0 表示全部已满
+	}{
+		{"无负载保持候选顺序", nil, nil, 1},
+		{"选负载最少", map[int64]int{1: 2, 2: 1, 3: 1}, nil, 2},
+		{"不限制时不跳过", map[int64]int{1: 5, 2: 5, 3: 5}, map[int64]int{1: 0}, 1},
+		{"跳过已达上限", map[int64]int{1: 1, 2: 3, 3: 2}, map[int64]int{1: 1, 3: 5}, 3},
+		{"上限内负载更低者优先", map[int64]int{1: 0, 2: 0, 3: 0}, map[int64]int{1: 1, 2: 1, 3: 1}, 1},
+		{"全部已满", map[int64]int{1: 2, 2: 1, 3: 1}, map[int64]int{1: 2, 2: 1, 3: 1}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestScheduler()
+			for id, n := range tt.load {
+				s.load[id] = n
+			}
+			var got int64
+			if i := s.leastLoadedLocked(candidates, tt.limits); i >= 0 {
+				got = candidates[i].ID
+			}
+			if got != tt.want {
+				t.Errorf("选中账号 %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSchedulerMaxConcurrent 按 max_concurrent 逐个分配任务，满载后返回 -1，释放后恢复可选
+func TestSchedulerMaxConcurrent(t *testing.T) {
+	s := newTestScheduler()
+	candidates := []model.SoraAccount{{ID: 1, MaxConcurrent: 2}, {ID: 2, MaxConcurrent: 1}}
+	limits, err := s.concurrencyLimits(candidates)
+	if err != nil {
+		t.Fatalf("concurrencyLimits: %v", err)
+	}
+
+	var picked []int64
+	for _, taskID := range []string{"t1", "t2", "t3", "t4"} {
+		i := s.leastLoadedLocked(candidates, limits)
+		if i < 0 {
+			picked = append(picked, 0)
+			continue
+		}
+		picked = append(picked, candidates[i].ID)
+		s.trackLocked(taskID, candidates[i].ID)
+	}
+	if want := []int64{1, 2, 1, 0}; !slices.Equal(picked, want) {
+		t.Fatalf("分配顺序 = %v, want %v", picked, want)
+	}
+
+	s.Release("t2")
+	if i := s.leastLoadedLocked(candidates, limits); i < 0 || candidates[i].ID != 2 {
+		t.Errorf("释放后应选中账号 2，got 下标 %d", i)
+	}
+}
+
+func TestConcurrencyLimitsWithoutGroup(t *testing.T) {
+	s := newTestScheduler()
+	limits, err := s.concurrencyLimits([]model.SoraAccount{
+		{ID: 1, MaxConcurrent: 3},
+		{ID: 2},
+		{ID: 3, MaxConcurrent: -1},
+	})
+	if err != nil {
+		t.Fatalf("concurrencyLimits: %v", err)
+	}
+	want := map[int64]int{1: 3, 2: 0, 3: -1}
+	for id, n := range want {
+		if limits[id] != n {
+			t.Errorf("账号 %d 上限 = %d, want %d", id, limits[id], n)
+		}
+	}
+}
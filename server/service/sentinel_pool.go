@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DouDOU-start/go-sora2api/server/logging"
+	"github.com/DouDOU-start/go-sora2api/server/model"
+	"github.com/DouDOU-start/go-sora2api/sora"
+	"gorm.io/gorm"
+)
+
+// sentinelRefillInterval 后台补充预热池的最长间隔（TTL 更短时按 TTL 的一半）
+const sentinelRefillInterval = 30 * time.Second
+
+// pooledSentinel 预热好的 Sentinel Token
+type pooledSentinel struct {
+	token       string
+	accessToken string // 生成时使用的 AT，账号 AT 刷新后作废
+	expiresAt   time.Time
+}
+
+// SentinelPool 按账号预热 Sentinel Token，提交任务时直接取用，省去 sentinel 请求和 PoW 耗时
+type SentinelPool struct {
+	db        *gorm.DB
+	scheduler *Scheduler
+	settings  *SettingsStore
+
+	mu     sync.Mutex
+	tokens map[int64][]pooledSentinel // accountID → 预热 Token（先进先出）
+	wake   chan struct{}
+
+	hits           atomic.Int64
+	misses         atomic.Int64
+	refills        atomic.Int64
+	refillFailures atomic.Int64
+	expired        atomic.Int64
+	invalidated    atomic.Int64
+}
+
+// NewSentinelPool 创建 Sentinel Token 预热池
+func NewSentinelPool(db *gorm.DB, scheduler *Scheduler, settings *SettingsStore) *SentinelPool {
+	return &SentinelPool{
+		db:        db,
+		scheduler: scheduler,
+		settings:  settings,
+		tokens:    make(map[int64][]pooledSentinel),
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// Start 启动后台预热
+func (p *SentinelPool) Start(ctx context.Context) {
+	go p.refillLoop(ctx)
+	cfg := p.settings.GetSentinelPoolConfig()
+	logging.For("sentinel_pool").Info("后台预热已启动", "size", cfg.Size, "ttl", cfg.TTL)
+}
+
+// Get 取出账号的一个预热 Token，池中没有可用 Token 时使用 client 内联生成
+func (p *SentinelPool) Get(ctx context.Context, client *sora.Client, account *model.SoraAccount) (string, error) {
+	token, ok := p.take(account)
+	p.notify()
+	if ok {
+		p.hits.Add(1)
+		return token, nil
+	}
+	p.misses.Add(1)
+	return client.GenerateSentinelToken(ctx, account.AccessToken)
+}
+
+// Invalidate 作废账号的全部预热 Token（上游拒绝 Sentinel Token 时调用）
+func (p *SentinelPool) Invalidate(accountID int64) {
+	p.mu.Lock()
+	n := len(p.tokens[accountID])
+	delete(p.tokens, accountID)
+	p.mu.Unlock()
+
+	if n > 0 {
+		p.invalidated.Add(int64(n))
+		logging.For("sentinel_pool").Info("预热 Token 已作废", "account_id", accountID, "count", n)
+	}
+	p.notify()
+}
+
+// Stats 返回预热池统计
+func (p *SentinelPool) Stats() model.SentinelPoolStats {
+	cfg := p.settings.GetSentinelPoolConfig()
+	stats := model.SentinelPoolStats{
+		PoolSize:       cfg.Size,
+		TTL:            cfg.TTL.String(),
+		Hits:           p.hits.Load(),
+		Misses:         p.misses.Load(),
+		Refills:        p.refills.Load(),
+		RefillFailures: p.refillFailures.Load(),
+		Expired:        p.expired.Load(),
+		Invalidated:    p.invalidated.Load(),
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+
+	now := time.Now()
+	p.mu.Lock()
+	for _, queue := range p.tokens {
+		valid := 0
+		for _, t := range queue {
+			if now.Before(t.expiresAt) {
+				valid++
+			}
+		}
+		if valid > 0 {
+			stats.Pooled += valid
+			stats.Accounts++
+		}
+	}
+	p.mu.Unlock()
+	return stats
+}
+
+// take 弹出一个未过期且与当前 AT 匹配的 Token，顺带丢弃失效的 Token
+func (p *SentinelPool) take(account *model.SoraAccount) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	queue := p.tokens[account.ID]
+	for len(queue) > 0 {
+		t := queue[0]
+		queue = queue[1:]
+		if now.Before(t.expiresAt) && t.accessToken == account.AccessToken {
+			p.tokens[account.ID] = queue
+			return t.token, true
+		}
+		p.expired.Add(1)
+	}
+	delete(p.tokens, account.ID)
+	return "", false
+}
+
+// notify 唤醒后台补充（非阻塞）
+func (p *SentinelPool) notify() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// refillLoop 后台补充循环：定时或取用后被唤醒
+func (p *SentinelPool) refillLoop(ctx context.Context) {
+	for {
+		p.refillAll(ctx)
+
+		interval := sentinelRefillInterval
+		if ttl := p.settings.GetSentinelPoolConfig().TTL; ttl/2 < interval {
+			interval = max(ttl/2, time.Second)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.wake:
+		case <-time.After(interval):
+		}
+	}
+}
+
+// refillAll 清理失效 Token，并为每个可用账号补足到目标数量
+func (p *SentinelPool) refillAll(ctx context.Context) {
+	var accounts []model.SoraAccount
+	if err := p.db.Where("enabled = ? AND status IN ?", true,
+		[]string{model.AccountStatusActive, model.AccountStatusExpiringSoon}).Find(&accounts).Error; err != nil {
+		logging.For("sentinel_pool").Error("查询账号失败", "err", err)
+		return
+	}
+
+	cfg := p.settings.GetSentinelPoolConfig()
+	p.prune(accounts, cfg.Size)
+	if cfg.Size <= 0 || len(accounts) == 0 {
+		return
+	}
+
+	for i := range accounts {
+		acc := &accounts[i]
+		if p.count(acc.ID) >= cfg.Size {
+			continue
+		}
+		client, err := p.scheduler.NewClient(acc)
+		if err != nil {
+			logging.For("sentinel_pool").Error("创建 Sora 客户端失败", "account_id", acc.ID, "err", err)
+			continue
+		}
+		for p.count(acc.ID) < cfg.Size {
+			if ctx.Err() != nil {
+				return
+			}
+			token, err := client.GenerateSentinelToken(AccountContext(ctx, acc.ID), acc.AccessToken)
+			if err != nil {
+				p.refillFailures.Add(1)
+				logging.For("sentinel_pool").Warn("预热失败", "account_id", acc.ID, "email", acc.Email, "err", err)
+				break
+			}
+			p.put(acc.ID, pooledSentinel{
+				token:       token,
+				accessToken: acc.AccessToken,
+				expiresAt:   time.Now().Add(cfg.TTL),
+			})
+			p.refills.Add(1)
+		}
+	}
+}
+
+// prune 移除不可用账号、已过期、AT 已变更或超出目标数量的 Token
+func (p *SentinelPool) prune(accounts []model.SoraAccount, size int) {
+	activeAT := make(map[int64]string, len(accounts))
+	for _, acc := range accounts {
+		activeAT[acc.ID] = acc.AccessToken
+	}
+
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for accountID, queue := range p.tokens {
+		at, ok := activeAT[accountID]
+		kept := queue[:0]
+		for _, t := range queue {
+			switch {
+			case !ok || t.accessToken != at || !now.Before(t.expiresAt):
+				p.expired.Add(1)
+			case len(kept) < size:
+				kept = append(kept, t)
+			}
+			// 调小池大小后超出目标数量的 Token 直接丢弃，不计入过期数
+		}
+		if len(kept) == 0 {
+			delete(p.tokens, accountID)
+		} else {
+			p.tokens[accountID] = kept
+		}
+	}
+}
+
+// count 账号当前池中的 Token 数
+func (p *SentinelPool) count(accountID int64) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.tokens[accountID])
+}
+
+// put 将 Token 加入账号队列
+func (p *SentinelPool) put(accountID int64, t pooledSentinel) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.tokens[accountID] = append(p.tokens[accountID], t)
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DouDOU-start/go-sora2api/server/model"
+)
+
+func TestSentinelPoolPrune(t *testing.T) {
+	now := time.Now()
+	fresh := func(at string) pooledSentinel {
+		return pooledSentinel{token: "t", accessToken: at, expiresAt: now.Add(time.Minute)}
+	}
+
+	tests := []struct {
+		name        string
+		tokens      map[int64][]pooledSentinel
+		size        int
+		wantKept    map[int64]int
+		wantExpired int64
+	}{
+		{
+			name:     "全部保留",
+			tokens:   map[int64][]pooledSentinel{1: {fresh("at1"), fresh("at1")}},
+			size:     2,
+			wantKept: map[int64]int{1: 2},
+		},
+		{
+			name:        "过期和 AT 变更计入过期数",
+			tokens:      map[int64][]pooledSentinel{1: {fresh("at1"), fresh("old"), {accessToken: "at1", expiresAt: now.Add(-time.Second)}}},
+			size:        3,
+			wantKept:    map[int64]int{1: 1},
+			wantExpired: 2,
+		},
+		{
+			name:        "账号不可用计入过期数",
+			tokens:      map[int64][]pooledSentinel{9: {fresh("at9")}},
+			size:        2,
+			wantKept:    map[int64]int{},
+			wantExpired: 1,
+		},
+		{
+			name:     "调小池大小丢弃的不计入过期数",
+			tokens:   map[int64][]pooledSentinel{1: {fresh("at1"), fresh("at1"), fresh("at1")}},
+			size:     1,
+			wantKept: map[int64]int{1: 1},
+		},
+	}
+	accounts := []model.SoraAccount{{ID: 1, AccessToken: "at1"}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewSentinelPool(nil, nil, nil)
+			p.tokens = tt.tokens
+			p.prune(accounts, tt.size)
+
+			if len(p.tokens) != len(tt.wantKept) {
+				t.Errorf("剩余账号数 = %d, want %d", len(p.tokens), len(tt.wantKept))
+			}
+			for id, n := range tt.wantKept {
+				if got := p.count(id); got != n {
+					t.Errorf("账号 %d 剩余 %d 个, want %d", id, got, n)
+				}
+			}
+			if got := p.expired.Load(); got != tt.wantExpired {
+				t.Errorf("expired = %d, want %d", got, tt.wantExpired)
+			}
+		})
+	}
+}
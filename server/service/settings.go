@@ -1,11 +1,13 @@
 package service
 
 import (
-	"log"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/DouDOU-start/go-sora2api/server/logging"
 	"github.com/DouDOU-start/go-sora2api/server/model"
+	"github.com/DouDOU-start/go-sora2api/sora"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
@@ -80,10 +82,24 @@ func (s *SettingsStore) GetProxyURL() string {
 	return s.Get(model.SettingProxyURL)
 }
 
+// GetProxyCheckInterval 获取代理池健康检查间隔（默认 5 分钟，最短 30 秒）
+func (s *SettingsStore) GetProxyCheckInterval() time.Duration {
+	d, err := time.ParseDuration(s.Get(model.SettingProxyCheckInterval))
+	if err != nil {
+		return 5 * time.Minute
+	}
+	if d < 30*time.Second {
+		return 30 * time.Second
+	}
+	return d
+}
+
 // GetSyncConfig 获取同步配置
 func (s *SettingsStore) GetSyncConfig() *SyncConfig {
 	cfg := &SyncConfig{
 		TokenRefreshInterval:     30 * time.Minute,
+		TokenRefreshAhead:        time.Hour,
+		TokenExpiringSoon:        24 * time.Hour,
 		CreditSyncInterval:       10 * time.Minute,
 		SubscriptionSyncInterval: 6 * time.Hour,
 	}
@@ -93,6 +109,16 @@ func (s *SettingsStore) GetSyncConfig() *SyncConfig {
 			cfg.TokenRefreshInterval = d
 		}
 	}
+	if v := s.Get(model.SettingTokenRefreshAhead); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			cfg.TokenRefreshAhead = d
+		}
+	}
+	if v := s.Get(model.SettingTokenExpiringSoon); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			cfg.TokenExpiringSoon = d
+		}
+	}
 	if v := s.Get(model.SettingCreditSyncInterval); v != "" {
 		if d, err := time.ParseDuration(v); err == nil {
 			cfg.CreditSyncInterval = d
@@ -106,11 +132,126 @@ func (s *SettingsStore) GetSyncConfig() *SyncConfig {
 	return cfg
 }
 
+// SentinelPoolConfig Sentinel Token 预热池配置
+type SentinelPoolConfig struct {
+	Size int           // 每个账号预热的 Token 数，0 为关闭
+	TTL  time.Duration // 预热 Token 有效期
+}
+
+// GetSentinelPoolConfig 获取 Sentinel Token 预热池配置
+func (s *SettingsStore) GetSentinelPoolConfig() *SentinelPoolConfig {
+	cfg := &SentinelPoolConfig{
+		Size: 2,
+		TTL:  5 * time.Minute,
+	}
+
+	if v := s.Get(model.SettingSentinelPoolSize); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.Size = n
+		}
+	}
+	if v := s.Get(model.SettingSentinelTTL); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			cfg.TTL = d
+		}
+	}
+	return cfg
+}
+
+// GetFingerprintProfiles 获取 PoW 指纹 profile 列表，未配置或解析失败时返回 nil（使用内置默认）
+func (s *SettingsStore) GetFingerprintProfiles() []sora.FingerprintProfile {
+	v := s.Get(model.SettingFingerprintProfiles)
+	if v == "" {
+		return nil
+	}
+	profiles, err := sora.ParseFingerprintProfiles([]byte(v))
+	if err != nil {
+		logging.For("settings").Warn("指纹配置无效，使用内置默认指纹", "err", err)
+		return nil
+	}
+	return profiles
+}
+
+// FingerprintFor 按 key（账号 ID）选取稳定的指纹 profile
+func (s *SettingsStore) FingerprintFor(key string) sora.FingerprintProfile {
+	return sora.PickFingerprint(s.GetFingerprintProfiles(), key)
+}
+
+// GetMaxUploadSize 获取单个上传文件大小上限（字节），0 表示不限制
+func (s *SettingsStore) GetMaxUploadSize() int64 {
+	if v := s.Get(model.SettingMaxUploadSize); v != "" {
+		if mb, err := strconv.ParseInt(v, 10, 64); err == nil && mb >= 0 {
+			return mb << 20
+		}
+	}
+	return sora.DefaultMaxUploadSize
+}
+
+// GetAccountRateLimit 获取单个账号请求上游的限流配置（每秒请求数，0 为不限制；突发数）
+func (s *SettingsStore) GetAccountRateLimit() (float64, int) {
+	rps, burst := 0.0, 5
+	if v := s.Get(model.SettingAccountRateLimit); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 {
+			rps = f
+		}
+	}
+	if v := s.Get(model.SettingAccountRateBurst); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			burst = n
+		}
+	}
+	return rps, burst
+}
+
+// GetSubmitWorkers 获取后台同时向上游提交任务的数量，默认 4
+func (s *SettingsStore) GetSubmitWorkers() int {
+	if v := s.Get(model.SettingSubmitWorkers); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 4
+}
+
+// GetSubmitMaxAttempts 获取提交因账号问题失败时最多尝试的账号数，默认 3
+func (s *SettingsStore) GetSubmitMaxAttempts() int {
+	if v := s.Get(model.SettingSubmitMaxAttempts); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 3
+}
+
+// GetMediaOptions 获取媒体下载的超时与大小限制，未配置或格式错误的项使用默认值
+func (s *SettingsStore) GetMediaOptions() sora.MediaOptions {
+	opts := sora.MediaOptions{
+		ConnectTimeout: sora.DefaultMediaConnectTimeout,
+		ReadTimeout:    sora.DefaultMediaReadTimeout,
+	}
+	if v := s.Get(model.SettingMediaConnectTimeout); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			opts.ConnectTimeout = d
+		}
+	}
+	if v := s.Get(model.SettingMediaReadTimeout); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			opts.ReadTimeout = d
+		}
+	}
+	if v := s.Get(model.SettingMaxDownloadSize); v != "" {
+		if mb, err := strconv.ParseInt(v, 10, 64); err == nil && mb >= 0 {
+			opts.MaxSize = mb << 20
+		}
+	}
+	return opts
+}
+
 // loadAll 从数据库加载所有设置到缓存
 func (s *SettingsStore) loadAll() {
 	var settings []model.SoraSetting
 	if err := s.db.Find(&settings).Error; err != nil {
-		log.Printf("[settings] 加载设置失败: %v", err)
+		logging.For("settings").Error("加载设置失败", "err", err)
 		return
 	}
 
@@ -0,0 +1,364 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/DouDOU-start/go-sora2api/server/logging"
+	"github.com/DouDOU-start/go-sora2api/server/model"
+	"github.com/DouDOU-start/go-sora2api/sora"
+)
+
+// 提交队列相关参数
+const (
+	submitTimeout        = 5 * time.Minute  // 单个任务从选取账号到提交完成的超时
+	submitAccountWait    = 10 * time.Minute // 无可用账号时任务在队列中等待的最长时间
+	submitAccountBackoff = 10 * time.Second // 无可用账号时重新入队的间隔
+)
+
+// submitQueue 待提交任务队列（任务本身持久化在 sora_tasks，队列只保存任务 ID）
+type submitQueue struct {
+	mu      sync.Mutex
+	pending []string      // 等待提交的任务 ID（先进先出）
+	notify  chan struct{} // 有新任务入队
+	freed   chan struct{} // 有 worker 空闲
+	active  int           // 正在提交的任务数
+}
+
+func newSubmitQueue() *submitQueue {
+	return &submitQueue{notify: make(chan struct{}, 1), freed: make(chan struct{}, 1)}
+}
+
+// push 将任务加入队尾
+func (q *submitQueue) push(taskID string) {
+	q.mu.Lock()
+	q.pending = append(q.pending, taskID)
+	q.mu.Unlock()
+	signal(q.notify)
+}
+
+// pop 取出队首任务
+func (q *submitQueue) pop() (string, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.pending) == 0 {
+		return "", false
+	}
+	id := q.pending[0]
+	q.pending = q.pending[1:]
+	return id, true
+}
+
+// acquire 在 worker 数未达上限时占用一个，返回是否成功
+func (q *submitQueue) acquire(limit int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.active >= limit {
+		return false
+	}
+	q.active++
+	return true
+}
+
+// release 释放 worker
+func (q *submitQueue) release() {
+	q.mu.Lock()
+	q.active--
+	q.mu.Unlock()
+	signal(q.freed)
+}
+
+// signal 非阻塞地发出通知
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// Submit 保存任务（状态为 queued，携带提交参数）并加入提交队列，由后台 worker 选取账号并提交到上游
+func (ts *TaskStore) Submit(task *model.SoraTask) error {
+	task.Status = model.TaskStatusQueued
+	if err := ts.db.Create(task).Error; err != nil {
+		return err
+	}
+	ts.queue.push(task.ID)
+	return nil
+}
+
+// dispatch 从队列取出任务交给 worker，同时提交的数量不超过 submit_workers 设置（修改后立即生效）
+func (ts *TaskStore) dispatch(ctx context.Context) {
+	for {
+		taskID, ok := ts.queue.pop()
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ts.queue.notify:
+			}
+			continue
+		}
+
+		for !ts.queue.acquire(ts.settings.GetSubmitWorkers()) {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ts.queue.freed:
+			}
+		}
+		go func() {
+			defer ts.queue.release()
+			ts.submit(taskID)
+		}()
+	}
+}
+
+// requeue 稍后将任务重新入队
+func (ts *TaskStore) requeue(taskID string, delay time.Duration) {
+	time.AfterFunc(delay, func() {
+		if ts.ctx.Err() == nil {
+			ts.queue.push(taskID)
+		}
+	})
+}
+
+// submit 选取账号 → 获取 Sentinel Token → 上传参考图 → 创建上游任务 → 开始轮询
+// 因账号问题（Token 失效、限流、Sentinel 错误）失败时排除该账号换一个重试，最多尝试 submit_max_attempts 个账号；
+// 指定了角色的任务只能使用角色所属账号，不换账号；重新入队的任务沿用已记录的失败尝试，不重复使用失败过的账号
+func (ts *TaskStore) submit(taskID string) {
+	task, err := ts.Get(taskID)
+	if err != nil {
+		logging.For("submit").Error("加载排队任务失败", "task_id", taskID, "err", err)
+		return
+	}
+	if task.Status != model.TaskStatusQueued {
+		return
+	}
+	sub := task.Submission
+	maxAttempts := ts.settings.GetSubmitMaxAttempts()
+
+	exclude, lastErr := failedAttempts(task.Attempts)
+	if len(exclude) >= maxAttempts {
+		ts.failTask(task.ID, fmt.Sprintf("提交 Sora 任务失败（已尝试 %d 个账号）: %v", len(exclude), lastErr))
+		return
+	}
+	for {
+		account, chars, err := ts.scheduler.PickAccountForTask(task.ID, sub.GroupID, sub.Characters, exclude)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrAccountsBusy):
+				// 账号并发已满不计入等待时长，等待已有任务结束后再提交
+				logging.For("submit").Info("账号并发任务数已满，稍后重试", "task_id", task.ID, "err", err)
+				ts.requeue(task.ID, submitAccountBackoff)
+			case lastErr != nil:
+				ts.failTask(task.ID, fmt.Sprintf("提交 Sora 任务失败（已尝试 %d 个账号）: %v", len(exclude), lastErr))
+			case errors.Is(err, ErrNoAvailableAccount) && time.Since(task.CreatedAt) < submitAccountWait:
+				logging.For("submit").Warn("暂无可用账号，稍后重试", "task_id", task.ID, "err", err)
+				ts.requeue(task.ID, submitAccountBackoff)
+			default:
+				ts.failTask(task.ID, fmt.Sprintf("无可用账号: %v", err))
+			}
+			return
+		}
+
+		soraTaskID, err := ts.submitWith(task, account, chars)
+		ts.recordAttempt(task, account, err)
+		if err == nil {
+			ts.StartPolling(task, account)
+			logging.For("submit").Info("任务已提交", "task_id", task.ID, "sora_task_id", soraTaskID, "kind", sub.Kind,
+				"account_id", account.ID, "email", account.Email, "model", task.Model, "attempts", len(task.Attempts))
+			return
+		}
+
+		ts.scheduler.Release(task.ID)
+		ts.reportSubmitError(account, err)
+		exclude = append(exclude, account.ID)
+		lastErr = err
+		if !IsFailoverError(err) || len(chars) > 0 || len(exclude) >= maxAttempts {
+			ts.failTask(task.ID, fmt.Sprintf("提交 Sora 任务失败: %v", err))
+			return
+		}
+		logging.For("submit").Warn("提交失败，换账号重试", "task_id", task.ID, "account_id", account.ID,
+			"email", account.Email, "attempt", len(exclude), "err", err)
+	}
+}
+
+// failedAttempts 从已记录的提交尝试中恢复失败过的账号（去重）和最后一次错误
+func failedAttempts(attempts model.TaskAttempts) ([]int64, error) {
+	var exclude []int64
+	var lastErr error
+	seen := make(map[int64]bool, len(attempts))
+	for _, a := range attempts {
+		if a.Error == "" {
+			continue
+		}
+		lastErr = errors.New(a.Error)
+		if !seen[a.AccountID] {
+			seen[a.AccountID] = true
+			exclude = append(exclude, a.AccountID)
+		}
+	}
+	return exclude, lastErr
+}
+
+// submitWith 使用指定账号提交任务，成功时记录上游任务 ID 并清空提交参数（参考图可能是较大的 data URI）
+func (ts *TaskStore) submitWith(task *model.SoraTask, account *model.SoraAccount, chars []model.SoraCharacter) (string, error) {
+	client, err := ts.scheduler.NewClient(account)
+	if err != nil {
+		return "", fmt.Errorf("创建 Sora 客户端失败: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(AccountContext(ts.ctx, account.ID), submitTimeout)
+	defer cancel()
+
+	soraTaskID, err := ts.createUpstream(ctx, client, account, task, chars)
+	if err != nil {
+		return "", err
+	}
+
+	task.SoraTaskID = soraTaskID
+	task.AccountID = account.ID
+	if err := ts.db.Model(&model.SoraTask{}).Where("id = ?", task.ID).Updates(map[string]interface{}{
+		"sora_task_id": soraTaskID,
+		"account_id":   account.ID,
+		"submission":   model.TaskSubmission{},
+	}).Error; err != nil {
+		logging.For("submit").Error("保存上游任务 ID 失败", "task_id", task.ID, "sora_task_id", soraTaskID, "err", err)
+	}
+	return soraTaskID, nil
+}
+
+// recordAttempt 记录一次提交尝试；失败时任务退回 queued（上游未受理，重启后可安全重新提交）
+func (ts *TaskStore) recordAttempt(task *model.SoraTask, account *model.SoraAccount, err error) {
+	attempt := model.TaskAttempt{AccountID: account.ID, Email: account.Email, At: time.Now()}
+	updates := map[string]interface{}{}
+	if err != nil {
+		attempt.Error = err.Error()
+		updates["status"] = model.TaskStatusQueued
+	}
+	task.Attempts = append(task.Attempts, attempt)
+	updates["attempts"] = task.Attempts
+	if err := ts.db.Model(&model.SoraTask{}).Where("id = ?", task.ID).Updates(updates).Error; err != nil {
+		logging.For("submit").Error("保存提交记录失败", "task_id", task.ID, "err", err)
+	}
+}
+
+// createUpstream 获取 Sentinel Token、上传参考图并创建上游任务，返回上游任务 ID
+// 发起创建请求前将任务标记为 submitting
+func (ts *TaskStore) createUpstream(ctx context.Context, client *sora.Client, account *model.SoraAccount, task *model.SoraTask, chars []model.SoraCharacter) (string, error) {
+	sub := task.Submission
+
+	sentinel, err := ts.sentinels.Get(ctx, client, account)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrSentinelUnavailable, err)
+	}
+
+	var mediaID string
+	if sub.InputReference != "" {
+		if mediaID, err = uploadInputReference(ctx, client, account, sub.InputReference); err != nil {
+			return "", err
+		}
+	}
+
+	if sub.Kind == model.SubmissionImage {
+		if err := ts.markSubmitting(task.ID, account.ID); err != nil {
+			return "", err
+		}
+		return client.CreateImage(ctx, account.AccessToken, sentinel, sora.ImageRequest{
+			Prompt:    task.Prompt,
+			Width:     sub.Width,
+			Height:    sub.Height,
+			MediaID:   mediaID,
+			NVariants: sub.N,
+		})
+	}
+
+	params, err := model.ParseModelName(task.Model)
+	if err != nil {
+		return "", err
+	}
+	prompt, styleID := task.Prompt, sub.Style
+	if styleID == "" {
+		prompt, styleID = sora.ExtractStyle(task.Prompt)
+	}
+
+	var cameoIDs, usernames []string
+	for _, ch := range chars {
+		cameoIDs = append(cameoIDs, ch.CharacterID)
+		usernames = append(usernames, ch.Username)
+	}
+
+	req := sora.VideoRequest{
+		Prompt:        sora.MentionCameos(prompt, usernames...),
+		Orientation:   params.Orientation,
+		NFrames:       params.NFrames,
+		Model:         params.Model,
+		Size:          params.Size,
+		StyleID:       styleID,
+		MediaIDs:      []string{mediaID},
+		RemixTargetID: sub.RemixTargetID,
+		CameoIDs:      cameoIDs,
+	}
+	if sub.Kind == model.SubmissionStoryboard {
+		shots, instructions := sora.ParseStoryboardPrompt(prompt)
+		req.Prompt = sora.MentionCameos(instructions, usernames...)
+		req.Storyboard = true
+		req.Shots = shots
+	}
+
+	logging.For("submit").Info("创建视频", "task_id", task.ID, "kind", sub.Kind, "model", params.Model, "orientation", params.Orientation,
+		"n_frames", params.NFrames, "size", params.Size, "style", styleID, "media_id", mediaID, "cameos", cameoIDs, "account_id", account.ID)
+
+	if err := ts.markSubmitting(task.ID, account.ID); err != nil {
+		return "", err
+	}
+	return client.CreateVideo(ctx, account.AccessToken, sentinel, req)
+}
+
+// markSubmitting 即将发起创建请求时将任务标记为 submitting 并记录账号
+func (ts *TaskStore) markSubmitting(taskID string, accountID int64) error {
+	if err := ts.db.Model(&model.SoraTask{}).Where("id = ?", taskID).Updates(map[string]interface{}{
+		"status":     model.TaskStatusSubmitting,
+		"account_id": accountID,
+	}).Error; err != nil {
+		return fmt.Errorf("保存任务状态失败: %w", err)
+	}
+	return nil
+}
+
+// uploadInputReference 打开参考图（URL 或 base64 data URI）并流式上传，返回 mediaID
+func uploadInputReference(ctx context.Context, client *sora.Client, account *model.SoraAccount, ref string) (string, error) {
+	var src io.ReadCloser
+	if sora.IsDataURI(ref) {
+		r, err := sora.OpenDataURI(ref)
+		if err != nil {
+			return "", fmt.Errorf("解析参考图片 base64 失败: %w", err)
+		}
+		src = io.NopCloser(r)
+	} else {
+		media, err := client.OpenMedia(ctx, ref)
+		if err != nil {
+			return "", fmt.Errorf("下载参考图片失败: %w", err)
+		}
+		src = media
+	}
+	defer func() {
+		if err := src.Close(); err != nil {
+			logging.For("submit").Warn("关闭参考素材失败", "err", err)
+		}
+	}()
+
+	return client.UploadImageReader(ctx, account.AccessToken, src, "reference")
+}
+
+// reportSubmitError 根据提交任务时的上游错误更新账号状态：Sentinel 被拒时清空预热池，Token 失效或限流时标记账号
+func (ts *TaskStore) reportSubmitError(account *model.SoraAccount, err error) {
+	if errors.Is(err, sora.ErrSentinelRejected) {
+		ts.sentinels.Invalidate(account.ID)
+	}
+	ts.scheduler.ReportSubmitError(account.ID, err)
+}
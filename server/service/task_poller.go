@@ -0,0 +1,354 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/DouDOU-start/go-sora2api/server/logging"
+	"github.com/DouDOU-start/go-sora2api/server/model"
+	"github.com/DouDOU-start/go-sora2api/sora"
+	"gorm.io/gorm"
+)
+
+// 任务轮询相关参数
+const (
+	taskPollInterval     = 5 * time.Second  // 每个账号请求上游的间隔
+	taskPollTimeout      = 30 * time.Minute // 单个任务的最长轮询时间
+	taskNotSeenGrace     = 30 * time.Second // 视频任务从未出现在 pending 列表中时，超过该时长才视为已完成
+	draftLookupTimeout   = 45 * time.Second // 单个任务获取草稿的超时时间
+	progressFlushPeriod  = 3 * time.Second  // 进度批量写库的间隔
+	imageLookupMaxPages  = 3                // 每轮查找图片任务时最多翻的 recent_tasks 页数
+	imageLookupPageLimit = 50               // 每页数量
+)
+
+// polledTask 内存中跟踪的进行中任务
+type polledTask struct {
+	id         string
+	soraTaskID string
+	taskType   string
+	started    time.Time
+	deadline   time.Time
+	progress   int // 已知最大进度
+
+	seen     bool  // 是否出现过在 pending 列表中
+	missing  int   // 连续未出现在 pending 列表中的轮数
+	finished bool  // 已判定生成结束，等待获取草稿
+	draftErr error // 最近一次获取草稿的错误，超时前持续重试
+}
+
+// accountWatcher 单个账号的任务轮询：每个周期只请求一次上游，把结果分发给该账号所有进行中的任务
+type accountWatcher struct {
+	accountID int64
+
+	mu    sync.Mutex
+	tasks map[string]*polledTask // taskID → task
+}
+
+// StartPolling 将任务加入其账号的轮询（账号没有轮询时启动一个）
+func (ts *TaskStore) StartPolling(task *model.SoraTask, account *model.SoraAccount) {
+	ts.db.Model(&model.SoraTask{}).Where("id = ?", task.ID).Updates(map[string]interface{}{
+		"status":   model.TaskStatusInProgress,
+		"progress": 5,
+	})
+
+	pt := &polledTask{
+		id:         task.ID,
+		soraTaskID: task.SoraTaskID,
+		taskType:   task.Type,
+		started:    time.Now(),
+		deadline:   time.Now().Add(taskPollTimeout),
+		progress:   5,
+	}
+
+	ts.watchMu.Lock()
+	defer ts.watchMu.Unlock()
+
+	w, ok := ts.watchers[account.ID]
+	if !ok {
+		w = &accountWatcher{accountID: account.ID, tasks: make(map[string]*polledTask)}
+		ts.watchers[account.ID] = w
+		go ts.watch(w)
+	}
+	w.mu.Lock()
+	w.tasks[pt.id] = pt
+	w.mu.Unlock()
+}
+
+// watch 账号轮询循环，账号没有进行中的任务时退出
+func (ts *TaskStore) watch(w *accountWatcher) {
+	ticker := time.NewTicker(taskPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ts.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		ts.watchMu.Lock()
+		w.mu.Lock()
+		empty := len(w.tasks) == 0
+		w.mu.Unlock()
+		if empty {
+			delete(ts.watchers, w.accountID)
+			ts.watchMu.Unlock()
+			return
+		}
+		ts.watchMu.Unlock()
+
+		ts.pollAccount(w)
+	}
+}
+
+// snapshot 返回当前跟踪的任务
+func (w *accountWatcher) snapshot() []*polledTask {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	tasks := make([]*polledTask, 0, len(w.tasks))
+	for _, t := range w.tasks {
+		tasks = append(tasks, t)
+	}
+	return tasks
+}
+
+// done 停止跟踪任务
+func (w *accountWatcher) done(taskID string) {
+	w.mu.Lock()
+	delete(w.tasks, taskID)
+	w.mu.Unlock()
+}
+
+// pollAccount 对一个账号执行一轮轮询
+func (ts *TaskStore) pollAccount(w *accountWatcher) {
+	tasks := w.snapshot()
+
+	// 账号可能已刷新 Token，每轮重新加载
+	var account model.SoraAccount
+	if err := ts.db.Where("id = ?", w.accountID).First(&account).Error; err != nil {
+		for _, t := range tasks {
+			ts.failTask(t.id, "找不到关联账号")
+			w.done(t.id)
+		}
+		return
+	}
+	client, err := ts.scheduler.NewClient(&account)
+	if err != nil {
+		logging.For("poll").Warn("创建 Sora 客户端失败", "account_id", account.ID, "err", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(AccountContext(ts.ctx, account.ID), taskPollInterval*6)
+	defer cancel()
+
+	var videos, images []*polledTask
+	now := time.Now()
+	for _, t := range tasks {
+		if now.After(t.deadline) {
+			if t.draftErr != nil {
+				ts.failTask(t.id, fmt.Sprintf("获取下载链接失败: %v", t.draftErr))
+			} else {
+				ts.failTask(t.id, "轮询超时")
+			}
+			w.done(t.id)
+			continue
+		}
+		if t.taskType == "image" {
+			images = append(images, t)
+		} else {
+			videos = append(videos, t)
+		}
+	}
+
+	completed := false
+	if len(videos) > 0 {
+		completed = ts.pollVideoTasks(ctx, w, client, &account, videos) || completed
+	}
+	if len(images) > 0 {
+		completed = ts.pollImageTasks(ctx, w, client, &account, images) || completed
+	}
+
+	// 有任务完成时更新一次账号配额（使用独立 context，避免本轮结束后被取消）
+	if completed {
+		go func() {
+			creditCtx, creditCancel := context.WithTimeout(AccountContext(context.Background(), account.ID), 30*time.Second)
+			defer creditCancel()
+			ts.syncAccountCredit(creditCtx, client, account.AccessToken, account.ID, account.Email)
+		}()
+	}
+}
+
+// pollVideoTasks 一次请求 nf/pending 更新账号所有视频任务，返回是否有任务完成
+// 任务出现过后连续两轮不在列表中，或从未出现且已超过 taskNotSeenGrace，视为生成结束并获取草稿
+func (ts *TaskStore) pollVideoTasks(ctx context.Context, w *accountWatcher, client *sora.Client, account *model.SoraAccount, tasks []*polledTask) bool {
+	pending, err := client.ListPendingTasks(ctx, account.AccessToken)
+	if err != nil {
+		logging.For("poll").Warn("视频任务查询失败", "account_id", account.ID, "tasks", len(tasks), "err", err)
+		return false
+	}
+	bySoraID := make(map[string]*sora.PendingTask, len(pending))
+	for i := range pending {
+		bySoraID[pending[i].ID] = &pending[i]
+	}
+
+	completed := false
+	for _, t := range tasks {
+		if !t.finished {
+			if p, ok := bySoraID[t.soraTaskID]; ok {
+				t.seen, t.missing = true, 0
+				if p.Status == "failed" || p.Status == "error" {
+					ts.failTask(t.id, (&sora.TaskError{TaskID: t.soraTaskID, Reason: p.FailureReason}).Error())
+					w.done(t.id)
+					continue
+				}
+				ts.updateProgress(t, p.Progress)
+				continue
+			}
+
+			t.missing++
+			if t.seen && t.missing < 2 || !t.seen && time.Since(t.started) < taskNotSeenGrace {
+				continue
+			}
+			t.finished = true
+		}
+
+		if ts.fetchDraft(w, client, account, t) {
+			completed = true
+		}
+	}
+	return completed
+}
+
+// fetchDraft 获取已结束视频任务的下载链接和编码信息，返回任务是否完成
+// 内容违规直接标记失败；其他错误保留任务，下一轮重试直到轮询超时
+func (ts *TaskStore) fetchDraft(w *accountWatcher, client *sora.Client, account *model.SoraAccount, t *polledTask) bool {
+	ctx, cancel := context.WithTimeout(AccountContext(ts.ctx, account.ID), draftLookupTimeout)
+	defer cancel()
+
+	draft, err := client.GetDraft(ctx, account.AccessToken, t.soraTaskID)
+	if err == nil && draft.Violation() {
+		ts.failTask(t.id, (&sora.TaskError{TaskID: t.soraTaskID, Reason: draft.ViolationReason, Violation: true}).Error())
+		w.done(t.id)
+		return false
+	}
+	if err != nil {
+		t.draftErr = err
+		logging.For("poll").Warn("视频任务获取下载链接失败，稍后重试", "task_id", t.id, "account_id", account.ID, "err", err)
+		return false
+	}
+
+	w.done(t.id)
+	ts.completeTask(t.id, draft.DownloadURL, toVideoEncodings(draft), nil)
+	return true
+}
+
+// pollImageTasks 翻 recent_tasks 前几页更新账号所有图片任务，返回是否有任务完成
+func (ts *TaskStore) pollImageTasks(ctx context.Context, w *accountWatcher, client *sora.Client, account *model.SoraAccount, tasks []*polledTask) bool {
+	waiting := make(map[string]*polledTask, len(tasks))
+	for _, t := range tasks {
+		waiting[t.soraTaskID] = t
+	}
+
+	completed := false
+	opts := sora.ListOptions{Limit: imageLookupPageLimit}
+	for page := 0; page < imageLookupMaxPages && len(waiting) > 0; page++ {
+		result, err := client.ListRecentTasks(ctx, account.AccessToken, opts)
+		if err != nil {
+			logging.For("poll").Warn("图片任务查询失败", "account_id", account.ID, "tasks", len(tasks), "err", err)
+			return completed
+		}
+
+		for i := range result.Tasks {
+			rt := &result.Tasks[i]
+			t, ok := waiting[rt.ID]
+			if !ok {
+				continue
+			}
+			delete(waiting, rt.ID)
+
+			switch rt.Status {
+			case "failed", "error":
+				ts.failTask(t.id, (&sora.TaskError{TaskID: t.soraTaskID, Reason: rt.FailureReason}).Error())
+				w.done(t.id)
+			case "succeeded":
+				w.done(t.id)
+				if len(rt.Generations) == 0 {
+					ts.failTask(t.id, "任务成功但未找到图片 URL")
+					continue
+				}
+				ts.completeTask(t.id, "", model.VideoEncodings{}, toTaskImages(rt.Generations))
+				completed = true
+			default:
+				ts.updateProgress(t, rt.Progress)
+			}
+		}
+
+		if opts.Cursor = result.NextCursor; opts.Cursor == "" {
+			break
+		}
+	}
+	return completed
+}
+
+// updateProgress 进度上升时记入内存并排队批量写库
+func (ts *TaskStore) updateProgress(t *polledTask, progress int) {
+	if progress <= t.progress {
+		return
+	}
+	t.progress = progress
+
+	ts.progressMu.Lock()
+	ts.pendingProgress[t.id] = progress
+	ts.progressMu.Unlock()
+}
+
+// dropProgress 丢弃任务待写库的进度（任务完成或失败时由最终状态覆盖）
+func (ts *TaskStore) dropProgress(taskID string) {
+	ts.progressMu.Lock()
+	delete(ts.pendingProgress, taskID)
+	ts.progressMu.Unlock()
+}
+
+// flushLoop 定期将排队的进度批量写库
+func (ts *TaskStore) flushLoop(ctx context.Context) {
+	ticker := time.NewTicker(progressFlushPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			ts.flushProgress()
+			return
+		case <-ticker.C:
+			ts.flushProgress()
+		}
+	}
+}
+
+// flushProgress 在一个事务中写入所有排队的进度
+func (ts *TaskStore) flushProgress() {
+	ts.progressMu.Lock()
+	batch := ts.pendingProgress
+	ts.pendingProgress = make(map[string]int)
+	ts.progressMu.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+	err := ts.db.Transaction(func(tx *gorm.DB) error {
+		for taskID, progress := range batch {
+			// 只更新仍在进行中的任务，避免覆盖已完成任务的 100%
+			if err := tx.Model(&model.SoraTask{}).
+				Where("id = ? AND status = ?", taskID, model.TaskStatusInProgress).
+				Update("progress", progress).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logging.For("poll").Error("批量写入任务进度失败", "tasks", len(batch), "err", err)
+	}
+}
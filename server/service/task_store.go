@@ -2,28 +2,53 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
-	"net/http"
 	"sync"
 	"time"
 
+	"github.com/DouDOU-start/go-sora2api/server/logging"
 	"github.com/DouDOU-start/go-sora2api/server/model"
 	"github.com/DouDOU-start/go-sora2api/sora"
 	"gorm.io/gorm"
 )
 
-// TaskStore 任务存储与后台轮询
+// TaskStore 任务存储、后台提交与轮询
 type TaskStore struct {
 	db        *gorm.DB
 	scheduler *Scheduler
-	polls     sync.Map // taskID → cancel func
+	sentinels *SentinelPool
+	settings  *SettingsStore
+	ctx       context.Context // Start 传入的后台 context
+	queue     *submitQueue
+
+	watchMu  sync.Mutex
+	watchers map[int64]*accountWatcher // accountID → 账号轮询
+
+	progressMu      sync.Mutex
+	pendingProgress map[string]int // taskID → 待写库的进度
 }
 
 // NewTaskStore 创建任务存储
-func NewTaskStore(db *gorm.DB, scheduler *Scheduler) *TaskStore {
-	return &TaskStore{db: db, scheduler: scheduler}
+func NewTaskStore(db *gorm.DB, scheduler *Scheduler, sentinels *SentinelPool, settings *SettingsStore) *TaskStore {
+	return &TaskStore{
+		db:              db,
+		scheduler:       scheduler,
+		sentinels:       sentinels,
+		settings:        settings,
+		ctx:             context.Background(),
+		queue:           newSubmitQueue(),
+		watchers:        make(map[int64]*accountWatcher),
+		pendingProgress: make(map[string]int),
+	}
+}
+
+// Start 启动任务提交 worker 和进度批量写库，ctx 取消时停止提交和所有账号轮询并写入剩余进度（需在恢复任务前调用）
+func (ts *TaskStore) Start(ctx context.Context) {
+	ts.ctx = ctx
+	go ts.dispatch(ctx)
+	go ts.flushLoop(ctx)
 }
 
 // Create 创建任务记录
@@ -70,116 +95,33 @@ func (ts *TaskStore) ListTasks(status, taskType string, page, pageSize int, apiK
 	return tasks, total, nil
 }
 
-// StartPolling 启动后台轮询任务状态
-func (ts *TaskStore) StartPolling(task *model.SoraTask, account *model.SoraAccount) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
-	ts.polls.Store(task.ID, cancel)
-
-	go func() {
-		defer cancel()
-		defer ts.polls.Delete(task.ID)
-
-		client, err := sora.New(ts.scheduler.GetProxyURL())
-		if err != nil {
-			ts.failTask(task.ID, fmt.Sprintf("创建 Sora 客户端失败: %v", err))
-			return
-		}
-
-		// 更新为进行中
-		ts.db.Model(&model.SoraTask{}).Where("id = ?", task.ID).Updates(map[string]interface{}{
-			"status":   model.TaskStatusInProgress,
-			"progress": 5,
-		})
-
-		startTime := time.Now()
-		ticker := time.NewTicker(5 * time.Second)
-		defer ticker.Stop()
-
-		maxProgress := 0
-
-		for {
-			select {
-			case <-ctx.Done():
-				ts.failTask(task.ID, "轮询超时")
-				return
-			case <-ticker.C:
-				switch task.Type {
-				case "image":
-					ts.pollImageTask(ctx, client, account.AccessToken, task, startTime, account.Email)
-				default:
-					ts.pollVideoTask(ctx, client, account.AccessToken, task, startTime, &maxProgress, account.Email)
-				}
-
-				// 检查任务是否已完成
-				var current model.SoraTask
-				if err := ts.db.Where("id = ?", task.ID).First(&current).Error; err == nil {
-					if current.Status == model.TaskStatusCompleted || current.Status == model.TaskStatusFailed {
-						return
-					}
-				}
-			}
-		}
-	}()
-}
-
-// pollVideoTask 轮询视频任务
-func (ts *TaskStore) pollVideoTask(ctx context.Context, client *sora.Client, at string, task *model.SoraTask, startTime time.Time, maxProgress *int, email string) {
-	result := client.QueryVideoTaskOnce(ctx, at, task.SoraTaskID, startTime, *maxProgress)
-	if result.Err != nil {
-		log.Printf("[poll] 视频任务 %s 查询失败: %v", task.ID, result.Err)
-		return
-	}
-
-	// 更新进度
-	if result.Progress.Percent > *maxProgress {
-		*maxProgress = result.Progress.Percent
-		ts.db.Model(&model.SoraTask{}).Where("id = ?", task.ID).Update("progress", result.Progress.Percent)
-	}
-
-	if result.Done {
-		// 获取下载链接
-		downloadURL, err := client.GetDownloadURL(ctx, at, task.SoraTaskID)
-		if err != nil {
-			log.Printf("[poll] 视频任务 %s 获取下载链接失败: %v", task.ID, err)
-			ts.failTask(task.ID, fmt.Sprintf("获取下载链接失败: %v", err))
-			return
-		}
-		ts.completeTask(task.ID, downloadURL, "")
-
-		// 异步更新账号配额（使用独立 context，避免轮询结束后被取消）
-		creditCtx, creditCancel := context.WithTimeout(context.Background(), 30*time.Second)
-		go func() {
-			defer creditCancel()
-			ts.syncAccountCredit(creditCtx, client, at, task.AccountID, email)
-		}()
+// toTaskImages 将 Sora 生成结果转换为任务图片列表
+func toTaskImages(gens []sora.Generation) model.TaskImages {
+	images := make(model.TaskImages, 0, len(gens))
+	for _, g := range gens {
+		images = append(images, model.TaskImage{URL: g.URL, GenerationID: g.ID})
 	}
+	return images
 }
 
-// pollImageTask 轮询图片任务
-func (ts *TaskStore) pollImageTask(ctx context.Context, client *sora.Client, at string, task *model.SoraTask, startTime time.Time, email string) {
-	result := client.QueryImageTaskOnce(ctx, at, task.SoraTaskID, startTime)
-	if result.Err != nil {
-		log.Printf("[poll] 图片任务 %s 查询失败: %v", task.ID, result.Err)
-		return
-	}
-
-	// 更新进度
-	ts.db.Model(&model.SoraTask{}).Where("id = ?", task.ID).Update("progress", result.Progress.Percent)
-
-	if result.Done {
-		ts.completeTask(task.ID, "", result.ImageURL)
-
-		// 异步更新账号配额（使用独立 context，避免轮询结束后被取消）
-		creditCtx, creditCancel := context.WithTimeout(context.Background(), 30*time.Second)
-		go func() {
-			defer creditCancel()
-			ts.syncAccountCredit(creditCtx, client, at, task.AccountID, email)
-		}()
+// toVideoEncodings 提取草稿中的编码链接与元数据
+func toVideoEncodings(d *sora.Draft) model.VideoEncodings {
+	return model.VideoEncodings{
+		Source:    d.Encodings.Source.Path,
+		MD:        d.Encodings.MD.Path,
+		LD:        d.Encodings.LD.Path,
+		Thumbnail: d.Encodings.Thumbnail.Path,
+		GIF:       d.Encodings.GIF.Path,
+		Width:     d.Width,
+		Height:    d.Height,
+		Duration:  d.Duration,
 	}
 }
 
 // completeTask 标记任务完成
-func (ts *TaskStore) completeTask(taskID, downloadURL, imageURL string) {
+func (ts *TaskStore) completeTask(taskID, downloadURL string, encodings model.VideoEncodings, images model.TaskImages) {
+	ts.dropProgress(taskID)
+	ts.scheduler.Release(taskID)
 	now := time.Now()
 	updates := map[string]interface{}{
 		"status":       model.TaskStatusCompleted,
@@ -189,29 +131,36 @@ func (ts *TaskStore) completeTask(taskID, downloadURL, imageURL string) {
 	if downloadURL != "" {
 		updates["download_url"] = downloadURL
 	}
-	if imageURL != "" {
-		updates["image_url"] = imageURL
+	if !encodings.IsZero() {
+		updates["encodings"] = encodings
+	}
+	if len(images) > 0 {
+		updates["image_url"] = images[0].URL
+		updates["images"] = images
 	}
 	ts.db.Model(&model.SoraTask{}).Where("id = ?", taskID).Updates(updates)
-	log.Printf("[poll] 任务 %s 已完成", taskID)
+	logging.For("poll").Info("任务已完成", "task_id", taskID)
 }
 
-// failTask 标记任务失败
+// failTask 标记任务失败并清空提交参数
 func (ts *TaskStore) failTask(taskID, errMsg string) {
+	ts.dropProgress(taskID)
+	ts.scheduler.Release(taskID)
 	now := time.Now()
 	ts.db.Model(&model.SoraTask{}).Where("id = ?", taskID).Updates(map[string]interface{}{
 		"status":        model.TaskStatusFailed,
 		"error_message": errMsg,
 		"completed_at":  &now,
+		"submission":    model.TaskSubmission{}, // 不再提交，清空可能较大的参考图 data URI
 	})
-	log.Printf("[poll] 任务 %s 失败: %s", taskID, errMsg)
+	logging.For("poll").Warn("任务失败", "task_id", taskID, "err", errMsg)
 }
 
 // syncAccountCredit 同步账号配额
 func (ts *TaskStore) syncAccountCredit(ctx context.Context, client *sora.Client, at string, accountID int64, email string) {
 	balance, err := client.GetCreditBalance(ctx, at)
 	if err != nil {
-		log.Printf("[poll] 同步账号 %s 配额失败: %v", email, err)
+		logging.For("poll").Warn("同步账号配额失败", "account_id", accountID, "email", email, "err", err)
 		return
 	}
 
@@ -234,159 +183,207 @@ func (ts *TaskStore) syncAccountCredit(ctx context.Context, client *sora.Client,
 	ts.db.Model(&model.SoraAccount{}).Where("id = ?", accountID).Updates(updates)
 }
 
-// RecoverInProgressTasks 服务重启后恢复进行中的任务轮询
+// RecoverInProgressTasks 服务重启后恢复未完成的任务：排队中的重新入队，已提交到上游的恢复轮询
+// 重启时处于 submitting 的任务无法确定上游是否已受理，标记失败以免重复生成
 func (ts *TaskStore) RecoverInProgressTasks() {
+	if err := ts.scheduler.ReconcileInFlight(); err != nil {
+		logging.For("task_store").Error("重建账号进行中任务数失败", "err", err)
+	}
+
 	var tasks []model.SoraTask
-	if err := ts.db.Where("status IN ?", []string{model.TaskStatusQueued, model.TaskStatusInProgress}).Find(&tasks).Error; err != nil {
-		log.Printf("[task_store] 查询进行中任务失败: %v", err)
+	if err := ts.db.Where("status IN ?", []string{model.TaskStatusQueued, model.TaskStatusSubmitting, model.TaskStatusInProgress}).
+		Order("created_at ASC").Find(&tasks).Error; err != nil {
+		logging.For("task_store").Error("查询进行中任务失败", "err", err)
 		return
 	}
 
+	queued := 0
 	for i := range tasks {
 		task := &tasks[i]
+		switch {
+		case task.SoraTaskID == "" && task.Status == model.TaskStatusSubmitting:
+			ts.failTask(task.ID, "服务重启时任务正在提交，无法确定上游是否已受理，请重新提交")
+			continue
+		case task.SoraTaskID == "":
+			ts.queue.push(task.ID)
+			queued++
+			continue
+		}
+
 		var account model.SoraAccount
 		if err := ts.db.Where("id = ?", task.AccountID).First(&account).Error; err != nil {
-			log.Printf("[task_store] 恢复任务 %s 失败：找不到账号 %d", task.ID, task.AccountID)
+			logging.For("task_store").Warn("恢复任务失败：找不到账号", "task_id", task.ID, "account_id", task.AccountID)
 			ts.failTask(task.ID, "服务重启后找不到关联账号")
 			continue
 		}
-		log.Printf("[task_store] 恢复轮询任务 %s（Sora: %s）", task.ID, task.SoraTaskID)
+		logging.For("task_store").Info("恢复轮询任务", "task_id", task.ID, "account_id", task.AccountID, "sora_task_id", task.SoraTaskID)
 		ts.StartPolling(task, &account)
 	}
 
 	if len(tasks) > 0 {
-		log.Printf("[task_store] 已恢复 %d 个进行中的任务", len(tasks))
+		logging.For("task_store").Info("已恢复进行中的任务", "count", len(tasks), "queued", queued)
 	}
 }
 
-// fetchVideoDownloadURL 获取视频下载链接（通过 Sora API）
-func (ts *TaskStore) fetchVideoDownloadURL(ctx context.Context, task *model.SoraTask) (string, error) {
+// taskClient 加载任务关联账号并创建 Sora 客户端
+func (ts *TaskStore) taskClient(task *model.SoraTask) (*model.SoraAccount, *sora.Client, error) {
 	var account model.SoraAccount
 	if err := ts.db.Where("id = ?", task.AccountID).First(&account).Error; err != nil {
-		return "", fmt.Errorf("找不到关联账号: %w", err)
-	}
-	client, err := sora.New(ts.scheduler.GetProxyURL())
-	if err != nil {
-		return "", fmt.Errorf("创建 Sora 客户端失败: %w", err)
+		return nil, nil, fmt.Errorf("找不到关联账号: %w", err)
 	}
-	url, err := client.GetDownloadURL(ctx, account.AccessToken, task.SoraTaskID)
+	client, err := ts.scheduler.NewClient(&account)
 	if err != nil {
-		return "", fmt.Errorf("获取下载链接失败: %w", err)
+		return nil, nil, fmt.Errorf("创建 Sora 客户端失败: %w", err)
 	}
-	// 缓存下载链接
-	ts.db.Model(&model.SoraTask{}).Where("id = ?", task.ID).Update("download_url", url)
-	return url, nil
+	return &account, client, nil
 }
 
-// DownloadVideo 下载视频并流式转发
-func (ts *TaskStore) DownloadVideo(ctx context.Context, task *model.SoraTask) (io.ReadCloser, int64, string, error) {
-	downloadURL := task.DownloadURL
+// refreshVideoDraft 通过 drafts 接口重新获取视频下载链接和编码信息，更新缓存并写回 task
+func (ts *TaskStore) refreshVideoDraft(ctx context.Context, client *sora.Client, account *model.SoraAccount, task *model.SoraTask) error {
+	draft, err := client.GetDraft(ctx, account.AccessToken, task.SoraTaskID)
+	if err != nil {
+		return fmt.Errorf("获取下载链接失败: %w", err)
+	}
+	task.DownloadURL = draft.DownloadURL
+	task.Encodings = toVideoEncodings(draft)
+	// 缓存下载链接和编码
+	ts.db.Model(&model.SoraTask{}).Where("id = ?", task.ID).Updates(map[string]interface{}{
+		"download_url": task.DownloadURL,
+		"encodings":    task.Encodings,
+	})
+	return nil
+}
 
-	// 如果没有缓存的下载链接，获取一个
-	if downloadURL == "" {
-		url, err := ts.fetchVideoDownloadURL(ctx, task)
-		if err != nil {
-			return nil, 0, "", err
-		}
-		downloadURL = url
-	}
+// isLinkExpired 判断下载失败是否由链接过期（上游返回非 2xx）导致
+func isLinkExpired(err error) bool {
+	var apiErr *sora.APIError
+	return errors.As(err, &apiErr)
+}
 
-	// HTTP GET 下载
-	resp, err := http.Get(downloadURL) //nolint:gosec // 来自 Sora 官方的下载链接
+// openVideoMedia 打开视频任务的媒体文件（连接中断时自动断点续传）
+// pick 从任务中取出链接；链接未缓存或已过期时重新获取草稿信息后重试
+func (ts *TaskStore) openVideoMedia(ctx context.Context, task *model.SoraTask, label string, pick func(*model.SoraTask) string) (*sora.Media, error) {
+	account, client, err := ts.taskClient(task)
 	if err != nil {
-		return nil, 0, "", fmt.Errorf("下载视频失败: %w", err)
+		return nil, err
 	}
 
-	// 链接过期（403/404 等），清除缓存并重新获取
-	if resp.StatusCode != http.StatusOK {
-		if err := resp.Body.Close(); err != nil {
-			log.Printf("[download] 关闭视频响应体失败: %v", err)
-		}
-		log.Printf("[download] 视频 %s 链接已过期（%d），重新获取", task.ID, resp.StatusCode)
-		ts.db.Model(&model.SoraTask{}).Where("id = ?", task.ID).Update("download_url", "")
-
-		newURL, err := ts.fetchVideoDownloadURL(ctx, task)
-		if err != nil {
-			return nil, 0, "", err
+	// 如果没有缓存的链接，获取一个
+	if pick(task) == "" {
+		if err := ts.refreshVideoDraft(ctx, client, account, task); err != nil {
+			return nil, err
 		}
-		resp, err = http.Get(newURL) //nolint:gosec
-		if err != nil {
-			return nil, 0, "", fmt.Errorf("下载视频失败: %w", err)
+		if pick(task) == "" {
+			return nil, fmt.Errorf("上游未提供%s链接", label)
 		}
-		if resp.StatusCode != http.StatusOK {
-			if err := resp.Body.Close(); err != nil {
-				log.Printf("[download] 关闭视频响应体失败: %v", err)
-			}
-			return nil, 0, "", fmt.Errorf("下载视频返回 %d", resp.StatusCode)
+	}
+
+	media, err := client.OpenMedia(ctx, pick(task))
+
+	// 链接过期（403/404 等），重新获取
+	if isLinkExpired(err) {
+		logging.For("download").Info(label+"链接已过期，重新获取", "task_id", task.ID, "account_id", task.AccountID, "err", err)
+		if err := ts.refreshVideoDraft(ctx, client, account, task); err != nil {
+			return nil, err
 		}
+		media, err = client.OpenMedia(ctx, pick(task))
 	}
+	if err != nil {
+		return nil, fmt.Errorf("下载%s失败: %w", label, err)
+	}
+	return media, nil
+}
 
-	contentType := resp.Header.Get("Content-Type")
+// DownloadVideo 下载视频并流式转发（连接中断时自动断点续传）
+func (ts *TaskStore) DownloadVideo(ctx context.Context, task *model.SoraTask) (io.ReadCloser, int64, string, error) {
+	media, err := ts.openVideoMedia(ctx, task, "视频", func(t *model.SoraTask) string { return t.DownloadURL })
+	if err != nil {
+		return nil, 0, "", err
+	}
+
+	contentType := media.ContentType
 	if contentType == "" {
 		contentType = "video/mp4"
 	}
 
-	return resp.Body, resp.ContentLength, contentType, nil
+	return media, media.Size, contentType, nil
 }
 
-// fetchImageURL 通过 Sora API 重新获取图片链接
-func (ts *TaskStore) fetchImageURL(ctx context.Context, task *model.SoraTask) (string, error) {
-	var account model.SoraAccount
-	if err := ts.db.Where("id = ?", task.AccountID).First(&account).Error; err != nil {
-		return "", fmt.Errorf("找不到关联账号: %w", err)
-	}
-	client, err := sora.New(ts.scheduler.GetProxyURL())
+// DownloadThumbnail 下载视频封面缩略图并流式转发
+func (ts *TaskStore) DownloadThumbnail(ctx context.Context, task *model.SoraTask) (io.ReadCloser, int64, string, error) {
+	media, err := ts.openVideoMedia(ctx, task, "缩略图", func(t *model.SoraTask) string { return t.Encodings.Thumbnail })
 	if err != nil {
-		return "", fmt.Errorf("创建 Sora 客户端失败: %w", err)
+		return nil, 0, "", err
 	}
-	url, err := client.GetDownloadURL(ctx, account.AccessToken, task.SoraTaskID)
+
+	contentType := media.ContentType
+	if contentType == "" {
+		contentType = "image/webp"
+	}
+
+	return media, media.Size, contentType, nil
+}
+
+// fetchImages 通过 Sora API 重新获取图片链接
+func (ts *TaskStore) fetchImages(ctx context.Context, task *model.SoraTask) (model.TaskImages, error) {
+	account, client, err := ts.taskClient(task)
 	if err != nil {
-		return "", fmt.Errorf("获取图片链接失败: %w", err)
+		return nil, err
+	}
+	result := client.QueryImageTaskOnce(ctx, account.AccessToken, task.SoraTaskID, time.Now())
+	if result.Err != nil {
+		return nil, fmt.Errorf("获取图片链接失败: %w", result.Err)
+	}
+	if len(result.Images) == 0 {
+		return nil, fmt.Errorf("获取图片链接失败: 最近任务中未找到 %s", task.SoraTaskID)
 	}
+	images := toTaskImages(result.Images)
 	// 更新缓存
-	ts.db.Model(&model.SoraTask{}).Where("id = ?", task.ID).Update("image_url", url)
-	return url, nil
+	ts.db.Model(&model.SoraTask{}).Where("id = ?", task.ID).Updates(map[string]interface{}{
+		"image_url": images[0].URL,
+		"images":    images,
+	})
+	return images, nil
 }
 
-// DownloadImage 下载图片并流式转发
-func (ts *TaskStore) DownloadImage(ctx context.Context, task *model.SoraTask) (io.ReadCloser, int64, string, error) {
-	imageURL := task.ImageURL
-	if imageURL == "" {
+// DownloadImage 下载第 index 张图片并流式转发（连接中断时自动断点续传）
+func (ts *TaskStore) DownloadImage(ctx context.Context, task *model.SoraTask, index int) (io.ReadCloser, int64, string, error) {
+	images := task.ImageList()
+	if len(images) == 0 {
 		return nil, 0, "", fmt.Errorf("图片 URL 为空")
 	}
+	if index < 0 || index >= len(images) {
+		return nil, 0, "", fmt.Errorf("图片索引 %d 超出范围（共 %d 张）", index, len(images))
+	}
 
-	resp, err := http.Get(imageURL) //nolint:gosec // 来自 Sora 官方的图片链接
+	_, client, err := ts.taskClient(task)
 	if err != nil {
-		return nil, 0, "", fmt.Errorf("下载图片失败: %w", err)
+		return nil, 0, "", err
 	}
 
+	media, err := client.OpenMedia(ctx, images[index].URL)
+
 	// 链接过期，重新获取
-	if resp.StatusCode != http.StatusOK {
-		if err := resp.Body.Close(); err != nil {
-			log.Printf("[download] 关闭图片响应体失败: %v", err)
-		}
-		log.Printf("[download] 图片 %s 链接已过期（%d），重新获取", task.ID, resp.StatusCode)
+	if isLinkExpired(err) {
+		logging.For("download").Info("图片链接已过期，重新获取", "task_id", task.ID, "account_id", task.AccountID, "index", index, "err", err)
 
-		newURL, err := ts.fetchImageURL(ctx, task)
-		if err != nil {
-			return nil, 0, "", err
+		images, fetchErr := ts.fetchImages(ctx, task)
+		if fetchErr != nil {
+			return nil, 0, "", fetchErr
 		}
-		resp, err = http.Get(newURL) //nolint:gosec
-		if err != nil {
-			return nil, 0, "", fmt.Errorf("下载图片失败: %w", err)
-		}
-		if resp.StatusCode != http.StatusOK {
-			if err := resp.Body.Close(); err != nil {
-				log.Printf("[download] 关闭图片响应体失败: %v", err)
-			}
-			return nil, 0, "", fmt.Errorf("下载图片返回 %d", resp.StatusCode)
+		if index >= len(images) {
+			return nil, 0, "", fmt.Errorf("图片索引 %d 超出范围（共 %d 张）", index, len(images))
 		}
+		media, err = client.OpenMedia(ctx, images[index].URL)
+	}
+	if err != nil {
+		return nil, 0, "", fmt.Errorf("下载图片失败: %w", err)
 	}
 
-	contentType := resp.Header.Get("Content-Type")
+	contentType := media.ContentType
 	if contentType == "" {
 		contentType = "image/png"
 	}
 
-	return resp.Body, resp.ContentLength, contentType, nil
+	return media, media.Size, contentType, nil
 }
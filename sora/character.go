@@ -5,11 +5,41 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"mime/multipart"
-	"net/textproto"
+	"net/url"
+	"strconv"
 	"time"
 )
 
+// defaultCharactersLimit 角色列表默认每页数量
+const defaultCharactersLimit = 50
+
+// defaultCharacterSampleSeconds 角色视频默认采样窗口长度（秒），与网页端一致
+const defaultCharacterSampleSeconds = 3
+
+// CharacterVideoOptions 上传角色视频的选项，零值使用默认采样窗口 0-3 秒
+type CharacterVideoOptions struct {
+	SampleStart float64 // 采样窗口起点（秒）
+	SampleEnd   float64 // 采样窗口终点（秒），<=0 时为 SampleStart+3
+}
+
+// timestamps 转换为上游 timestamps 字段（"start,end"）
+func (o CharacterVideoOptions) timestamps() (string, error) {
+	end := o.SampleEnd
+	if end <= 0 {
+		end = o.SampleStart + defaultCharacterSampleSeconds
+	}
+	if o.SampleStart < 0 || end <= o.SampleStart {
+		return "", fmt.Errorf("无效的采样窗口 %v-%v 秒", o.SampleStart, end)
+	}
+	return strconv.FormatFloat(o.SampleStart, 'f', -1, 64) + "," + strconv.FormatFloat(end, 'f', -1, 64), nil
+}
+
+// CharacterFinalizeOptions 定稿角色的可选参数
+type CharacterFinalizeOptions struct {
+	InstructionSet       string // 角色指令（如说话风格、行为描述），为空不设置
+	SafetyInstructionSet string // 安全指令（限制角色的使用方式），为空不设置
+}
+
 // CameoStatus 角色处理状态
 type CameoStatus struct {
 	ID              string // 角色 cameo ID
@@ -20,51 +50,16 @@ type CameoStatus struct {
 }
 
 // UploadCharacterVideo 上传角色视频，返回 cameoID
-// videoData 为视频二进制数据（mp4 格式），timestamps 默认 "0,3"
+// videoData 为视频二进制数据（mp4 格式），timestamps 默认 "0,3"，大文件建议使用 UploadCharacterVideoReader
 func (c *Client) UploadCharacterVideo(ctx context.Context, accessToken string, videoData []byte) (string, error) {
-	headers := c.baseHeaders(accessToken)
-
-	var buf bytes.Buffer
-	writer := multipart.NewWriter(&buf)
-
-	// 添加视频文件
-	partHeader := make(textproto.MIMEHeader)
-	partHeader.Set("Content-Disposition", `form-data; name="file"; filename="video.mp4"`)
-	partHeader.Set("Content-Type", "video/mp4")
-	part, err := writer.CreatePart(partHeader)
-	if err != nil {
-		return "", err
-	}
-	if _, err := part.Write(videoData); err != nil {
-		return "", err
-	}
-
-	// 添加 timestamps 字段
-	if err := writer.WriteField("timestamps", "0,3"); err != nil {
-		return "", err
-	}
-	if err := writer.Close(); err != nil {
-		return "", err
-	}
-
-	resp, err := c.doPostMultipart(ctx, soraBaseURL+"/characters/upload", headers, &buf, writer.FormDataContentType())
-	if err != nil {
-		return "", fmt.Errorf("上传角色视频失败: %w", err)
-	}
-
-	cameoID, ok := resp["id"].(string)
-	if !ok || cameoID == "" {
-		return "", fmt.Errorf("响应中无 cameo_id: %v", resp)
-	}
-
-	return cameoID, nil
+	return c.UploadCharacterVideoReader(ctx, accessToken, bytes.NewReader(videoData))
 }
 
 // GetCameoStatus 获取角色处理状态
 func (c *Client) GetCameoStatus(ctx context.Context, accessToken, cameoID string) (CameoStatus, error) {
 	headers := c.baseHeaders(accessToken)
 
-	body, err := c.doGet(ctx, soraBaseURL+"/project_y/cameos/in_progress/"+cameoID, headers)
+	body, err := c.doGet(ctx, c.soraBaseURL+"/project_y/cameos/in_progress/"+cameoID, headers)
 	if err != nil {
 		return CameoStatus{}, fmt.Errorf("获取角色状态失败: %w", err)
 	}
@@ -133,12 +128,7 @@ func (c *Client) PollCameoStatus(ctx context.Context, accessToken, cameoID strin
 
 // DownloadCharacterImage 下载角色头像图片
 func (c *Client) DownloadCharacterImage(ctx context.Context, imageURL string) ([]byte, error) {
-	userAgent := desktopUserAgents[c.randIntn(len(desktopUserAgents))]
-	headers := map[string]string{
-		"User-Agent": userAgent,
-	}
-
-	body, err := c.doGet(ctx, imageURL, headers)
+	body, err := c.DownloadFile(ctx, imageURL)
 	if err != nil {
 		return nil, fmt.Errorf("下载角色图片失败: %w", err)
 	}
@@ -147,34 +137,16 @@ func (c *Client) DownloadCharacterImage(ctx context.Context, imageURL string) ([
 }
 
 // UploadCharacterImage 上传角色头像图片，返回 assetPointer
-// imageData 为图片二进制数据（webp 格式）
+// imageData 为图片二进制数据（通常为 webp 格式，内容类型按实际内容嗅探）
 func (c *Client) UploadCharacterImage(ctx context.Context, accessToken string, imageData []byte) (string, error) {
-	headers := c.baseHeaders(accessToken)
-
-	var buf bytes.Buffer
-	writer := multipart.NewWriter(&buf)
-
-	// 添加图片文件
-	partHeader := make(textproto.MIMEHeader)
-	partHeader.Set("Content-Disposition", `form-data; name="file"; filename="profile.webp"`)
-	partHeader.Set("Content-Type", "image/webp")
-	part, err := writer.CreatePart(partHeader)
+	contentType, body, err := sniffContent(bytes.NewReader(imageData))
 	if err != nil {
-		return "", err
-	}
-	if _, err := part.Write(imageData); err != nil {
-		return "", err
-	}
-
-	// 添加 use_case 字段
-	if err := writer.WriteField("use_case", "profile"); err != nil {
-		return "", err
-	}
-	if err := writer.Close(); err != nil {
-		return "", err
+		return "", fmt.Errorf("上传角色头像失败: %w", err)
 	}
 
-	resp, err := c.doPostMultipart(ctx, soraBaseURL+"/project_y/file/upload", headers, &buf, writer.FormDataContentType())
+	resp, err := c.postMultipartStream(ctx, c.soraBaseURL+"/project_y/file/upload", c.baseHeaders(accessToken),
+		multipartFile{filename: withSniffedExt("profile", "profile", contentType), contentType: contentType, r: body},
+		[][2]string{{"use_case", "profile"}})
 	if err != nil {
 		return "", fmt.Errorf("上传角色头像失败: %w", err)
 	}
@@ -189,6 +161,11 @@ func (c *Client) UploadCharacterImage(ctx context.Context, accessToken string, i
 
 // FinalizeCharacter 定稿角色，返回 characterID
 func (c *Client) FinalizeCharacter(ctx context.Context, accessToken, cameoID, username, displayName, profileAssetPointer string) (string, error) {
+	return c.FinalizeCharacterWithOptions(ctx, accessToken, cameoID, username, displayName, profileAssetPointer, CharacterFinalizeOptions{})
+}
+
+// FinalizeCharacterWithOptions 定稿角色并设置指令集，返回 characterID
+func (c *Client) FinalizeCharacterWithOptions(ctx context.Context, accessToken, cameoID, username, displayName, profileAssetPointer string, opts CharacterFinalizeOptions) (string, error) {
 	headers := c.jsonHeaders(accessToken)
 
 	payload := map[string]interface{}{
@@ -196,11 +173,11 @@ func (c *Client) FinalizeCharacter(ctx context.Context, accessToken, cameoID, us
 		"username":               username,
 		"display_name":           displayName,
 		"profile_asset_pointer":  profileAssetPointer,
-		"instruction_set":        nil,
-		"safety_instruction_set": nil,
+		"instruction_set":        nilIfEmpty(opts.InstructionSet),
+		"safety_instruction_set": nilIfEmpty(opts.SafetyInstructionSet),
 	}
 
-	resp, err := c.doPost(ctx, soraBaseURL+"/characters/finalize", headers, payload)
+	resp, err := c.doSubmit(ctx, c.soraBaseURL+"/characters/finalize", headers, payload)
 	if err != nil {
 		return "", fmt.Errorf("定稿角色失败: %w", err)
 	}
@@ -236,7 +213,7 @@ func (c *Client) SetCharacterVisibility(ctx context.Context, accessToken, cameoI
 		"visibility": visibility,
 	}
 
-	_, err := c.doPost(ctx, soraBaseURL+"/project_y/cameos/by_id/"+cameoID+"/update_v2", headers, payload)
+	_, err := c.doPost(ctx, c.soraBaseURL+"/project_y/cameos/by_id/"+cameoID+"/update_v2", headers, payload)
 	if err != nil {
 		return fmt.Errorf("设置角色可见性失败: %w", err)
 	}
@@ -246,5 +223,98 @@ func (c *Client) SetCharacterVisibility(ctx context.Context, accessToken, cameoI
 
 // DeleteCharacter 删除角色
 func (c *Client) DeleteCharacter(ctx context.Context, accessToken, characterID string) error {
-	return c.doDelete(ctx, soraBaseURL+"/project_y/characters/"+characterID, c.baseHeaders(accessToken))
+	return c.doDelete(ctx, c.soraBaseURL+"/project_y/characters/"+characterID, c.baseHeaders(accessToken))
+}
+
+// Character 账号下已定稿的角色
+type Character struct {
+	CharacterID string  `json:"character_id"`
+	CameoID     string  `json:"cameo_id"`
+	Username    string  `json:"username"`
+	DisplayName string  `json:"display_name"`
+	ProfileURL  string  `json:"profile_picture_url"` // 头像链接（带签名，会过期）
+	Visibility  string  `json:"visibility"`          // public / private
+	CreatedAt   float64 `json:"created_at"`          // 创建时间（Unix 秒）
+}
+
+// IsPublic 角色是否公开
+func (ch Character) IsPublic() bool {
+	return ch.Visibility == "public"
+}
+
+// CharactersPage 角色列表的一页结果
+type CharactersPage struct {
+	Characters []Character
+	NextCursor string // 下一页游标，为空表示没有更多
+}
+
+// CharacterUpdate 角色更新内容，空字段表示不修改
+type CharacterUpdate struct {
+	DisplayName         string
+	Username            string
+	ProfileAssetPointer string // 新头像，通过 UploadCharacterImage 获得
+}
+
+type charactersResp struct {
+	Items  []Character `json:"items"`
+	Cursor *string     `json:"cursor"`
+}
+
+// ListCharacters 分页获取账号下的角色（最新在前）
+func (c *Client) ListCharacters(ctx context.Context, accessToken string, opts ListOptions) (*CharactersPage, error) {
+	body, err := c.doGet(ctx, c.soraBaseURL+"/project_y/characters?"+listQuery(opts, defaultCharactersLimit, "cursor"), c.baseHeaders(accessToken))
+	if err != nil {
+		return nil, fmt.Errorf("获取角色列表失败: %w", err)
+	}
+
+	var result charactersResp
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+
+	page := &CharactersPage{Characters: result.Items}
+	if result.Cursor != nil {
+		page.NextCursor = *result.Cursor
+	}
+	return page, nil
+}
+
+// GetCharacter 获取角色详情，角色不存在（已在上游删除）时返回的错误满足 errors.Is(err, ErrNotFound)
+func (c *Client) GetCharacter(ctx context.Context, accessToken, characterID string) (*Character, error) {
+	body, err := c.doGet(ctx, c.soraBaseURL+"/project_y/characters/"+url.PathEscape(characterID), c.baseHeaders(accessToken))
+	if err != nil {
+		return nil, fmt.Errorf("获取角色失败: %w", err)
+	}
+
+	var ch Character
+	if err := json.Unmarshal(body, &ch); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+	if ch.CharacterID == "" {
+		ch.CharacterID = characterID
+	}
+	return &ch, nil
+}
+
+// UpdateCharacter 修改角色的显示名称、用户名或头像
+func (c *Client) UpdateCharacter(ctx context.Context, accessToken, cameoID string, update CharacterUpdate) error {
+	payload := map[string]interface{}{}
+	if update.DisplayName != "" {
+		payload["display_name"] = update.DisplayName
+	}
+	if update.Username != "" {
+		payload["username"] = update.Username
+	}
+	if update.ProfileAssetPointer != "" {
+		payload["profile_asset_pointer"] = update.ProfileAssetPointer
+	}
+	if len(payload) == 0 {
+		return nil
+	}
+
+	_, err := c.doPost(ctx, c.soraBaseURL+"/project_y/cameos/by_id/"+cameoID+"/update_v2", c.jsonHeaders(accessToken), payload)
+	if err != nil {
+		return fmt.Errorf("更新角色失败: %w", err)
+	}
+	return nil
 }
@@ -5,9 +5,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"math/rand"
+	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	http "github.com/bogdanfinn/fhttp"
 	tls_client "github.com/bogdanfinn/tls-client"
@@ -15,9 +20,11 @@ import (
 )
 
 const (
-	soraBaseURL    = "https://sora.chatgpt.com/backend"
-	chatgptBaseURL = "https://chatgpt.com"
-	sentinelFlow   = "sora_2_create_task"
+	defaultRequestTimeout = 30 * time.Second // API 请求的整体超时
+	defaultSoraBaseURL    = "https://sora.chatgpt.com/backend"
+	defaultChatGPTBaseURL = "https://chatgpt.com"
+	defaultAuthBaseURL    = "https://auth.openai.com"
+	sentinelFlow          = "sora_2_create_task"
 )
 
 var desktopUserAgents = []string{
@@ -45,18 +52,122 @@ type Progress struct {
 // ProgressFunc 进度回调函数类型
 type ProgressFunc func(Progress)
 
-// Client Sora API 客户端
+// Doer 执行 HTTP 请求的最小接口（tls_client.HttpClient 已实现）
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client Sora API 客户端（可在多个 goroutine 间共享）
 type Client struct {
-	httpClient tls_client.HttpClient
-	rng        *rand.Rand
-	rngMu      sync.Mutex
+	httpClient Doer
+	wrapDoer   func(Doer) Doer // 包装最终使用的 Doer（如统计请求耗时）
+	proxyURL   string
+	builtinTLS bool // httpClient 是否为内置 TLS 客户端（未使用 WithDoer）
+
+	retry   RetryPolicy  // API 请求的重试策略
+	limiter *RateLimiter // 按 access token 限流，nil 不限流
+	hooks   Hooks        // 请求观察回调
+	logger  *slog.Logger // 为空时使用 slog.Default()
+
+	mediaOpts   MediaOptions // 媒体下载的超时与大小限制（已填充默认值）
+	mediaMu     sync.Mutex
+	mediaClient Doer // 媒体下载专用执行器（无整体超时，首次下载时创建）
+	rng         *rand.Rand
+	rngMu       sync.Mutex
+
+	soraBaseURL    string // Sora 后端地址
+	chatgptBaseURL string // ChatGPT 地址（sentinel 接口）
+	authBaseURL    string // OpenAI 认证地址（刷新 token）
+
+	maxUploadSize int64              // 单个上传文件大小上限（字节），<=0 不限制
+	fingerprint   FingerprintProfile // PoW 浏览器指纹（已填充默认值）
+	powWorkers    int                // PoW 并发 worker 数
+	powSolves     atomic.Int64
+	powFailures   atomic.Int64
+	powIterations atomic.Int64
+}
+
+// Option 客户端可选配置
+type Option func(*Client)
+
+// WithSoraBaseURL 覆盖 Sora 后端地址，默认 https://sora.chatgpt.com/backend
+func WithSoraBaseURL(baseURL string) Option {
+	return func(c *Client) { c.soraBaseURL = strings.TrimRight(baseURL, "/") }
+}
+
+// WithChatGPTBaseURL 覆盖 ChatGPT 地址（用于 sentinel 接口），默认 https://chatgpt.com
+func WithChatGPTBaseURL(baseURL string) Option {
+	return func(c *Client) { c.chatgptBaseURL = strings.TrimRight(baseURL, "/") }
+}
+
+// WithAuthBaseURL 覆盖 OpenAI 认证地址（用于刷新 token），默认 https://auth.openai.com
+func WithAuthBaseURL(baseURL string) Option {
+	return func(c *Client) { c.authBaseURL = strings.TrimRight(baseURL, "/") }
+}
+
+// WithPowWorkers 设置 PoW 计算的并发 worker 数，默认 runtime.GOMAXPROCS(0)
+func WithPowWorkers(n int) Option {
+	return func(c *Client) {
+		if n > 0 {
+			c.powWorkers = n
+		}
+	}
+}
+
+// WithFingerprint 使用指定的 PoW 浏览器指纹，空字段使用内置默认值
+func WithFingerprint(p FingerprintProfile) Option {
+	return func(c *Client) { c.fingerprint = p.withDefaults() }
+}
+
+// WithDoer 使用自定义的 HTTP 执行器替代内置的 TLS 客户端（此时 proxyURL 被忽略）
+func WithDoer(d Doer) Option {
+	return func(c *Client) { c.httpClient = d }
+}
+
+// WithDoerWrapper 包装客户端最终使用的 HTTP 执行器（内置 TLS 客户端或 WithDoer 指定的执行器），
+// 可用于统计请求耗时等
+func WithDoerWrapper(wrap func(Doer) Doer) Option {
+	return func(c *Client) { c.wrapDoer = wrap }
 }
 
 // New 创建客户端，proxyURL 为空则不使用代理
-func New(proxyURL string) (*Client, error) {
+func New(proxyURL string, opts ...Option) (*Client, error) {
+	client := &Client{
+		rng:            rand.New(rand.NewSource(rand.Int63())),
+		soraBaseURL:    defaultSoraBaseURL,
+		chatgptBaseURL: defaultChatGPTBaseURL,
+		authBaseURL:    defaultAuthBaseURL,
+		maxUploadSize:  DefaultMaxUploadSize,
+		fingerprint:    DefaultFingerprintProfile(),
+		powWorkers:     runtime.GOMAXPROCS(0),
+		retry:          DefaultRetryPolicy(),
+	}
+	for _, opt := range opts {
+		opt(client)
+	}
+	client.proxyURL = proxyURL
+	client.mediaOpts = client.mediaOpts.withDefaults()
+
+	if client.httpClient == nil {
+		c, err := newTLSClient(proxyURL, defaultRequestTimeout)
+		if err != nil {
+			return nil, err
+		}
+		client.httpClient = c
+		client.builtinTLS = true
+	}
+	if client.wrapDoer != nil {
+		client.httpClient = client.wrapDoer(client.httpClient)
+	}
+
+	return client, nil
+}
+
+// newTLSClient 创建模拟 Chrome TLS 指纹的 HTTP 客户端，timeout 为单个请求的整体超时（0 不限制）
+func newTLSClient(proxyURL string, timeout time.Duration) (Doer, error) {
 	options := []tls_client.HttpClientOption{
 		tls_client.WithClientProfile(profiles.Chrome_131),
-		tls_client.WithTimeoutSeconds(30),
+		tls_client.WithTimeoutMilliseconds(int(timeout / time.Millisecond)),
 		tls_client.WithNotFollowRedirects(),
 	}
 
@@ -70,11 +181,44 @@ func New(proxyURL string) (*Client, error) {
 	if err != nil {
 		return nil, fmt.Errorf("创建 TLS 客户端失败: %w", err)
 	}
+	return c, nil
+}
+
+// mediaDoer 返回媒体下载使用的执行器：与 API 请求使用相同的代理和 TLS 指纹，
+// 但不设整体超时（大文件由 MediaOptions 的连接/读取超时控制）；使用 WithDoer 时与 API 请求共用
+func (c *Client) mediaDoer() (Doer, error) {
+	c.mediaMu.Lock()
+	defer c.mediaMu.Unlock()
+
+	if c.mediaClient != nil {
+		return c.mediaClient, nil
+	}
+	if !c.builtinTLS {
+		c.mediaClient = c.httpClient
+		return c.mediaClient, nil
+	}
+	d, err := newTLSClient(c.proxyURL, 0)
+	if err != nil {
+		return nil, err
+	}
+	if c.wrapDoer != nil {
+		d = c.wrapDoer(d)
+	}
+	c.mediaClient = d
+	return d, nil
+}
+
+// CloseIdleConnections 关闭底层 HTTP 执行器的空闲连接（执行器不支持时忽略）
+func (c *Client) CloseIdleConnections() {
+	c.mediaMu.Lock()
+	media := c.mediaClient
+	c.mediaMu.Unlock()
 
-	return &Client{
-		httpClient: c,
-		rng:        rand.New(rand.NewSource(rand.Int63())),
-	}, nil
+	for _, d := range []Doer{c.httpClient, media} {
+		if closer, ok := d.(interface{ CloseIdleConnections() }); ok {
+			closer.CloseIdleConnections()
+		}
+	}
 }
 
 // randIntn 使用实例级别的随机数生成器，避免全局锁竞争
@@ -101,6 +245,15 @@ func (c *Client) randRead(b []byte) {
 }
 
 func (c *Client) doPost(ctx context.Context, url string, headers map[string]string, body interface{}) (map[string]interface{}, error) {
+	return c.postJSON(ctx, retryByMethod, url, headers, body)
+}
+
+// doSubmit 发送提交类 POST 请求（创建任务、发布等），默认不重试以免重复生成
+func (c *Client) doSubmit(ctx context.Context, url string, headers map[string]string, body interface{}) (map[string]interface{}, error) {
+	return c.postJSON(ctx, retrySubmit, url, headers, body)
+}
+
+func (c *Client) postJSON(ctx context.Context, mode retryMode, url string, headers map[string]string, body interface{}) (map[string]interface{}, error) {
 	select {
 	case <-ctx.Done():
 		return nil, ctx.Err()
@@ -112,34 +265,19 @@ func (c *Client) doPost(ctx context.Context, url string, headers map[string]stri
 		return nil, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(bodyBytes))
+	resp, err := c.do(ctx, mode, func() (*http.Request, error) {
+		return newRequest(ctx, "POST", url, headers, bytes.NewReader(bodyBytes))
+	})
 	if err != nil {
 		return nil, err
 	}
-	for k, v := range headers {
-		req.Header.Set(k, v)
-	}
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("请求失败: %w", err)
-	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
-			log.Printf("[sora] close response body failed: %v", err)
+			c.log().Warn("关闭响应体失败", "err", err)
 		}
 	}()
 
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("解析响应失败 (HTTP %d): %w", resp.StatusCode, err)
-	}
-
-	if resp.StatusCode != 200 && resp.StatusCode != 201 {
-		return result, fmt.Errorf("HTTP %d: %s", resp.StatusCode, extractAPIError(result))
-	}
-
-	return result, nil
+	return decodeJSONResponse(resp)
 }
 
 func (c *Client) doGet(ctx context.Context, url string, headers map[string]string) ([]byte, error) {
@@ -149,21 +287,15 @@ func (c *Client) doGet(ctx context.Context, url string, headers map[string]strin
 	default:
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	resp, err := c.do(ctx, retryByMethod, func() (*http.Request, error) {
+		return newRequest(ctx, "GET", url, headers, nil)
+	})
 	if err != nil {
 		return nil, err
 	}
-	for k, v := range headers {
-		req.Header.Set(k, v)
-	}
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("请求失败: %w", err)
-	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
-			log.Printf("[sora] close response body failed: %v", err)
+			c.log().Warn("关闭响应体失败", "err", err)
 		}
 	}()
 
@@ -173,48 +305,58 @@ func (c *Client) doGet(ctx context.Context, url string, headers map[string]strin
 	}
 
 	if resp.StatusCode != 200 && resp.StatusCode != 201 {
-		return buf, fmt.Errorf("HTTP %d: %s", resp.StatusCode, truncate(string(buf), 200))
+		return buf, newAPIError(resp, buf)
 	}
 
 	return buf, nil
 }
 
-func (c *Client) doPostMultipart(ctx context.Context, url string, headers map[string]string, body *bytes.Buffer, contentType string) (map[string]interface{}, error) {
+// decodeJSONResponse 读取 JSON 响应体，非 2xx 时返回 *APIError（result 尽量保留解析结果）
+func decodeJSONResponse(resp *http.Response) (map[string]interface{}, error) {
+	buf, err := readAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string]interface{}
+	decodeErr := json.Unmarshal(buf, &result)
+
+	if resp.StatusCode != 200 && resp.StatusCode != 201 {
+		return result, newAPIError(resp, buf)
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("解析响应失败 (HTTP %d): %w", resp.StatusCode, decodeErr)
+	}
+
+	return result, nil
+}
+
+// doPostMultipart 流式上传，请求体无法重放，不重试
+func (c *Client) doPostMultipart(ctx context.Context, url string, headers map[string]string, body io.Reader, contentType string) (map[string]interface{}, error) {
 	select {
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	default:
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, body)
+	resp, err := c.do(ctx, retryNever, func() (*http.Request, error) {
+		req, err := newRequest(ctx, "POST", url, headers, body)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", contentType)
+		return req, nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	for k, v := range headers {
-		req.Header.Set(k, v)
-	}
-	req.Header.Set("Content-Type", contentType)
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("请求失败: %w", err)
-	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
-			log.Printf("[sora] close response body failed: %v", err)
+			c.log().Warn("关闭响应体失败", "err", err)
 		}
 	}()
 
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("解析响应失败 (HTTP %d): %w", resp.StatusCode, err)
-	}
-
-	if resp.StatusCode != 200 && resp.StatusCode != 201 {
-		return result, fmt.Errorf("HTTP %d: %s", resp.StatusCode, extractAPIError(result))
-	}
-
-	return result, nil
+	return decodeJSONResponse(resp)
 }
 
 func (c *Client) doDelete(ctx context.Context, url string, headers map[string]string) error {
@@ -224,32 +366,38 @@ func (c *Client) doDelete(ctx context.Context, url string, headers map[string]st
 	default:
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+	resp, err := c.do(ctx, retryByMethod, func() (*http.Request, error) {
+		return newRequest(ctx, "DELETE", url, headers, nil)
+	})
 	if err != nil {
 		return err
 	}
-	for k, v := range headers {
-		req.Header.Set(k, v)
-	}
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("请求失败: %w", err)
-	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
-			log.Printf("[sora] close response body failed: %v", err)
+			c.log().Warn("关闭响应体失败", "err", err)
 		}
 	}()
 
 	if resp.StatusCode != 200 && resp.StatusCode != 204 {
 		buf, _ := readAll(resp.Body)
-		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, truncate(string(buf), 200))
+		return newAPIError(resp, buf)
 	}
 
 	return nil
 }
 
+// newRequest 构造带请求头的请求
+func newRequest(ctx context.Context, method, url string, headers map[string]string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return req, nil
+}
+
 // baseHeaders 返回基础请求头（Authorization + User-Agent + Origin + Referer）
 func (c *Client) baseHeaders(accessToken string) map[string]string {
 	return map[string]string{
@@ -278,7 +426,10 @@ func (c *Client) sentinelHeaders(accessToken, sentinelToken string) map[string]s
 func (c *Client) GenerateSentinelToken(ctx context.Context, accessToken string) (string, error) {
 	reqID := c.generateUUID()
 	userAgent := desktopUserAgents[c.randIntn(len(desktopUserAgents))]
-	powToken := c.getPowToken(userAgent)
+	powToken, err := c.getPowToken(ctx, userAgent)
+	if err != nil {
+		return "", fmt.Errorf("PoW 计算失败: %w", err)
+	}
 
 	headers := map[string]string{
 		"Accept":        "application/json, text/plain, */*",
@@ -295,10 +446,14 @@ func (c *Client) GenerateSentinelToken(ctx context.Context, accessToken string)
 		"id":   reqID,
 	}
 
-	resp, err := c.doPost(ctx, chatgptBaseURL+"/backend-api/sentinel/req", headers, payload)
+	resp, err := c.doPost(ctx, c.chatgptBaseURL+"/backend-api/sentinel/req", headers, payload)
 	if err != nil {
 		return "", fmt.Errorf("sentinel 请求失败: %w", err)
 	}
 
-	return c.buildSentinelToken(sentinelFlow, reqID, powToken, resp, userAgent), nil
+	token, err := c.buildSentinelToken(ctx, sentinelFlow, reqID, powToken, resp, userAgent)
+	if err != nil {
+		return "", fmt.Errorf("PoW 计算失败: %w", err)
+	}
+	return token, nil
 }
@@ -0,0 +1,233 @@
+package sora_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DouDOU-start/go-sora2api/sora"
+	"github.com/DouDOU-start/go-sora2api/sora/soratest"
+	http "github.com/bogdanfinn/fhttp"
+)
+
+const testAccessToken = "at_soratest"
+
+func newTestClient(t *testing.T, srv *soratest.Server, opts ...sora.Option) *sora.Client {
+	t.Helper()
+	client, err := sora.New("", append(srv.ClientOptions(), opts...)...)
+	if err != nil {
+		t.Fatalf("sora.New: %v", err)
+	}
+	return client
+}
+
+func findPending(tasks []sora.PendingTask, id string) *sora.PendingTask {
+	for i := range tasks {
+		if tasks[i].ID == id {
+			return &tasks[i]
+		}
+	}
+	return nil
+}
+
+// TestVideoLifecycle 提交 → pending 中推进 → 从 pending 消失 → drafts 取得下载链接
+func TestVideoLifecycle(t *testing.T) {
+	srv := soratest.NewServer()
+	defer srv.Close()
+	client := newTestClient(t, srv)
+	ctx := context.Background()
+
+	sentinel, err := client.GenerateSentinelToken(ctx, testAccessToken)
+	if err != nil {
+		t.Fatalf("GenerateSentinelToken: %v", err)
+	}
+	if n := len(srv.RequestsTo(http.MethodPost, "/backend-api/sentinel/req")); n != 1 {
+		t.Fatalf("sentinel 请求次数 = %d, want 1", n)
+	}
+
+	taskID, err := client.CreateVideo(ctx, testAccessToken, sentinel, sora.VideoRequest{Prompt: "a cat on the moon"})
+	if err != nil {
+		t.Fatalf("CreateVideo: %v", err)
+	}
+	creates := srv.RequestsTo(http.MethodPost, "/backend/nf/create")
+	if len(creates) != 1 {
+		t.Fatalf("create 请求次数 = %d, want 1", len(creates))
+	}
+	if got := creates[0].JSON()["prompt"]; got != "a cat on the moon" {
+		t.Errorf("提交的 prompt = %v", got)
+	}
+	if got := creates[0].Header.Get("Authorization"); got != "Bearer "+testAccessToken {
+		t.Errorf("Authorization = %q", got)
+	}
+
+	pending, err := client.ListPendingTasks(ctx, testAccessToken)
+	if err != nil {
+		t.Fatalf("ListPendingTasks: %v", err)
+	}
+	p := findPending(pending, taskID)
+	if p == nil {
+		t.Fatalf("pending 中未找到任务 %s", taskID)
+	}
+	if p.Status != soratest.StatusRunning || p.Progress != 50 {
+		t.Errorf("pending = %+v, want running 50%%", *p)
+	}
+
+	pending, err = client.ListPendingTasks(ctx, testAccessToken)
+	if err != nil {
+		t.Fatalf("ListPendingTasks: %v", err)
+	}
+	if p := findPending(pending, taskID); p != nil {
+		t.Fatalf("任务完成后仍在 pending 中: %+v", *p)
+	}
+
+	draft, err := client.GetDraft(ctx, testAccessToken, taskID)
+	if err != nil {
+		t.Fatalf("GetDraft: %v", err)
+	}
+	if draft.Violation() {
+		t.Errorf("草稿不应违规: %+v", draft)
+	}
+	if want := srv.FileURL(taskID + ".mp4"); draft.DownloadURL != want {
+		t.Errorf("DownloadURL = %q, want %q", draft.DownloadURL, want)
+	}
+}
+
+func TestVideoFailedLifecycle(t *testing.T) {
+	srv := soratest.NewServer()
+	defer srv.Close()
+	srv.EnqueueLifecycle(soratest.FailedLifecycle("blocked"))
+	client := newTestClient(t, srv)
+	ctx := context.Background()
+
+	taskID, err := client.CreateVideo(ctx, testAccessToken, "sentinel", sora.VideoRequest{Prompt: "p"})
+	if err != nil {
+		t.Fatalf("CreateVideo: %v", err)
+	}
+	pending, err := client.ListPendingTasks(ctx, testAccessToken)
+	if err != nil {
+		t.Fatalf("ListPendingTasks: %v", err)
+	}
+	p := findPending(pending, taskID)
+	if p == nil || p.Status != soratest.StatusFailed || p.FailureReason != "blocked" {
+		t.Fatalf("pending = %+v, want failed(blocked)", p)
+	}
+}
+
+func TestScriptedErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		body   string
+		call   func(context.Context, *sora.Client) error
+		want   error
+	}{
+		{
+			name:   "提交 429 不重试",
+			method: http.MethodPost,
+			path:   "/backend/nf/create",
+			status: http.StatusTooManyRequests,
+			body:   `{"error":{"code":"rate_limit_exceeded","message":"slow down"}}`,
+			call: func(ctx context.Context, c *sora.Client) error {
+				_, err := c.CreateVideo(ctx, testAccessToken, "sentinel", sora.VideoRequest{Prompt: "p"})
+				return err
+			},
+			want: sora.ErrRateLimited,
+		},
+		{
+			name:   "查询 401",
+			method: http.MethodGet,
+			path:   "/backend/nf/pending/v2",
+			status: http.StatusUnauthorized,
+			body:   `{"error":{"code":"token_expired","message":"expired"}}`,
+			call: func(ctx context.Context, c *sora.Client) error {
+				_, err := c.ListPendingTasks(ctx, testAccessToken)
+				return err
+			},
+			want: sora.ErrUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := soratest.NewServer()
+			defer srv.Close()
+			srv.InjectError(tt.method, tt.path, tt.status, tt.body)
+			client := newTestClient(t, srv)
+
+			err := tt.call(context.Background(), client)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if errors.Is(err, sora.ErrSentinelRejected) {
+				t.Errorf("不应视为 Sentinel 被拒: %v", err)
+			}
+			if n := len(srv.RequestsTo(tt.method, tt.path)); n != 1 {
+				t.Errorf("请求次数 = %d, want 1", n)
+			}
+		})
+	}
+}
+
+// TestGetRetriedAfter503 GET 请求遇到 503 时按重试策略重试
+func TestGetRetriedAfter503(t *testing.T) {
+	srv := soratest.NewServer()
+	defer srv.Close()
+	srv.InjectError(http.MethodGet, "/backend/nf/pending/v2", http.StatusServiceUnavailable, `{"error":{"message":"busy"}}`)
+	client := newTestClient(t, srv, sora.WithRetryPolicy(sora.RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    10 * time.Millisecond,
+	}))
+
+	if _, err := client.ListPendingTasks(context.Background(), testAccessToken); err != nil {
+		t.Fatalf("ListPendingTasks: %v", err)
+	}
+	if n := len(srv.RequestsTo(http.MethodGet, "/backend/nf/pending/v2")); n != 2 {
+		t.Errorf("请求次数 = %d, want 2", n)
+	}
+}
+
+func TestRefreshAccessToken(t *testing.T) {
+	srv := soratest.NewServer()
+	defer srv.Close()
+	client := newTestClient(t, srv)
+
+	at, rt, err := client.RefreshAccessToken(context.Background(), "rt_old", "")
+	if err != nil {
+		t.Fatalf("RefreshAccessToken: %v", err)
+	}
+	if at == "" || rt == "" {
+		t.Errorf("token 为空: at=%q rt=%q", at, rt)
+	}
+	if n := len(srv.RequestsTo(http.MethodPost, "/oauth/token")); n != 1 {
+		t.Errorf("oauth/token 请求次数 = %d, want 1", n)
+	}
+}
+
+// countingDoer 统计经过的请求数
+type countingDoer struct {
+	next  sora.Doer
+	count atomic.Int64
+}
+
+func (d *countingDoer) Do(req *http.Request) (*http.Response, error) {
+	d.count.Add(1)
+	return d.next.Do(req)
+}
+
+func TestWithDoer(t *testing.T) {
+	srv := soratest.NewServer()
+	defer srv.Close()
+	doer := &countingDoer{next: &http.Client{Timeout: 10 * time.Second}}
+	client := newTestClient(t, srv, sora.WithDoer(doer))
+
+	if _, err := client.ListPendingTasks(context.Background(), testAccessToken); err != nil {
+		t.Fatalf("ListPendingTasks: %v", err)
+	}
+	if n := doer.count.Load(); n != 1 {
+		t.Errorf("Doer 收到 %d 个请求, want 1", n)
+	}
+}
@@ -0,0 +1,425 @@
+package sora
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	http "github.com/bogdanfinn/fhttp"
+)
+
+// defaultDownloadRetries 下载中断后使用 Range 续传的默认最大次数
+const defaultDownloadRetries = 3
+
+// 媒体下载默认超时
+const (
+	DefaultMediaConnectTimeout = 30 * time.Second
+	DefaultMediaReadTimeout    = 60 * time.Second
+)
+
+var (
+	errMediaConnectTimeout = errors.New("等待响应头超时")
+	errMediaReadTimeout    = errors.New("读取响应体超时")
+)
+
+// MediaOptions 媒体下载的超时与大小限制，零值字段使用默认值
+type MediaOptions struct {
+	ConnectTimeout time.Duration // 建立连接到收到响应头的超时，默认 30s
+	ReadTimeout    time.Duration // 两次读到数据之间的最大间隔，超时后按 Range 续传，默认 60s
+	MaxSize        int64         // 单个文件大小上限（字节），<=0 表示不限制
+}
+
+// withDefaults 用默认值填充未设置的超时
+func (o MediaOptions) withDefaults() MediaOptions {
+	if o.ConnectTimeout <= 0 {
+		o.ConnectTimeout = DefaultMediaConnectTimeout
+	}
+	if o.ReadTimeout <= 0 {
+		o.ReadTimeout = DefaultMediaReadTimeout
+	}
+	return o
+}
+
+// WithMediaOptions 设置媒体下载（OpenMedia / DownloadTo / DownloadFile）的超时与大小限制
+func WithMediaOptions(o MediaOptions) Option {
+	return func(c *Client) { c.mediaOpts = o }
+}
+
+// DownloadProgress 下载进度
+type DownloadProgress struct {
+	Written int64 // 已下载字节数（含 Offset 之前的部分）
+	Total   int64 // 总字节数，未知时为 -1
+}
+
+// DownloadOptions 下载选项，零值可用
+type DownloadOptions struct {
+	Offset     int64                  // 起始偏移，本地已有前 Offset 字节时从此处续传
+	MaxRetries int                    // 连续中断（期间未读到新数据）时 Range 续传的最大次数，0 使用默认值 3，<0 不重试
+	OnProgress func(DownloadProgress) // 进度回调，每写入一块数据调用一次，可为空
+}
+
+// Media 流式读取的远程媒体，连接中断（含读取超时）时自动使用 Range 从断点续传
+// 读取结束时校验已读字节数与 Content-Length 一致，不一致返回 ErrIncompleteDownload；
+// 超过 MediaOptions.MaxSize 时返回 ErrMediaTooLarge
+type Media struct {
+	ContentType string // 响应的 Content-Type，可能为空
+	Size        int64  // 总字节数（来自 Content-Length / Content-Range），未知时为 -1
+
+	client     *Client
+	ctx        context.Context
+	url        string
+	body       io.ReadCloser
+	offset     int64 // 下一个要读取的字节位置
+	resumable  bool  // 服务端是否支持 Range
+	progressed bool  // 上次（重新）连接后是否读到过数据
+	retries    int   // 连续续传次数，读到新数据后清零
+	maxRetries int
+
+	reqCtx context.Context         // 当前请求的 context，超时时携带 cause
+	cancel context.CancelCauseFunc // 取消当前请求
+	timer  *time.Timer             // 当前请求的连接/读取超时计时器
+}
+
+// OpenMedia 以流的方式打开远程媒体，调用方负责关闭返回值
+func (c *Client) OpenMedia(ctx context.Context, fileURL string) (*Media, error) {
+	return c.openMedia(ctx, fileURL, 0, defaultDownloadRetries)
+}
+
+// DownloadTo 流式下载 fileURL 写入 w，返回本次写入的字节数
+// opts.Offset > 0 时通过 Range 请求只下载剩余部分（如续写本地未完成的文件）；
+// 服务端返回 416 且告知的总大小等于 Offset 时视为本地已完整，返回 0 和 nil
+func (c *Client) DownloadTo(ctx context.Context, fileURL string, w io.Writer, opts DownloadOptions) (int64, error) {
+	retries := opts.MaxRetries
+	switch {
+	case retries == 0:
+		retries = defaultDownloadRetries
+	case retries < 0:
+		retries = 0
+	}
+
+	m, err := c.openMedia(ctx, fileURL, opts.Offset, retries)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err := m.Close(); err != nil {
+			c.log().Warn("关闭媒体流失败", "err", err)
+		}
+	}()
+
+	var dst io.Writer = w
+	if opts.OnProgress != nil {
+		dst = &progressWriter{w: w, written: opts.Offset, media: m, fn: opts.OnProgress}
+		// 本地已有完整内容时不会写入数据，仍报告一次完成进度
+		if m.Size >= 0 && opts.Offset >= m.Size {
+			opts.OnProgress(DownloadProgress{Written: opts.Offset, Total: m.Size})
+		}
+	}
+	return io.Copy(dst, m)
+}
+
+// DownloadFile 下载 URL 内容并返回字节数据（大文件建议使用 DownloadTo / OpenMedia）
+func (c *Client) DownloadFile(ctx context.Context, fileURL string) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := c.DownloadTo(ctx, fileURL, &buf, DownloadOptions{}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Read 读取媒体内容，连接中断时按剩余重试次数从断点续传
+func (m *Media) Read(p []byte) (int, error) {
+	for {
+		n, err := m.body.Read(p)
+		m.offset += int64(n)
+		if n > 0 {
+			m.progressed = true
+			m.timer.Reset(m.client.mediaOpts.ReadTimeout)
+		}
+		if limit := m.client.mediaOpts.MaxSize; limit > 0 && m.offset > limit {
+			return n, fmt.Errorf("%w: 已超过 %d 字节", ErrMediaTooLarge, limit)
+		}
+		if err == nil {
+			return n, nil
+		}
+		if errors.Is(err, io.EOF) {
+			if m.Size < 0 || m.offset >= m.Size {
+				return n, io.EOF
+			}
+			err = io.ErrUnexpectedEOF
+		}
+
+		if m.progressed {
+			m.retries = 0
+		}
+		if m.ctx.Err() == nil && m.reqCtx.Err() != nil {
+			err = context.Cause(m.reqCtx)
+		}
+		if m.ctx.Err() != nil || !m.resumable || m.retries >= m.maxRetries {
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				return n, fmt.Errorf("%w: 已下载 %d/%d 字节", ErrIncompleteDownload, m.offset, m.Size)
+			}
+			return n, err
+		}
+
+		m.retries++
+		m.client.log().Warn("下载中断，断点续传", "err", err, "offset", m.offset, "retry", m.retries)
+		m.release()
+		if closeErr := m.body.Close(); closeErr != nil {
+			m.client.log().Warn("关闭响应体失败", "err", closeErr)
+		}
+		if err := m.wait(); err != nil {
+			return n, err
+		}
+		if err := m.open(m.offset); err != nil {
+			return n, err
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// Close 关闭底层连接
+func (m *Media) Close() error {
+	m.release()
+	return m.body.Close()
+}
+
+// release 停止当前请求的超时计时器并释放其 context
+func (m *Media) release() {
+	m.timer.Stop()
+	m.cancel(nil)
+}
+
+// openMedia 打开远程媒体，offset > 0 时从该位置开始读取
+func (c *Client) openMedia(ctx context.Context, fileURL string, offset int64, maxRetries int) (*Media, error) {
+	m := &Media{
+		Size:       -1,
+		client:     c,
+		ctx:        ctx,
+		url:        fileURL,
+		maxRetries: maxRetries,
+	}
+	if err := m.open(offset); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// open 发起（续传）请求，校验响应并更新 body / Size / offset
+func (m *Media) open(offset int64) error {
+	doer, err := m.client.mediaDoer()
+	if err != nil {
+		return err
+	}
+	opts := m.client.mediaOpts
+
+	// 收到响应头前按连接超时计时，之后改为读取间隔超时（每读到数据重新计时）
+	reqCtx, cancel := context.WithCancelCause(m.ctx)
+	timer := time.AfterFunc(opts.ConnectTimeout, func() { cancel(errMediaConnectTimeout) })
+	fail := func(err error) error {
+		timer.Stop()
+		cancel(nil)
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(reqCtx, "GET", m.url, nil)
+	if err != nil {
+		return fail(err)
+	}
+	req.Header.Set("User-Agent", desktopUserAgents[m.client.randIntn(len(desktopUserAgents))])
+	// 媒体本身已压缩；不显式指定时 fhttp 会自行请求 gzip 并丢弃 Content-Length
+	req.Header.Set("Accept-Encoding", "identity")
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	observed := m.client.observe(m.ctx, requestInfo(m.ctx, req.Method, req.URL.Path, "", m.retries+1))
+	resp, err := doer.Do(req)
+	if err != nil {
+		observed(0, err)
+		if m.ctx.Err() == nil && reqCtx.Err() != nil {
+			err = context.Cause(reqCtx)
+		}
+		return fail(fmt.Errorf("请求失败: %w", err))
+	}
+	observed(resp.StatusCode, nil)
+	if !timer.Stop() {
+		closeBody(resp)
+		return fail(fmt.Errorf("请求失败: %w", context.Cause(reqCtx)))
+	}
+	timer = time.AfterFunc(opts.ReadTimeout, func() { cancel(errMediaReadTimeout) })
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		m.resumable = strings.EqualFold(resp.Header.Get("Accept-Ranges"), "bytes")
+		if size := contentLength(resp); size >= 0 {
+			m.Size = size
+		}
+		if opts.MaxSize > 0 && m.Size > opts.MaxSize {
+			closeBody(resp)
+			return fail(fmt.Errorf("%w: %d 字节（上限 %d 字节）", ErrMediaTooLarge, m.Size, opts.MaxSize))
+		}
+		// 服务端忽略了 Range，跳过已读部分
+		if offset > 0 {
+			if _, err := io.CopyN(io.Discard, &idleReader{r: resp.Body, timer: timer, d: opts.ReadTimeout}, offset); err != nil {
+				closeBody(resp)
+				return fail(fmt.Errorf("续传跳过已下载部分失败: %w", err))
+			}
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// 本地已有完整内容（如上次写完后未及处理）时服务端返回 bytes */N，N 等于 offset 视为已下载完成
+		total, ok := parseUnsatisfiedRange(resp.Header.Get("Content-Range"))
+		if !ok || offset == 0 || total != offset {
+			return fail(readAPIError(resp))
+		}
+		closeBody(resp)
+		m.resumable = true
+		m.Size = total
+		resp.Body = http.NoBody
+	case http.StatusPartialContent:
+		start, total, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			closeBody(resp)
+			return fail(fmt.Errorf("续传响应 Content-Range 不匹配: %q（期望从 %d 开始）", resp.Header.Get("Content-Range"), offset))
+		}
+		m.resumable = true
+		m.Size = total
+		if opts.MaxSize > 0 && m.Size > opts.MaxSize {
+			closeBody(resp)
+			return fail(fmt.Errorf("%w: %d 字节（上限 %d 字节）", ErrMediaTooLarge, m.Size, opts.MaxSize))
+		}
+	default:
+		return fail(readAPIError(resp))
+	}
+
+	if m.ContentType == "" {
+		m.ContentType = resp.Header.Get("Content-Type")
+	}
+	m.body = resp.Body
+	m.reqCtx = reqCtx
+	m.cancel = cancel
+	m.timer = timer
+	m.offset = offset
+	m.progressed = false
+	return nil
+}
+
+// idleReader 每读到数据时重置读取超时计时器
+type idleReader struct {
+	r     io.Reader
+	timer *time.Timer
+	d     time.Duration
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.timer.Reset(r.d)
+	}
+	return n, err
+}
+
+// wait 续传前的退避等待
+func (m *Media) wait() error {
+	select {
+	case <-m.ctx.Done():
+		return m.ctx.Err()
+	case <-time.After(time.Duration(m.retries) * 500 * time.Millisecond):
+		return nil
+	}
+}
+
+// parseContentRange 解析 "bytes start-end/total"，total 未知（*）时返回 -1
+func parseContentRange(v string) (start, total int64, ok bool) {
+	v, found := strings.CutPrefix(strings.TrimSpace(v), "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+	rng, size, found := strings.Cut(v, "/")
+	if !found {
+		return 0, 0, false
+	}
+	first, _, found := strings.Cut(rng, "-")
+	if !found {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	if size == "*" {
+		return start, -1, true
+	}
+	total, err = strconv.ParseInt(size, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, total, true
+}
+
+// contentLength 返回响应体字节数，未知时为 -1
+// fhttp 的 HTTP/2 传输层总是把 resp.ContentLength 置为 -1，此时回退到原始 Content-Length 头；
+// 响应体经过压缩编码时该头是压缩后的长度，视为未知
+func contentLength(resp *http.Response) int64 {
+	if resp.ContentLength >= 0 {
+		return resp.ContentLength
+	}
+	if ce := resp.Header.Get("Content-Encoding"); ce != "" && !strings.EqualFold(ce, "identity") {
+		return -1
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(resp.Header.Get("Content-Length")), 10, 64)
+	if err != nil || n < 0 {
+		return -1
+	}
+	return n
+}
+
+// parseUnsatisfiedRange 解析 416 响应的 "bytes */total"
+func parseUnsatisfiedRange(v string) (total int64, ok bool) {
+	size, found := strings.CutPrefix(strings.TrimSpace(v), "bytes */")
+	if !found {
+		return 0, false
+	}
+	total, err := strconv.ParseInt(size, 10, 64)
+	if err != nil || total < 0 {
+		return 0, false
+	}
+	return total, true
+}
+
+// readAPIError 读取（截断的）错误响应体并关闭响应
+func readAPIError(resp *http.Response) *APIError {
+	buf, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	closeBody(resp)
+	return newAPIError(resp, buf)
+}
+
+func closeBody(resp *http.Response) {
+	if err := resp.Body.Close(); err != nil {
+		slog.Warn("关闭响应体失败", "err", err)
+	}
+}
+
+// progressWriter 写入时回调下载进度
+// 总字节数每次从 media 读取：续传时才从 Content-Range 得知大小的情况下也能报告正确的总数
+type progressWriter struct {
+	w       io.Writer
+	written int64
+	media   *Media
+	fn      func(DownloadProgress)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.written += int64(n)
+	p.fn(DownloadProgress{Written: p.written, Total: p.media.Size})
+	return n, err
+}
@@ -0,0 +1,367 @@
+package sora
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	http "github.com/bogdanfinn/fhttp"
+)
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		value string
+		start int64
+		total int64
+		ok    bool
+	}{
+		{"bytes 0-99/100", 0, 100, true},
+		{"bytes 4000-9999/10000", 4000, 10000, true},
+		{" bytes 10-19/* ", 10, -1, true},
+		{"bytes */10000", 0, 0, false},
+		{"bytes 10-19", 0, 0, false},
+		{"items 0-9/10", 0, 0, false},
+		{"bytes x-9/10", 0, 0, false},
+		{"bytes 0-9/y", 0, 0, false},
+		{"bytes 5/10", 0, 0, false},
+		{"", 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			start, total, ok := parseContentRange(tt.value)
+			if ok != tt.ok || start != tt.start || total != tt.total {
+				t.Errorf("parseContentRange(%q) = (%d, %d, %v), want (%d, %d, %v)",
+					tt.value, start, total, ok, tt.start, tt.total, tt.ok)
+			}
+		})
+	}
+}
+
+// mediaServer 模拟媒体服务：首次完整请求只发送 cutAt 字节后断开连接
+type mediaServer struct {
+	content     []byte
+	cutAt       int  // >0 时首个非 Range 请求在此处断开
+	rangeable   bool // 是否支持 Range
+	ignoreRange bool // 收到 Range 仍返回 200 全量
+	stallAt     int  // >0 时首个非 Range 请求发送该字节数后停顿 stall，模拟读取卡住
+	stall       time.Duration
+
+	mu     sync.Mutex
+	ranges []string // 每个请求的 Range 头
+}
+
+func (s *mediaServer) ServeHTTP(w nethttp.ResponseWriter, r *nethttp.Request) {
+	rng := r.Header.Get("Range")
+	s.mu.Lock()
+	s.ranges = append(s.ranges, rng)
+	n := len(s.ranges)
+	s.mu.Unlock()
+	if s.rangeable {
+		w.Header().Set("Accept-Ranges", "bytes")
+	}
+	w.Header().Set("Content-Type", "video/mp4")
+
+	if rng != "" && s.rangeable && !s.ignoreRange {
+		start, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+		if err != nil || start >= len(s.content) {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", len(s.content)))
+			w.WriteHeader(nethttp.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(s.content)-1, len(s.content)))
+		w.Header().Set("Content-Length", strconv.Itoa(len(s.content)-start))
+		w.WriteHeader(nethttp.StatusPartialContent)
+		_, _ = w.Write(s.content[start:])
+		return
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(len(s.content)))
+	w.WriteHeader(nethttp.StatusOK)
+	if s.stallAt > 0 && n == 1 {
+		_, _ = w.Write(s.content[:s.stallAt])
+		w.(nethttp.Flusher).Flush()
+		time.Sleep(s.stall)
+		return
+	}
+	if s.cutAt > 0 && n == 1 {
+		// 写入少于 Content-Length 的数据后返回，服务端关闭连接，客户端读到 unexpected EOF
+		_, _ = w.Write(s.content[:s.cutAt])
+		return
+	}
+	_, _ = w.Write(s.content)
+}
+
+func testContent(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i % 251)
+	}
+	return b
+}
+
+func TestDownloadTo(t *testing.T) {
+	content := testContent(10000)
+
+	tests := []struct {
+		name       string
+		srv        *mediaServer
+		opts       DownloadOptions
+		mediaOpts  MediaOptions
+		want       []byte
+		wantErr    error
+		wantRanges []string
+	}{
+		{
+			name:       "完整下载",
+			srv:        &mediaServer{content: content, rangeable: true},
+			want:       content,
+			wantRanges: []string{""},
+		},
+		{
+			name:       "中断后 Range 续传",
+			srv:        &mediaServer{content: content, rangeable: true, cutAt: 4000},
+			want:       content,
+			wantRanges: []string{"", "bytes=4000-"},
+		},
+		{
+			name:       "指定 Offset 只下载剩余部分",
+			srv:        &mediaServer{content: content, rangeable: true},
+			opts:       DownloadOptions{Offset: 6000},
+			want:       content[6000:],
+			wantRanges: []string{"bytes=6000-"},
+		},
+		{
+			name:       "服务端忽略 Range 时跳过已有部分",
+			srv:        &mediaServer{content: content, rangeable: true, ignoreRange: true},
+			opts:       DownloadOptions{Offset: 6000},
+			want:       content[6000:],
+			wantRanges: []string{"bytes=6000-"},
+		},
+		{
+			name:       "本地已完整时 416 视为完成",
+			srv:        &mediaServer{content: content, rangeable: true},
+			opts:       DownloadOptions{Offset: int64(len(content))},
+			want:       []byte{},
+			wantRanges: []string{"bytes=10000-"},
+		},
+		{
+			name:       "不支持 Range 时中断返回不完整",
+			srv:        &mediaServer{content: content, cutAt: 4000},
+			wantErr:    ErrIncompleteDownload,
+			wantRanges: []string{""},
+		},
+		{
+			name:       "关闭重试时中断返回不完整",
+			srv:        &mediaServer{content: content, rangeable: true, cutAt: 4000},
+			opts:       DownloadOptions{MaxRetries: -1},
+			wantErr:    ErrIncompleteDownload,
+			wantRanges: []string{""},
+		},
+		{
+			name:       "读取超时后 Range 续传",
+			srv:        &mediaServer{content: content, rangeable: true, stallAt: 4000, stall: 300 * time.Millisecond},
+			mediaOpts:  MediaOptions{ReadTimeout: 50 * time.Millisecond},
+			want:       content,
+			wantRanges: []string{"", "bytes=4000-"},
+		},
+		{
+			name:       "超过大小上限",
+			srv:        &mediaServer{content: content, rangeable: true},
+			mediaOpts:  MediaOptions{MaxSize: 5000},
+			wantErr:    ErrMediaTooLarge,
+			wantRanges: []string{""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(tt.srv)
+			defer ts.Close()
+			c, err := New("", WithMediaOptions(tt.mediaOpts))
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+
+			var buf bytes.Buffer
+			n, err := c.DownloadTo(context.Background(), ts.URL+"/video.mp4", &buf, tt.opts)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("DownloadTo: %v", err)
+				}
+				if n != int64(len(tt.want)) || !bytes.Equal(buf.Bytes(), tt.want) {
+					t.Errorf("下载 %d 字节，内容与期望不一致（期望 %d 字节）", n, len(tt.want))
+				}
+			}
+			tt.srv.mu.Lock()
+			ranges := tt.srv.ranges
+			tt.srv.mu.Unlock()
+			if strings.Join(ranges, ",") != strings.Join(tt.wantRanges, ",") {
+				t.Errorf("Range 请求头 = %q, want %q", ranges, tt.wantRanges)
+			}
+		})
+	}
+}
+
+func TestDownloadProgress(t *testing.T) {
+	content := testContent(10000)
+	ts := httptest.NewServer(&mediaServer{content: content, rangeable: true, cutAt: 4000})
+	defer ts.Close()
+	c, err := New("")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	var last DownloadProgress
+	var buf bytes.Buffer
+	_, err = c.DownloadTo(context.Background(), ts.URL+"/video.mp4", &buf, DownloadOptions{
+		OnProgress: func(p DownloadProgress) {
+			if p.Written < last.Written {
+				t.Errorf("进度回退: %d → %d", last.Written, p.Written)
+			}
+			last = p
+		},
+	})
+	if err != nil {
+		t.Fatalf("DownloadTo: %v", err)
+	}
+	if last.Written != int64(len(content)) || last.Total != int64(len(content)) {
+		t.Errorf("最终进度 = %+v, want %d/%d", last, len(content), len(content))
+	}
+}
+
+func TestContentLength(t *testing.T) {
+	tests := []struct {
+		name   string
+		length int64
+		header map[string]string
+		want   int64
+	}{
+		{"ContentLength 已知", 42, nil, 42},
+		{"回退到 Content-Length 头", -1, map[string]string{"Content-Length": "10000"}, 10000},
+		{"identity 编码", -1, map[string]string{"Content-Length": "10", "Content-Encoding": "identity"}, 10},
+		{"压缩编码视为未知", -1, map[string]string{"Content-Length": "10", "Content-Encoding": "gzip"}, -1},
+		{"无 Content-Length", -1, nil, -1},
+		{"Content-Length 无效", -1, map[string]string{"Content-Length": "abc"}, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{ContentLength: tt.length, Header: http.Header{}}
+			for k, v := range tt.header {
+				resp.Header.Set(k, v)
+			}
+			if got := contentLength(resp); got != tt.want {
+				t.Errorf("contentLength = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestOpenMediaSize 经内置 TLS 客户端（fhttp）打开媒体时也能从响应头得知大小
+func TestOpenMediaSize(t *testing.T) {
+	content := testContent(10000)
+	var acceptEncoding string
+	ts := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		acceptEncoding = r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		_, _ = w.Write(content)
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		maxSize  int64
+		wantSize int64
+		wantErr  error
+	}{
+		{"大小已知", 0, int64(len(content)), nil},
+		{"打开时即超过大小上限", 5000, 0, ErrMediaTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New("", WithMediaOptions(MediaOptions{MaxSize: tt.maxSize}))
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			m, err := c.OpenMedia(context.Background(), ts.URL+"/video.mp4")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("OpenMedia err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("OpenMedia: %v", err)
+			}
+			defer func() { _ = m.Close() }()
+			if m.Size != tt.wantSize {
+				t.Errorf("Size = %d, want %d", m.Size, tt.wantSize)
+			}
+			if acceptEncoding != "identity" {
+				t.Errorf("Accept-Encoding = %q, want identity", acceptEncoding)
+			}
+		})
+	}
+}
+
+func TestParseUnsatisfiedRange(t *testing.T) {
+	tests := []struct {
+		value string
+		total int64
+		ok    bool
+	}{
+		{"bytes */10000", 10000, true},
+		{" bytes */0 ", 0, true},
+		{"bytes 0-9/10", 0, false},
+		{"bytes */*", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			total, ok := parseUnsatisfiedRange(tt.value)
+			if ok != tt.ok || total != tt.total {
+				t.Errorf("parseUnsatisfiedRange(%q) = (%d, %v), want (%d, %v)", tt.value, total, ok, tt.total, tt.ok)
+			}
+		})
+	}
+}
+
+// TestDownloadRangeNotSatisfiable 416 时仅总大小等于 Offset 视为完成，否则返回上游错误
+func TestDownloadRangeNotSatisfiable(t *testing.T) {
+	content := testContent(10000)
+	ts := httptest.NewServer(&mediaServer{content: content, rangeable: true})
+	defer ts.Close()
+	c, err := New("")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	var last DownloadProgress
+	n, err := c.DownloadTo(context.Background(), ts.URL+"/video.mp4", io.Discard, DownloadOptions{
+		Offset:     int64(len(content)),
+		OnProgress: func(p DownloadProgress) { last = p },
+	})
+	if err != nil || n != 0 {
+		t.Fatalf("DownloadTo = (%d, %v), want (0, nil)", n, err)
+	}
+	if last.Written != int64(len(content)) || last.Total != int64(len(content)) {
+		t.Errorf("进度 = %+v, want %d/%d", last, len(content), len(content))
+	}
+
+	_, err = c.DownloadTo(context.Background(), ts.URL+"/video.mp4", io.Discard, DownloadOptions{Offset: 12000})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		t.Errorf("Offset 超过文件大小时 err = %v, want HTTP 416", err)
+	}
+}
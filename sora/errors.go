@@ -0,0 +1,148 @@
+package sora
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	http "github.com/bogdanfinn/fhttp"
+)
+
+// 哨兵错误，配合 errors.Is 判断上游错误类别
+var (
+	ErrUnauthorized       = errors.New("未授权（token 无效或已过期）")
+	ErrRateLimited        = errors.New("触发速率限制")
+	ErrContentViolation   = errors.New("内容违反使用政策")
+	ErrTaskFailed         = errors.New("任务失败")
+	ErrNotFound           = errors.New("资源不存在")
+	ErrSentinelRejected   = errors.New("Sentinel Token 被拒绝")
+	ErrUploadTooLarge     = errors.New("上传文件超过大小限制")
+	ErrIncompleteDownload = errors.New("下载内容不完整")
+	ErrMediaTooLarge      = errors.New("媒体文件超过大小限制")
+)
+
+// APIError 上游返回的非 2xx 响应
+type APIError struct {
+	StatusCode  int           // HTTP 状态码
+	Code        string        // 上游错误码（error.code），可能为空
+	Message     string        // 上游错误信息（error.message），无法解析时为截断后的响应体
+	RetryAfter  time.Duration // Retry-After 响应头，未提供时为 0
+	ResetsInSec int           // 响应体中的 access_resets_in_seconds，未提供时为 0
+}
+
+func (e *APIError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("HTTP %d: %s: %s", e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Message)
+}
+
+// Is 支持 errors.Is(err, ErrUnauthorized) 等哨兵错误判断
+func (e *APIError) Is(target error) bool {
+	code := strings.ToLower(e.Code)
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests || strings.Contains(code, "rate_limit")
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrContentViolation:
+		return strings.Contains(code, "violation") || strings.Contains(code, "content_policy") || strings.Contains(code, "moderation")
+	case ErrSentinelRejected:
+		// 仅按上游错误码或响应内容判断，CDN/WAF 或权限类 403 不视为 Sentinel 被拒
+		return strings.Contains(code, "sentinel") || strings.Contains(strings.ToLower(e.Message), "sentinel")
+	}
+	return false
+}
+
+// ResetsIn 返回上游建议的等待时间（优先 access_resets_in_seconds，其次 Retry-After），未知时为 0
+func (e *APIError) ResetsIn() time.Duration {
+	if e.ResetsInSec > 0 {
+		return time.Duration(e.ResetsInSec) * time.Second
+	}
+	return e.RetryAfter
+}
+
+// RetryAfterSeconds 从错误链中提取限流重置时间（秒），无法提取时返回 0
+func RetryAfterSeconds(err error) int {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return 0
+	}
+	return int(apiErr.ResetsIn().Seconds())
+}
+
+// TaskError 任务在上游失败或被判定为内容违规
+type TaskError struct {
+	TaskID    string // Sora 任务 ID
+	Reason    string // 上游给出的原因
+	Violation bool   // 是否为内容违规
+}
+
+func (e *TaskError) Error() string {
+	if e.Violation {
+		return fmt.Sprintf("内容违规: %s", e.Reason)
+	}
+	return fmt.Sprintf("任务失败: %s", e.Reason)
+}
+
+// Is 任务错误总是匹配 ErrTaskFailed，内容违规时同时匹配 ErrContentViolation
+func (e *TaskError) Is(target error) bool {
+	return target == ErrTaskFailed || (e.Violation && target == ErrContentViolation)
+}
+
+// newAPIError 根据响应状态码、响应头和响应体构造 APIError
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(body, &result); err != nil || result == nil {
+		apiErr.Message = truncate(string(body), 200)
+		return apiErr
+	}
+
+	apiErr.ResetsInSec = resetsInFromBody(result)
+	if errMap, ok := result["error"].(map[string]interface{}); ok {
+		apiErr.Code, _ = errMap["code"].(string)
+		apiErr.Message, _ = errMap["message"].(string)
+		if apiErr.ResetsInSec == 0 {
+			apiErr.ResetsInSec = resetsInFromBody(errMap)
+		}
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = extractAPIError(result)
+	}
+	return apiErr
+}
+
+// resetsInFromBody 读取 access_resets_in_seconds 字段
+func resetsInFromBody(m map[string]interface{}) int {
+	if v, ok := m["access_resets_in_seconds"].(float64); ok && v > 0 {
+		return int(v)
+	}
+	return 0
+}
+
+// parseRetryAfter 解析 Retry-After 头（秒数或 HTTP 日期）
+func parseRetryAfter(v string) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if sec, err := strconv.Atoi(v); err == nil && sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,70 @@
+package sora
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	http "github.com/bogdanfinn/fhttp"
+)
+
+func TestAPIErrorIs(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   []error // 应匹配的哨兵错误，其余均不应匹配
+	}{
+		{"401", http.StatusUnauthorized, `{"error":{"code":"token_expired"}}`, []error{ErrUnauthorized}},
+		{"429", http.StatusTooManyRequests, `{"error":{"message":"slow down"}}`, []error{ErrRateLimited}},
+		{"rate_limit 错误码", http.StatusBadRequest, `{"error":{"code":"rate_limit_exceeded"}}`, []error{ErrRateLimited}},
+		{"404", http.StatusNotFound, `not found`, []error{ErrNotFound}},
+		{"内容违规", http.StatusBadRequest, `{"error":{"code":"content_policy_violation"}}`, []error{ErrContentViolation}},
+		{"Sentinel 错误码", http.StatusForbidden, `{"error":{"code":"invalid_sentinel_token"}}`, []error{ErrSentinelRejected}},
+		{"Sentinel 消息", http.StatusBadRequest, `{"error":{"message":"Sentinel token invalid"}}`, []error{ErrSentinelRejected}},
+		{"普通 403", http.StatusForbidden, `{"error":{"code":"forbidden","message":"access denied"}}`, nil},
+		{"WAF 403", http.StatusForbidden, `<html>Just a moment...</html>`, nil},
+	}
+	sentinels := []error{ErrUnauthorized, ErrRateLimited, ErrNotFound, ErrContentViolation, ErrSentinelRejected}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			err := fmt.Errorf("包装: %w", newAPIError(resp, []byte(tt.body)))
+			for _, target := range sentinels {
+				want := false
+				for _, w := range tt.want {
+					want = want || w == target
+				}
+				if got := errors.Is(err, target); got != want {
+					t.Errorf("errors.Is(%q) = %v, want %v", target, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	header := http.Header{}
+	header.Set("Retry-After", "7")
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"非 APIError", errors.New("x"), 0},
+		{"Retry-After 头", newAPIError(&http.Response{StatusCode: 429, Header: header}, nil), 7},
+		{"响应体优先", newAPIError(&http.Response{StatusCode: 429, Header: header}, []byte(`{"error":{"access_resets_in_seconds":120}}`)), 120},
+		{"未知", &APIError{StatusCode: 429, RetryAfter: 0}, 0},
+		{"包装", fmt.Errorf("w: %w", &APIError{RetryAfter: 3 * time.Second}), 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RetryAfterSeconds(tt.err); got != tt.want {
+				t.Errorf("RetryAfterSeconds = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
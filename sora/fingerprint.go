@@ -0,0 +1,199 @@
+package sora
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+)
+
+// FingerprintProfile PoW 浏览器指纹配置（对应 getConfig 中的脚本、部署 ID、navigator 键、屏幕、核数和时区）
+// 上游轮换部署 ID 时只需更新 profile，无需重新编译；空字段使用内置默认值
+type FingerprintProfile struct {
+	Name          string   `json:"name,omitempty"`
+	Weight        int      `json:"weight,omitempty"`         // 多 profile 时的选取权重，<=0 视为 1
+	Scripts       []string `json:"scripts,omitempty"`        // _ssgManifest.js 等脚本地址
+	DPL           []string `json:"dpl,omitempty"`            // 部署 ID（prod-xxx）
+	NavigatorKeys []string `json:"navigator_keys,omitempty"` // navigator 属性，键值以 U+2212 分隔
+	Screens       []int    `json:"screens,omitempty"`        // 屏幕宽高之和
+	Cores         []int    `json:"cores,omitempty"`          // hardwareConcurrency
+	UTCOffset     *int     `json:"utc_offset,omitempty"`     // 时区偏移（分钟，东正西负），默认 -300（EST）
+	TimezoneName  string   `json:"timezone_name,omitempty"`  // 时区显示名，默认 Eastern Standard Time
+}
+
+// navigatorKeySep navigator 键值分隔符 U+2212 (MINUS SIGN)
+const navigatorKeySep = "\u2212"
+
+// defaultUTCOffset 默认时区偏移（EST，分钟）
+const defaultUTCOffset = -5 * 60
+
+// DefaultFingerprintProfile 返回内置的默认指纹（副本，可安全修改）
+func DefaultFingerprintProfile() FingerprintProfile {
+	offset := defaultUTCOffset
+	return FingerprintProfile{
+		Name: "default",
+		Scripts: []string{
+			"https://cdn.oaistatic.com/_next/static/cXh69klOLzS0Gy2joLDRS/_ssgManifest.js?dpl=453ebaec0d44c2decab71692e1bfe39be35a24b3",
+		},
+		DPL: []string{
+			"prod-f501fe933b3edf57aea882da888e1a544df99840",
+		},
+		// 注意: 分隔符是 U+2212 (MINUS SIGN)，不是 U+002D (HYPHEN-MINUS)
+		NavigatorKeys: []string{
+			"registerProtocolHandler\u2212function registerProtocolHandler() { [native code] }",
+			"storage\u2212[object StorageManager]",
+			"locks\u2212[object LockManager]",
+			"appCodeName\u2212Mozilla",
+			"permissions\u2212[object Permissions]",
+			"webdriver\u2212false",
+			"vendor\u2212Google Inc.",
+			"mediaDevices\u2212[object MediaDevices]",
+			"cookieEnabled\u2212true",
+			"product\u2212Gecko",
+			"productSub\u221220030107",
+			"hardwareConcurrency\u221232",
+			"onLine\u2212true",
+		},
+		Screens:      []int{3000, 4000, 3120, 4160},
+		Cores:        []int{8, 16, 24, 32},
+		UTCOffset:    &offset,
+		TimezoneName: "Eastern Standard Time",
+	}
+}
+
+// Validate 校验 profile 字段取值（空字段合法，表示使用默认值）
+func (p FingerprintProfile) Validate() error {
+	if p.Weight < 0 {
+		return fmt.Errorf("weight 不能为负数")
+	}
+	for _, s := range p.Scripts {
+		if !strings.HasPrefix(s, "https://") {
+			return fmt.Errorf("scripts 需为 https 地址: %q", s)
+		}
+	}
+	for _, d := range p.DPL {
+		if strings.TrimSpace(d) == "" {
+			return fmt.Errorf("dpl 不能包含空值")
+		}
+	}
+	for _, k := range p.NavigatorKeys {
+		if !strings.Contains(k, navigatorKeySep) {
+			return fmt.Errorf("navigator_keys 需以 U+2212 (\u2212) 分隔键值: %q", k)
+		}
+	}
+	for _, s := range p.Screens {
+		if s <= 0 {
+			return fmt.Errorf("screens 需为正整数: %d", s)
+		}
+	}
+	for _, c := range p.Cores {
+		if c <= 0 {
+			return fmt.Errorf("cores 需为正整数: %d", c)
+		}
+	}
+	if p.UTCOffset != nil && (*p.UTCOffset < -12*60 || *p.UTCOffset > 14*60) {
+		return fmt.Errorf("utc_offset 超出范围 [-720, 840]: %d", *p.UTCOffset)
+	}
+	return nil
+}
+
+// withDefaults 用内置默认值填充空字段
+func (p FingerprintProfile) withDefaults() FingerprintProfile {
+	def := DefaultFingerprintProfile()
+	if len(p.Scripts) == 0 {
+		p.Scripts = def.Scripts
+	}
+	if len(p.DPL) == 0 {
+		p.DPL = def.DPL
+	}
+	if len(p.NavigatorKeys) == 0 {
+		p.NavigatorKeys = def.NavigatorKeys
+	}
+	if len(p.Screens) == 0 {
+		p.Screens = def.Screens
+	}
+	if len(p.Cores) == 0 {
+		p.Cores = def.Cores
+	}
+	if p.UTCOffset == nil {
+		p.UTCOffset = def.UTCOffset
+	}
+	if p.TimezoneName == "" {
+		p.TimezoneName = def.TimezoneName
+	}
+	return p
+}
+
+// parseTime 按 profile 时区生成浏览器 Date.toString() 格式的时间字符串
+func (p FingerprintProfile) parseTime(now time.Time) string {
+	offset := *p.UTCOffset
+	sign := '+'
+	abs := offset
+	if offset < 0 {
+		sign, abs = '-', -offset
+	}
+	loc := time.FixedZone(p.TimezoneName, offset*60)
+	return fmt.Sprintf("%s GMT%c%02d%02d (%s)",
+		now.In(loc).Format("Mon Jan 02 2006 15:04:05"), sign, abs/60, abs%60, p.TimezoneName)
+}
+
+// ParseFingerprintProfiles 解析并校验 JSON 格式的 profile 列表（支持单个对象或数组）
+func ParseFingerprintProfiles(data []byte) ([]FingerprintProfile, error) {
+	trimmed := strings.TrimSpace(string(data))
+	if trimmed == "" {
+		return nil, nil
+	}
+
+	var profiles []FingerprintProfile
+	if strings.HasPrefix(trimmed, "{") {
+		var p FingerprintProfile
+		if err := json.Unmarshal([]byte(trimmed), &p); err != nil {
+			return nil, fmt.Errorf("解析指纹配置失败: %w", err)
+		}
+		profiles = []FingerprintProfile{p}
+	} else if err := json.Unmarshal([]byte(trimmed), &profiles); err != nil {
+		return nil, fmt.Errorf("解析指纹配置失败: %w", err)
+	}
+
+	for i, p := range profiles {
+		if err := p.Validate(); err != nil {
+			name := p.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i+1)
+			}
+			return nil, fmt.Errorf("指纹配置 %s 无效: %w", name, err)
+		}
+	}
+	return profiles, nil
+}
+
+// PickFingerprint 按权重为 key（如账号 ID）选取 profile，同一 key 在列表不变时始终得到同一 profile
+// profiles 为空时返回默认指纹
+func PickFingerprint(profiles []FingerprintProfile, key string) FingerprintProfile {
+	if len(profiles) == 0 {
+		return DefaultFingerprintProfile()
+	}
+
+	total := 0
+	for _, p := range profiles {
+		total += profileWeight(p)
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	n := int(h.Sum32() % uint32(total))
+	for _, p := range profiles {
+		n -= profileWeight(p)
+		if n < 0 {
+			return p
+		}
+	}
+	return profiles[len(profiles)-1]
+}
+
+func profileWeight(p FingerprintProfile) int {
+	if p.Weight <= 0 {
+		return 1
+	}
+	return p.Weight
+}
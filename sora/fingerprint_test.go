@@ -0,0 +1,148 @@
+package sora
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPickFingerprintWeighted(t *testing.T) {
+	tests := []struct {
+		name     string
+		profiles []FingerprintProfile
+		want     map[string]float64 // profile 名称 → 期望占比
+	}{
+		{
+			name:     "单个 profile",
+			profiles: []FingerprintProfile{{Name: "a", Weight: 5}},
+			want:     map[string]float64{"a": 1},
+		},
+		{
+			name:     "按权重 1:3",
+			profiles: []FingerprintProfile{{Name: "a", Weight: 1}, {Name: "b", Weight: 3}},
+			want:     map[string]float64{"a": 0.25, "b": 0.75},
+		},
+		{
+			name:     "权重 <=0 视为 1",
+			profiles: []FingerprintProfile{{Name: "a"}, {Name: "b", Weight: -1}, {Name: "c", Weight: 2}},
+			want:     map[string]float64{"a": 0.25, "b": 0.25, "c": 0.5},
+		},
+	}
+
+	const keys = 20000
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counts := make(map[string]int)
+			for i := 0; i < keys; i++ {
+				counts[PickFingerprint(tt.profiles, strconv.Itoa(i)).Name]++
+			}
+			for name, share := range tt.want {
+				got := float64(counts[name]) / keys
+				if math.Abs(got-share) > 0.03 {
+					t.Errorf("%s 占比 = %.3f, want %.2f±0.03", name, got, share)
+				}
+			}
+		})
+	}
+}
+
+func TestPickFingerprintStable(t *testing.T) {
+	profiles := []FingerprintProfile{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	for _, key := range []string{"1", "42", "account-7"} {
+		first := PickFingerprint(profiles, key).Name
+		for i := 0; i < 5; i++ {
+			if got := PickFingerprint(profiles, key).Name; got != first {
+				t.Fatalf("key %q 选取结果不稳定: %s / %s", key, first, got)
+			}
+		}
+	}
+
+	if got := PickFingerprint(nil, "1"); got.Name != DefaultFingerprintProfile().Name {
+		t.Errorf("空列表应返回默认指纹，got %q", got.Name)
+	}
+}
+
+func TestParseFingerprintProfiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []string // 解析出的 profile 名称
+		wantErr string
+	}{
+		{name: "空", data: "  ", want: nil},
+		{name: "单个对象", data: `{"name":"a","dpl":["prod-1"]}`, want: []string{"a"}},
+		{name: "数组", data: `[{"name":"a"},{"name":"b","weight":2}]`, want: []string{"a", "b"}},
+		{name: "JSON 无效", data: `[{"name":`, wantErr: "解析指纹配置失败"},
+		{name: "无名 profile 按序号报错", data: `[{"name":"a"},{"cores":[0]}]`, wantErr: "指纹配置 #2 无效"},
+		{name: "navigator 分隔符错误", data: `{"name":"x","navigator_keys":["vendor-Google"]}`, wantErr: "指纹配置 x 无效"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profiles, err := ParseFingerprintProfiles([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want 包含 %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseFingerprintProfiles: %v", err)
+			}
+			var names []string
+			for _, p := range profiles {
+				names = append(names, p.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("profiles = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestFingerprintValidate(t *testing.T) {
+	offset := func(v int) *int { return &v }
+	tests := []struct {
+		name    string
+		profile FingerprintProfile
+		ok      bool
+	}{
+		{"空 profile", FingerprintProfile{}, true},
+		{"默认 profile", DefaultFingerprintProfile(), true},
+		{"负权重", FingerprintProfile{Weight: -1}, false},
+		{"非 https 脚本", FingerprintProfile{Scripts: []string{"http://cdn/x.js"}}, false},
+		{"空 dpl", FingerprintProfile{DPL: []string{" "}}, false},
+		{"屏幕非正数", FingerprintProfile{Screens: []int{0}}, false},
+		{"时区上限", FingerprintProfile{UTCOffset: offset(14 * 60)}, true},
+		{"时区越界", FingerprintProfile{UTCOffset: offset(-13 * 60)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.profile.Validate(); (err == nil) != tt.ok {
+				t.Errorf("Validate() = %v, want ok=%v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestFingerprintParseTime(t *testing.T) {
+	now := time.Date(2025, 3, 4, 17, 30, 0, 0, time.UTC)
+	offset := 8 * 60
+
+	tests := []struct {
+		name    string
+		profile FingerprintProfile
+		want    string
+	}{
+		{"默认 EST", FingerprintProfile{}, "Tue Mar 04 2025 12:30:00 GMT-0500 (Eastern Standard Time)"},
+		{"东八区", FingerprintProfile{UTCOffset: &offset, TimezoneName: "China Standard Time"}, "Wed Mar 05 2025 01:30:00 GMT+0800 (China Standard Time)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.profile.withDefaults().parseTime(now); got != tt.want {
+				t.Errorf("parseTime = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package sora
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// RequestInfo 发往上游的单次 HTTP 请求摘要（不含 token、查询参数等敏感信息）
+type RequestInfo struct {
+	Method  string // HTTP 方法
+	Path    string // 路径模板，ID 等可变段替换为 {id}
+	Attempt int    // 第几次尝试，从 1 开始
+	Account string // 账号提示：WithAccountHint 设置的值，否则为 access token 的摘要（tok:xxxxxxxx），无 token 时为空
+}
+
+// ResponseInfo 单次 HTTP 请求的结果
+type ResponseInfo struct {
+	RequestInfo
+	Status  int           // HTTP 状态码，请求未得到响应时为 0
+	Latency time.Duration // 从发出请求到收到响应头的耗时
+	Err     error         // 请求错误（网络错误、超时等），收到响应时为 nil
+}
+
+// Hooks 请求观察回调，字段均可为空；回调在请求所在 goroutine 同步执行，应尽快返回
+type Hooks struct {
+	OnRequest  func(ctx context.Context, info RequestInfo)
+	OnResponse func(ctx context.Context, info ResponseInfo)
+}
+
+// WithHooks 设置请求观察回调（API 请求的每次尝试和媒体下载请求都会触发）
+func WithHooks(h Hooks) Option {
+	return func(c *Client) { c.hooks = h }
+}
+
+// WithLogger 设置客户端日志使用的 slog.Logger，默认 slog.Default()
+func WithLogger(l *slog.Logger) Option {
+	return func(c *Client) { c.logger = l }
+}
+
+// accountHintKey context 中账号提示的键
+type accountHintKey struct{}
+
+// WithAccountHint 在 ctx 中附加账号提示（如账号 ID），Hooks 回调的 RequestInfo.Account 优先使用该值
+func WithAccountHint(ctx context.Context, hint string) context.Context {
+	return context.WithValue(ctx, accountHintKey{}, hint)
+}
+
+// TokenHint 返回 access token 的不可逆摘要（tok:前 8 位 sha256），用于在日志中区分账号而不泄露 token
+func TokenHint(token string) string {
+	if token == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(token))
+	return "tok:" + hex.EncodeToString(sum[:4])
+}
+
+// log 返回客户端使用的日志记录器
+func (c *Client) log() *slog.Logger {
+	if c.logger != nil {
+		return c.logger
+	}
+	return slog.Default()
+}
+
+// requestInfo 构造请求摘要
+func requestInfo(ctx context.Context, method, path, token string, attempt int) RequestInfo {
+	account, _ := ctx.Value(accountHintKey{}).(string)
+	if account == "" {
+		account = TokenHint(token)
+	}
+	return RequestInfo{Method: method, Path: pathTemplate(path), Attempt: attempt, Account: account}
+}
+
+// observe 触发 OnRequest，返回在收到响应（或失败）后调用的 OnResponse 回调
+func (c *Client) observe(ctx context.Context, info RequestInfo) func(status int, err error) {
+	if c.hooks.OnRequest != nil {
+		c.hooks.OnRequest(ctx, info)
+	}
+	start := time.Now()
+	return func(status int, err error) {
+		if c.hooks.OnResponse != nil {
+			c.hooks.OnResponse(ctx, ResponseInfo{RequestInfo: info, Status: status, Latency: time.Since(start), Err: err})
+		}
+	}
+}
+
+// pathTemplate 将路径中的 ID 段（含 3 个以上数字，如 task_xxx、UUID）替换为 {id}，便于按接口聚合
+func pathTemplate(path string) string {
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if isIDSegment(seg) {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// isIDSegment 判断路径段是否为 ID
+func isIDSegment(seg string) bool {
+	digits := 0
+	for _, r := range seg {
+		if unicode.IsDigit(r) {
+			digits++
+		}
+	}
+	return digits >= 3
+}
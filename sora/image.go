@@ -0,0 +1,97 @@
+package sora
+
+import (
+	"context"
+	"fmt"
+)
+
+// 图片请求默认值
+const (
+	DefaultImageWidth  = 1792
+	DefaultImageHeight = 1024
+	MaxImageVariants   = 4
+)
+
+// ImageRequest 图片生成请求，零值字段使用默认值
+type ImageRequest struct {
+	Prompt    string // 提示词
+	Width     int    // 宽度（像素），默认 1792
+	Height    int    // 高度（像素），默认 1024
+	MediaID   string // UploadImage 返回的 mediaID，非空表示图生图
+	NVariants int    // 生成数量 1-4，默认 1
+}
+
+// Generation 任务的一个生成结果
+type Generation struct {
+	ID  string // generation ID（gen_xxx），用于发布帖子
+	URL string // 结果文件 URL
+}
+
+// CreateImage 按请求创建图片任务，返回 Sora 任务 ID
+func (c *Client) CreateImage(ctx context.Context, accessToken, sentinelToken string, req ImageRequest) (string, error) {
+	headers := c.sentinelHeaders(accessToken, sentinelToken)
+
+	width := req.Width
+	if width <= 0 {
+		width = DefaultImageWidth
+	}
+	height := req.Height
+	if height <= 0 {
+		height = DefaultImageHeight
+	}
+	nVariants := req.NVariants
+	if nVariants <= 0 {
+		nVariants = 1
+	}
+	if nVariants > MaxImageVariants {
+		nVariants = MaxImageVariants
+	}
+
+	operation := "simple_compose"
+	inpaintItems := []interface{}{}
+	if req.MediaID != "" {
+		operation = "remix"
+		inpaintItems = []interface{}{
+			map[string]interface{}{
+				"type":            "image",
+				"frame_index":     0,
+				"upload_media_id": req.MediaID,
+			},
+		}
+	}
+
+	payload := map[string]interface{}{
+		"type":          "image_gen",
+		"operation":     operation,
+		"prompt":        req.Prompt,
+		"width":         width,
+		"height":        height,
+		"n_variants":    nVariants,
+		"n_frames":      1,
+		"inpaint_items": inpaintItems,
+	}
+
+	resp, err := c.doSubmit(ctx, c.soraBaseURL+"/video_gen", headers, payload)
+	if err != nil {
+		return "", fmt.Errorf("创建图片任务失败: %w", err)
+	}
+
+	taskID, ok := resp["id"].(string)
+	if !ok || taskID == "" {
+		return "", fmt.Errorf("响应中无 task_id: %v", resp)
+	}
+
+	return taskID, nil
+}
+
+// toGenerations 提取带 URL 的生成结果
+func toGenerations(items []generationItem) []Generation {
+	gens := make([]Generation, 0, len(items))
+	for _, g := range items {
+		if g.URL == "" {
+			continue
+		}
+		gens = append(gens, Generation{ID: g.ID, URL: g.URL})
+	}
+	return gens
+}
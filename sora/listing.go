@@ -0,0 +1,223 @@
+package sora
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// 默认每页数量（与网页端一致）
+const (
+	defaultRecentTasksLimit = 20
+	defaultDraftsLimit      = 15
+)
+
+// 按任务 ID 翻页查找的上限：最多翻 taskLookupMaxPages 页或耗时超过 taskLookupTimeout
+const (
+	taskLookupMaxPages = 10
+	taskLookupTimeout  = 15 * time.Second
+)
+
+// ListOptions 分页参数
+type ListOptions struct {
+	Limit  int    // 每页数量，<=0 使用默认值（recent_tasks 20，drafts 15）
+	Cursor string // 上一页返回的 NextCursor，为空表示第一页
+}
+
+// RecentTask recent_tasks 中的一个任务（图片任务及近期视频任务）
+type RecentTask struct {
+	ID            string
+	Status        string // queued / running / succeeded / failed 等
+	FailureReason string
+	Progress      int          // 进度百分比 0-100
+	Generations   []Generation // 成功后的生成结果
+}
+
+// PendingTask nf/pending 中的一个进行中的视频任务（完成后从列表中消失）
+type PendingTask struct {
+	ID            string
+	Status        string // queued / running / failed 等
+	FailureReason string
+	Progress      int // 进度百分比 0-100
+}
+
+// RecentTasksPage recent_tasks 的一页结果
+type RecentTasksPage struct {
+	Tasks      []RecentTask
+	NextCursor string // 下一页游标，为空表示没有更多
+}
+
+// Draft 草稿箱中的一个视频（未发布的生成结果）
+type Draft struct {
+	TaskID          string
+	GenerationID    string // gen_xxx，用于发布帖子
+	Kind            string // sora_draft / sora_content_violation 等
+	ViolationReason string // 内容违规原因，为空表示未违规
+	DownloadURL     string // 下载链接（优先 downloadable_url）
+	Width           int
+	Height          int
+	Duration        float64   // 时长（秒）
+	Encodings       Encodings // 各清晰度、缩略图和 GIF 预览
+}
+
+// Violation 是否被判定为内容违规
+func (d Draft) Violation() bool {
+	return d.Kind == "sora_content_violation" || d.ViolationReason != ""
+}
+
+// DraftsPage 草稿箱的一页结果
+type DraftsPage struct {
+	Drafts     []Draft
+	NextCursor string // 下一页游标，为空表示没有更多
+}
+
+// ListRecentTasks 分页获取最近任务（最新在前）
+func (c *Client) ListRecentTasks(ctx context.Context, accessToken string, opts ListOptions) (*RecentTasksPage, error) {
+	result, err := c.listRecentTasks(ctx, c.baseHeaders(accessToken), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &RecentTasksPage{Tasks: make([]RecentTask, 0, len(result.TaskResponses))}
+	for i := range result.TaskResponses {
+		item := &result.TaskResponses[i]
+		page.Tasks = append(page.Tasks, RecentTask{
+			ID:            item.ID,
+			Status:        item.Status,
+			FailureReason: item.FailureReason,
+			Progress:      parseProgressFromNumber(item.ProgressPct),
+			Generations:   toGenerations(item.Generations),
+		})
+	}
+	page.NextCursor = result.nextCursor()
+	return page, nil
+}
+
+// ListPendingTasks 获取账号所有进行中的视频任务（一次请求，可用于同时跟踪多个任务）
+func (c *Client) ListPendingTasks(ctx context.Context, accessToken string) ([]PendingTask, error) {
+	body, err := c.doGet(ctx, c.soraBaseURL+"/nf/pending/v2", c.baseHeaders(accessToken))
+	if err != nil {
+		return nil, err
+	}
+
+	var items []pendingTaskItem
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, fmt.Errorf("解析失败: %w", err)
+	}
+
+	tasks := make([]PendingTask, 0, len(items))
+	for i := range items {
+		tasks = append(tasks, PendingTask{
+			ID:            items[i].ID,
+			Status:        items[i].Status,
+			FailureReason: items[i].FailureReason,
+			Progress:      parseProgressFromNumber(items[i].ProgressPct),
+		})
+	}
+	return tasks, nil
+}
+
+// ListDrafts 分页获取草稿箱（最新在前）
+func (c *Client) ListDrafts(ctx context.Context, accessToken string, opts ListOptions) (*DraftsPage, error) {
+	result, err := c.listDrafts(ctx, c.baseHeaders(accessToken), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &DraftsPage{Drafts: make([]Draft, 0, len(result.Items))}
+	for i := range result.Items {
+		page.Drafts = append(page.Drafts, result.Items[i].toDraft())
+	}
+	if result.Cursor != nil {
+		page.NextCursor = *result.Cursor
+	}
+	return page, nil
+}
+
+// listRecentTasks 请求一页 recent_tasks
+func (c *Client) listRecentTasks(ctx context.Context, headers map[string]string, opts ListOptions) (*recentTasksResp, error) {
+	body, err := c.doGet(ctx, c.soraBaseURL+"/v2/recent_tasks?"+listQuery(opts, defaultRecentTasksLimit, "before"), headers)
+	if err != nil {
+		return nil, err
+	}
+	var result recentTasksResp
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("解析 recent_tasks 响应失败: %w", err)
+	}
+	return &result, nil
+}
+
+// listDrafts 请求一页草稿箱
+func (c *Client) listDrafts(ctx context.Context, headers map[string]string, opts ListOptions) (*draftsResp, error) {
+	body, err := c.doGet(ctx, c.soraBaseURL+"/project_y/profile/drafts?"+listQuery(opts, defaultDraftsLimit, "cursor"), headers)
+	if err != nil {
+		return nil, err
+	}
+	var result draftsResp
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("解析 drafts 响应失败: %w", err)
+	}
+	return &result, nil
+}
+
+// findRecentTask 从第一页起逐页查找 recent_tasks 中的任务
+// 未找到（翻完、达到页数上限或超过查找时限）时返回 nil, nil
+func (c *Client) findRecentTask(ctx context.Context, headers map[string]string, taskID string) (*taskResponseItem, error) {
+	deadline := time.Now().Add(taskLookupTimeout)
+	var opts ListOptions
+	for page := 0; page < taskLookupMaxPages; page++ {
+		result, err := c.listRecentTasks(ctx, headers, opts)
+		if err != nil {
+			return nil, err
+		}
+		for i := range result.TaskResponses {
+			if result.TaskResponses[i].ID == taskID {
+				return &result.TaskResponses[i], nil
+			}
+		}
+		opts.Cursor = result.nextCursor()
+		if opts.Cursor == "" || time.Now().After(deadline) {
+			break
+		}
+	}
+	return nil, nil
+}
+
+// findDraft 从第一页起逐页查找草稿箱中的任务，未找到时返回 nil, nil
+func (c *Client) findDraft(ctx context.Context, headers map[string]string, taskID string) (*draftItem, error) {
+	deadline := time.Now().Add(taskLookupTimeout)
+	var opts ListOptions
+	for page := 0; page < taskLookupMaxPages; page++ {
+		result, err := c.listDrafts(ctx, headers, opts)
+		if err != nil {
+			return nil, err
+		}
+		for i := range result.Items {
+			if result.Items[i].TaskID == taskID {
+				return &result.Items[i], nil
+			}
+		}
+		if result.Cursor == nil || *result.Cursor == "" || time.Now().After(deadline) {
+			break
+		}
+		opts.Cursor = *result.Cursor
+	}
+	return nil, nil
+}
+
+// listQuery 构造分页查询参数，cursorParam 为上游的游标参数名
+func listQuery(opts ListOptions, defaultLimit int, cursorParam string) string {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	q := url.Values{}
+	q.Set("limit", strconv.Itoa(limit))
+	if opts.Cursor != "" {
+		q.Set(cursorParam, opts.Cursor)
+	}
+	return q.Encode()
+}
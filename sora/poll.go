@@ -13,6 +13,16 @@ import (
 
 type recentTasksResp struct {
 	TaskResponses []taskResponseItem `json:"task_responses"`
+	LastID        string             `json:"last_id"`
+	HasMore       bool               `json:"has_more"`
+}
+
+// nextCursor 下一页游标（上一页最后一个任务 ID），没有更多时为空
+func (r *recentTasksResp) nextCursor() string {
+	if !r.HasMore {
+		return ""
+	}
+	return r.LastID
 }
 
 type taskResponseItem struct {
@@ -36,17 +46,59 @@ type pendingTaskItem struct {
 }
 
 type draftsResp struct {
-	Items []draftItem `json:"items"`
+	Items  []draftItem `json:"items"`
+	Cursor *string     `json:"cursor"` // 下一页游标，没有更多时为 null
 }
 
 type draftItem struct {
-	TaskID          string `json:"task_id"`
-	GenerationID    string `json:"generation_id"` // gen_xxx，用于发布帖子
-	Kind            string `json:"kind"`
-	ReasonStr       string `json:"reason_str"`
-	MarkdownReason  string `json:"markdown_reason_str"`
-	DownloadableURL string `json:"downloadable_url"`
-	URL             string `json:"url"`
+	TaskID          string    `json:"task_id"`
+	GenerationID    string    `json:"generation_id"` // gen_xxx，用于发布帖子
+	Kind            string    `json:"kind"`
+	ReasonStr       string    `json:"reason_str"`
+	MarkdownReason  string    `json:"markdown_reason_str"`
+	DownloadableURL string    `json:"downloadable_url"`
+	URL             string    `json:"url"`
+	Width           int       `json:"width"`
+	Height          int       `json:"height"`
+	DurationS       float64   `json:"duration_s"`
+	Encodings       Encodings `json:"encodings"`
+}
+
+// violationReason 内容违规原因，未违规时为空
+func (d *draftItem) violationReason() string {
+	if d.ReasonStr != "" {
+		return d.ReasonStr
+	}
+	if d.MarkdownReason != "" {
+		return d.MarkdownReason
+	}
+	if d.Kind == "sora_content_violation" {
+		return "内容违反使用政策"
+	}
+	return ""
+}
+
+// toDraft 转换为公开的 Draft 结构
+func (d *draftItem) toDraft() Draft {
+	return Draft{
+		TaskID:          d.TaskID,
+		GenerationID:    d.GenerationID,
+		Kind:            d.Kind,
+		ViolationReason: d.violationReason(),
+		DownloadURL:     d.downloadURL(),
+		Width:           d.Width,
+		Height:          d.Height,
+		Duration:        d.DurationS,
+		Encodings:       d.Encodings,
+	}
+}
+
+// downloadURL 下载链接，优先使用 downloadable_url
+func (d *draftItem) downloadURL() string {
+	if d.DownloadableURL != "" {
+		return d.DownloadableURL
+	}
+	return d.URL
 }
 
 // parseProgressFromNumber 从 json.Number 解析进度百分比
@@ -63,11 +115,11 @@ func parseProgressFromNumber(n json.Number) int {
 
 // backoff 计算退避间隔：min(base * 2^attempt, maxInterval)
 func backoff(base time.Duration, attempt int, maxInterval time.Duration) time.Duration {
-	d := time.Duration(float64(base) * math.Pow(1.5, float64(attempt)))
-	if d > maxInterval {
-		d = maxInterval
+	d := float64(base) * math.Pow(2, float64(attempt))
+	if d > float64(maxInterval) {
+		return maxInterval
 	}
-	return d
+	return time.Duration(d)
 }
 
 // sleepWithContext 可被 ctx 取消的 sleep
@@ -82,47 +134,44 @@ func sleepWithContext(ctx context.Context, d time.Duration) error {
 	}
 }
 
-// PollImageTask 轮询图片任务进度，返回图片 URL
+// PollImageTask 轮询图片任务进度，返回第一张图片 URL
 // onProgress 可为 nil，非 nil 时在每次轮询后回调进度
 func (c *Client) PollImageTask(ctx context.Context, accessToken, taskID string, pollInterval, pollTimeout time.Duration, onProgress ProgressFunc) (string, error) {
+	gens, err := c.PollImageGenerations(ctx, accessToken, taskID, pollInterval, pollTimeout, onProgress)
+	if err != nil {
+		return "", err
+	}
+	return gens[0].URL, nil
+}
+
+// PollImageGenerations 轮询图片任务进度，返回全部生成结果（多张图片时按上游顺序）
+// onProgress 可为 nil，非 nil 时在每次轮询后回调进度
+func (c *Client) PollImageGenerations(ctx context.Context, accessToken, taskID string, pollInterval, pollTimeout time.Duration, onProgress ProgressFunc) ([]Generation, error) {
 	headers := c.baseHeaders(accessToken)
 
 	startTime := time.Now()
 	if err := sleepWithContext(ctx, 2*time.Second); err != nil {
-		return "", err
+		return nil, err
 	}
 
 	failCount := 0
 	for {
 		elapsed := time.Since(startTime)
 		if elapsed > pollTimeout {
-			return "", fmt.Errorf("轮询超时 (%v)", pollTimeout)
+			return nil, fmt.Errorf("轮询超时 (%v)", pollTimeout)
 		}
 
-		body, err := c.doGet(ctx, soraBaseURL+"/v2/recent_tasks?limit=20", headers)
+		task, err := c.findRecentTask(ctx, headers, taskID)
 		if err != nil {
 			failCount++
 			if err := sleepWithContext(ctx, backoff(pollInterval, failCount, 30*time.Second)); err != nil {
-				return "", err
+				return nil, err
 			}
 			continue
 		}
 		failCount = 0
 
-		var result recentTasksResp
-		if err := json.Unmarshal(body, &result); err != nil {
-			if err := sleepWithContext(ctx, pollInterval); err != nil {
-				return "", err
-			}
-			continue
-		}
-
-		for i := range result.TaskResponses {
-			task := &result.TaskResponses[i]
-			if task.ID != taskID {
-				continue
-			}
-
+		if task != nil {
 			progressPct := parseProgressFromNumber(task.ProgressPct)
 
 			if onProgress != nil {
@@ -134,23 +183,19 @@ func (c *Client) PollImageTask(ctx context.Context, accessToken, taskID string,
 			}
 
 			if task.Status == "failed" || task.Status == "error" {
-				return "", fmt.Errorf("任务失败: %s", task.FailureReason)
+				return nil, &TaskError{TaskID: taskID, Reason: task.FailureReason}
 			}
 
 			if task.Status == "succeeded" {
-				for j := range task.Generations {
-					if task.Generations[j].URL != "" {
-						return task.Generations[j].URL, nil
-					}
+				if gens := toGenerations(task.Generations); len(gens) > 0 {
+					return gens, nil
 				}
-				return "", fmt.Errorf("任务成功但未找到图片 URL")
+				return nil, fmt.Errorf("任务成功但未找到图片 URL")
 			}
-
-			break
 		}
 
 		if err := sleepWithContext(ctx, pollInterval); err != nil {
-			return "", err
+			return nil, err
 		}
 	}
 }
@@ -176,7 +221,7 @@ func (c *Client) PollVideoTask(ctx context.Context, accessToken, taskID string,
 			return fmt.Errorf("轮询超时 (%v)", pollTimeout)
 		}
 
-		body, err := c.doGet(ctx, soraBaseURL+"/nf/pending/v2", headers)
+		body, err := c.doGet(ctx, c.soraBaseURL+"/nf/pending/v2", headers)
 		if err != nil {
 			failCount++
 			if err := sleepWithContext(ctx, backoff(pollInterval, failCount, 30*time.Second)); err != nil {
@@ -219,7 +264,7 @@ func (c *Client) PollVideoTask(ctx context.Context, accessToken, taskID string,
 			}
 
 			if task.Status == "failed" || task.Status == "error" {
-				return fmt.Errorf("任务失败: %s", task.FailureReason)
+				return &TaskError{TaskID: taskID, Reason: task.FailureReason}
 			}
 			break
 		}
@@ -240,65 +285,48 @@ func (c *Client) PollVideoTask(ctx context.Context, accessToken, taskID string,
 	}
 }
 
-// GetDownloadURL 从 drafts 接口获取下载链接
-func (c *Client) GetDownloadURL(ctx context.Context, accessToken, taskID string) (string, error) {
+// GetDraft 从 drafts 接口获取视频任务的生成结果（下载链接、编码、尺寸和时长）
+// 任务刚完成时草稿可能尚未就绪，最多重试 3 次；被判定内容违规时仍返回草稿，由调用方检查 Violation
+func (c *Client) GetDraft(ctx context.Context, accessToken, taskID string) (*Draft, error) {
 	headers := c.baseHeaders(accessToken)
 
 	for attempt := 0; attempt < 3; attempt++ {
-		body, err := c.doGet(ctx, soraBaseURL+"/project_y/profile/drafts?limit=15", headers)
+		item, err := c.findDraft(ctx, headers, taskID)
 		if err != nil {
 			if attempt < 2 {
 				if err := sleepWithContext(ctx, backoff(3*time.Second, attempt, 15*time.Second)); err != nil {
-					return "", err
+					return nil, err
 				}
 			}
 			continue
 		}
 
-		var result draftsResp
-		if err := json.Unmarshal(body, &result); err != nil {
-			if attempt < 2 {
-				if err := sleepWithContext(ctx, 3*time.Second); err != nil {
-					return "", err
-				}
-			}
-			continue
-		}
-
-		for i := range result.Items {
-			item := &result.Items[i]
-			if item.TaskID != taskID {
-				continue
-			}
-
-			if item.Kind == "sora_content_violation" || item.ReasonStr != "" || item.MarkdownReason != "" {
-				reason := item.ReasonStr
-				if reason == "" {
-					reason = item.MarkdownReason
-				}
-				if reason == "" {
-					reason = "内容违反使用政策"
-				}
-				return "", fmt.Errorf("内容违规: %s", reason)
-			}
-
-			downloadURL := item.DownloadableURL
-			if downloadURL == "" {
-				downloadURL = item.URL
-			}
-			if downloadURL != "" {
-				return downloadURL, nil
+		if item != nil {
+			if draft := item.toDraft(); draft.Violation() || draft.DownloadURL != "" {
+				return &draft, nil
 			}
 		}
 
 		if attempt < 2 {
 			if err := sleepWithContext(ctx, 3*time.Second); err != nil {
-				return "", err
+				return nil, err
 			}
 		}
 	}
 
-	return "", fmt.Errorf("在最近草稿中未找到任务 %s", taskID)
+	return nil, fmt.Errorf("在最近草稿中未找到任务 %s: %w", taskID, ErrNotFound)
+}
+
+// GetDownloadURL 从 drafts 接口获取下载链接（逐页查找，任务不在第一页时也能找到）
+func (c *Client) GetDownloadURL(ctx context.Context, accessToken, taskID string) (string, error) {
+	draft, err := c.GetDraft(ctx, accessToken, taskID)
+	if err != nil {
+		return "", err
+	}
+	if draft.Violation() {
+		return "", &TaskError{TaskID: taskID, Reason: draft.ViolationReason, Violation: true}
+	}
+	return draft.DownloadURL, nil
 }
 
 // GetGenerationID 从 recent_tasks 或 drafts 接口获取任务的 generation ID
@@ -307,50 +335,32 @@ func (c *Client) GetGenerationID(ctx context.Context, accessToken, taskID string
 	headers := c.baseHeaders(accessToken)
 
 	// 先从 recent_tasks 获取
-	body, err := c.doGet(ctx, soraBaseURL+"/v2/recent_tasks?limit=20", headers)
-	if err == nil {
-		var result recentTasksResp
-		if err := json.Unmarshal(body, &result); err == nil {
-			for i := range result.TaskResponses {
-				task := &result.TaskResponses[i]
-				if task.ID != taskID {
-					continue
-				}
-				for j := range task.Generations {
-					if task.Generations[j].ID != "" {
-						return task.Generations[j].ID, nil
-					}
-				}
+	if task, err := c.findRecentTask(ctx, headers, taskID); err == nil && task != nil {
+		for j := range task.Generations {
+			if task.Generations[j].ID != "" {
+				return task.Generations[j].ID, nil
 			}
 		}
 	}
 
 	// 回退到 drafts 接口
-	body, err = c.doGet(ctx, soraBaseURL+"/project_y/profile/drafts?limit=15", headers)
+	item, err := c.findDraft(ctx, headers, taskID)
 	if err != nil {
 		return "", fmt.Errorf("获取 generation ID 失败: %w", err)
 	}
-
-	var drafts draftsResp
-	if err := json.Unmarshal(body, &drafts); err != nil {
-		return "", fmt.Errorf("解析 drafts 响应失败: %w", err)
-	}
-
-	for i := range drafts.Items {
-		item := &drafts.Items[i]
-		if item.TaskID == taskID && item.GenerationID != "" {
-			return item.GenerationID, nil
-		}
+	if item != nil && item.GenerationID != "" {
+		return item.GenerationID, nil
 	}
 
-	return "", fmt.Errorf("未找到任务 %s 的 generation ID", taskID)
+	return "", fmt.Errorf("未找到任务 %s 的 generation ID: %w", taskID, ErrNotFound)
 }
 
 // ImageTaskResult 图片任务单次查询结果
 type ImageTaskResult struct {
 	Progress Progress
 	Done     bool
-	ImageURL string
+	ImageURL string       // 第一张图片 URL
+	Images   []Generation // 全部生成结果
 	Err      error
 }
 
@@ -367,34 +377,22 @@ func (c *Client) QueryImageTaskOnce(ctx context.Context, accessToken, taskID str
 
 	elapsed := time.Since(startTime)
 
-	body, err := c.doGet(ctx, soraBaseURL+"/v2/recent_tasks?limit=20", headers)
+	task, err := c.findRecentTask(ctx, headers, taskID)
 	if err != nil {
 		return ImageTaskResult{Err: fmt.Errorf("查询失败: %w", err)}
 	}
 
-	var result recentTasksResp
-	if err := json.Unmarshal(body, &result); err != nil {
-		return ImageTaskResult{Err: fmt.Errorf("解析失败: %w", err)}
-	}
-
-	for i := range result.TaskResponses {
-		task := &result.TaskResponses[i]
-		if task.ID != taskID {
-			continue
-		}
-
+	if task != nil {
 		progressPct := parseProgressFromNumber(task.ProgressPct)
 		progress := Progress{Percent: progressPct, Status: task.Status, Elapsed: int(elapsed.Seconds())}
 
 		if task.Status == "failed" || task.Status == "error" {
-			return ImageTaskResult{Progress: progress, Done: true, Err: fmt.Errorf("任务失败: %s", task.FailureReason)}
+			return ImageTaskResult{Progress: progress, Done: true, Err: &TaskError{TaskID: taskID, Reason: task.FailureReason}}
 		}
 
 		if task.Status == "succeeded" {
-			for j := range task.Generations {
-				if task.Generations[j].URL != "" {
-					return ImageTaskResult{Progress: progress, Done: true, ImageURL: task.Generations[j].URL}
-				}
+			if gens := toGenerations(task.Generations); len(gens) > 0 {
+				return ImageTaskResult{Progress: progress, Done: true, ImageURL: gens[0].URL, Images: gens}
 			}
 			return ImageTaskResult{Progress: progress, Done: true, Err: fmt.Errorf("任务成功但未找到图片 URL")}
 		}
@@ -408,35 +406,27 @@ func (c *Client) QueryImageTaskOnce(ctx context.Context, accessToken, taskID str
 // QueryVideoTaskOnce 单次查询视频任务状态（非阻塞，供 TUI 使用）
 // maxProgress 应传入之前的最大进度值，返回的结果中会包含更新后的进度
 func (c *Client) QueryVideoTaskOnce(ctx context.Context, accessToken, taskID string, startTime time.Time, maxProgress int) VideoTaskResult {
-	headers := c.baseHeaders(accessToken)
-
 	elapsed := time.Since(startTime)
 
-	body, err := c.doGet(ctx, soraBaseURL+"/nf/pending/v2", headers)
+	tasks, err := c.ListPendingTasks(ctx, accessToken)
 	if err != nil {
 		return VideoTaskResult{Err: fmt.Errorf("查询失败: %w", err)}
 	}
 
-	var tasks []pendingTaskItem
-	if err := json.Unmarshal(body, &tasks); err != nil {
-		return VideoTaskResult{Err: fmt.Errorf("解析失败: %w", err)}
-	}
-
 	for i := range tasks {
 		task := &tasks[i]
 		if task.ID != taskID {
 			continue
 		}
 
-		progressPct := parseProgressFromNumber(task.ProgressPct)
-		if progressPct > maxProgress {
-			maxProgress = progressPct
+		if task.Progress > maxProgress {
+			maxProgress = task.Progress
 		}
 
 		progress := Progress{Percent: maxProgress, Status: task.Status, Elapsed: int(elapsed.Seconds())}
 
 		if task.Status == "failed" || task.Status == "error" {
-			return VideoTaskResult{Progress: progress, Done: true, Err: fmt.Errorf("任务失败: %s", task.FailureReason)}
+			return VideoTaskResult{Progress: progress, Done: true, Err: &TaskError{TaskID: taskID, Reason: task.FailureReason}}
 		}
 
 		return VideoTaskResult{Progress: progress}
@@ -470,7 +460,7 @@ func (c *Client) GetCreditBalance(ctx context.Context, accessToken string) (Cred
 	headers := c.baseHeaders(accessToken)
 	headers["Accept"] = "application/json"
 
-	body, err := c.doGet(ctx, soraBaseURL+"/nf/check", headers)
+	body, err := c.doGet(ctx, c.soraBaseURL+"/nf/check", headers)
 	if err != nil {
 		return CreditBalance{}, fmt.Errorf("获取配额信息失败: %w", err)
 	}
@@ -545,7 +535,7 @@ func (c *Client) GetUserInfo(ctx context.Context, accessToken string) (UserInfo,
 	headers := c.baseHeaders(accessToken)
 	headers["Accept"] = "application/json"
 
-	body, err := c.doGet(ctx, soraBaseURL+"/me", headers)
+	body, err := c.doGet(ctx, c.soraBaseURL+"/me", headers)
 	if err != nil {
 		return UserInfo{}, fmt.Errorf("获取用户信息失败: %w", err)
 	}
@@ -570,7 +560,7 @@ func (c *Client) GetSubscriptionInfo(ctx context.Context, accessToken string) (S
 	headers := c.baseHeaders(accessToken)
 	headers["Accept"] = "application/json"
 
-	body, err := c.doGet(ctx, soraBaseURL+"/billing/subscriptions", headers)
+	body, err := c.doGet(ctx, c.soraBaseURL+"/billing/subscriptions", headers)
 	if err != nil {
 		return SubscriptionInfo{}, fmt.Errorf("获取订阅信息失败: %w", err)
 	}
@@ -2,9 +2,52 @@ package sora
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 )
 
+// Encoding 视频的一种编码（清晰度/格式）
+type Encoding struct {
+	Path string `json:"path"`           // 文件链接（带签名，会过期）
+	Size int64  `json:"size,omitempty"` // 文件大小（字节），上游未提供时为 0
+}
+
+// Encodings 视频的全部编码，上游未提供的编码 Path 为空
+type Encodings struct {
+	Source    Encoding `json:"source"`    // 原始清晰度（无水印）
+	SourceWM  Encoding `json:"source_wm"` // 原始清晰度（带水印）
+	MD        Encoding `json:"md"`        // 中等清晰度
+	LD        Encoding `json:"ld"`        // 低清晰度
+	Thumbnail Encoding `json:"thumbnail"` // 封面缩略图
+	GIF       Encoding `json:"gif"`       // GIF 预览
+}
+
+// Attachment 帖子附件（一个视频生成结果）
+type Attachment struct {
+	ID           string    `json:"id"`
+	Kind         string    `json:"kind"`
+	GenerationID string    `json:"generation_id"`
+	TaskID       string    `json:"task_id"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	NFrames      int       `json:"n_frames"`
+	Duration     float64   `json:"duration_s"` // 时长（秒）
+	Encodings    Encodings `json:"encodings"`
+}
+
+// Post 已发布的帖子
+type Post struct {
+	ID          string       `json:"id"`
+	Text        string       `json:"text"`
+	Permalink   string       `json:"permalink"`
+	PostedAt    float64      `json:"posted_at"` // 发布时间（Unix 秒）
+	Attachments []Attachment `json:"attachments"`
+}
+
+type postResp struct {
+	Post *Post `json:"post"`
+}
+
 // PublishVideo 发布视频帖子，返回 postID
 // generationID 为视频的生成 ID（格式如 gen_xxx）
 func (c *Client) PublishVideo(ctx context.Context, accessToken, sentinelToken, generationID string) (string, error) {
@@ -20,7 +63,7 @@ func (c *Client) PublishVideo(ctx context.Context, accessToken, sentinelToken, g
 		"post_text": "",
 	}
 
-	resp, err := c.doPost(ctx, soraBaseURL+"/project_y/post", headers, payload)
+	resp, err := c.doSubmit(ctx, c.soraBaseURL+"/project_y/post", headers, payload)
 	if err != nil {
 		return "", fmt.Errorf("发布视频失败: %w", err)
 	}
@@ -38,7 +81,36 @@ func (c *Client) PublishVideo(ctx context.Context, accessToken, sentinelToken, g
 	return postID, nil
 }
 
+// GetPost 获取帖子详情（含附件的全部编码、尺寸和时长）
+// postID 为 Sora 分享链接中的视频 ID（s_xxx），也可以传入完整链接（自动提取 ID）
+func (c *Client) GetPost(ctx context.Context, accessToken, postID string) (*Post, error) {
+	if extracted := ExtractVideoID(postID); extracted != "" {
+		postID = extracted
+	}
+
+	headers := map[string]string{
+		"Authorization":    "Bearer " + accessToken,
+		"User-Agent":       mobileUserAgents[c.randIntn(len(mobileUserAgents))],
+		"Accept":           "application/json",
+		"oai-package-name": "com.openai.sora",
+	}
+
+	body, err := c.doGet(ctx, c.soraBaseURL+"/project_y/post/"+postID, headers)
+	if err != nil {
+		return nil, fmt.Errorf("获取视频信息失败: %w", err)
+	}
+
+	var result postResp
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+	if result.Post == nil {
+		return nil, fmt.Errorf("响应中无 post 字段: %s", truncate(string(body), 200))
+	}
+	return result.Post, nil
+}
+
 // DeletePost 删除已发布的帖子
 func (c *Client) DeletePost(ctx context.Context, accessToken, postID string) error {
-	return c.doDelete(ctx, soraBaseURL+"/project_y/post/"+postID, c.baseHeaders(accessToken))
+	return c.doDelete(ctx, c.soraBaseURL+"/project_y/post/"+postID, c.baseHeaders(accessToken))
 }
@@ -1,11 +1,14 @@
 package sora
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/crypto/sha3"
@@ -14,32 +17,6 @@ import (
 const maxIteration = 500000
 
 var (
-	cores   = []int{8, 16, 24, 32}
-	screens = []int{3000, 4000, 3120, 4160}
-
-	scripts = []string{
-		"https://cdn.oaistatic.com/_next/static/cXh69klOLzS0Gy2joLDRS/_ssgManifest.js?dpl=453ebaec0d44c2decab71692e1bfe39be35a24b3",
-	}
-	dpl = []string{
-		"prod-f501fe933b3edf57aea882da888e1a544df99840",
-	}
-
-	// 注意: 分隔符是 U+2212 (MINUS SIGN)，不是 U+002D (HYPHEN-MINUS)
-	navigatorKeys = []string{
-		"registerProtocolHandler\u2212function registerProtocolHandler() { [native code] }",
-		"storage\u2212[object StorageManager]",
-		"locks\u2212[object LockManager]",
-		"appCodeName\u2212Mozilla",
-		"permissions\u2212[object Permissions]",
-		"webdriver\u2212false",
-		"vendor\u2212Google Inc.",
-		"mediaDevices\u2212[object MediaDevices]",
-		"cookieEnabled\u2212true",
-		"product\u2212Gecko",
-		"productSub\u221220030107",
-		"hardwareConcurrency\u221232",
-		"onLine\u2212true",
-	}
 	documentKeys = []string{"_reactListeningo743lnnpvdg", "location"}
 	windowKeys   = []string{
 		"0", "window", "self", "document", "name", "location",
@@ -49,36 +26,30 @@ var (
 	}
 )
 
-// getParseTime 生成 EST 时区时间字符串
-func getParseTime() string {
-	loc := time.FixedZone("EST", -5*3600)
-	now := time.Now().In(loc)
-	return now.Format("Mon Jan 02 2006 15:04:05") + " GMT-0500 (Eastern Standard Time)"
-}
-
-// getConfig 构造 18 元素的浏览器指纹数组（使用 Client 实例的 rand）
+// getConfig 构造 18 元素的浏览器指纹数组（使用 Client 实例的 rand 和指纹 profile）
 func (c *Client) getConfig(userAgent string) []interface{} {
+	fp := c.fingerprint
 	perfCounter := float64(time.Now().UnixNano()%1e12) / 1e6
 	timeMs := float64(time.Now().UnixMilli())
 
 	return []interface{}{
-		screens[c.randIntn(len(screens))],
-		getParseTime(),
+		fp.Screens[c.randIntn(len(fp.Screens))],
+		fp.parseTime(time.Now()),
 		4294705152,
 		0,
 		userAgent,
-		scripts[c.randIntn(len(scripts))],
-		dpl[c.randIntn(len(dpl))],
+		fp.Scripts[c.randIntn(len(fp.Scripts))],
+		fp.DPL[c.randIntn(len(fp.DPL))],
 		"en-US",
 		"en-US,es-US,en,es",
 		0,
-		navigatorKeys[c.randIntn(len(navigatorKeys))],
+		fp.NavigatorKeys[c.randIntn(len(fp.NavigatorKeys))],
 		documentKeys[c.randIntn(len(documentKeys))],
 		windowKeys[c.randIntn(len(windowKeys))],
 		perfCounter,
 		c.generateUUID(),
 		"",
-		cores[c.randIntn(len(cores))],
+		fp.Cores[c.randIntn(len(fp.Cores))],
 		timeMs - perfCounter,
 	}
 }
@@ -88,11 +59,29 @@ func compactJSON(v interface{}) []byte {
 	return b
 }
 
-// solve 执行 PoW 计算：SHA3-512 哈希碰撞（已优化缓冲区复用）
-func solve(seed, difficulty string, configList []interface{}) (string, bool) {
+// powCheckInterval 每个 worker 每隔多少次迭代检查一次取消/完成信号
+const powCheckInterval = 1024
+
+// powResult PoW 计算结果
+type powResult struct {
+	Answer     string // 命中的 base64 答案；未命中时为错误 token
+	Solved     bool   // 是否在 maxIteration 内命中
+	Iterations int64  // 所有 worker 实际执行的迭代总数
+}
+
+// powTemplate 预先序列化的配置片段，nonce 插入在片段之间
+type powTemplate struct {
+	seed    []byte
+	diff    []byte
+	part1   []byte
+	part2   []byte
+	part3   []byte
+	maxJSON int
+	maxB64  int
+}
+
+func newPowTemplate(seed, difficulty string, configList []interface{}) *powTemplate {
 	diffBytes, _ := hex.DecodeString(difficulty)
-	diffLen := len(diffBytes)
-	seedBytes := []byte(seed)
 
 	part1JSON := compactJSON(configList[:3])
 	staticPart1 := append(part1JSON[:len(part1JSON)-1], ',')
@@ -108,19 +97,86 @@ func solve(seed, difficulty string, configList []interface{}) (string, bool) {
 	staticPart3 = append(staticPart3, ',')
 	staticPart3 = append(staticPart3, part3JSON[1:]...)
 
-	// 预分配缓冲区，循环内复用
 	maxJSONLen := len(staticPart1) + 10 + len(staticPart2) + 10 + len(staticPart3)
-	jsonBuf := make([]byte, 0, maxJSONLen)
-	b64Buf := make([]byte, base64.StdEncoding.EncodedLen(maxJSONLen))
+	return &powTemplate{
+		seed:    []byte(seed),
+		diff:    diffBytes,
+		part1:   staticPart1,
+		part2:   staticPart2,
+		part3:   staticPart3,
+		maxJSON: maxJSONLen,
+		maxB64:  base64.StdEncoding.EncodedLen(maxJSONLen),
+	}
+}
+
+// solve 执行 PoW 计算：SHA3-512 哈希碰撞
+// nonce 空间按 workers 交错切分（worker w 处理 w, w+workers, ...），
+// 每个 worker 复用哈希器与缓冲区；任一 worker 命中或 ctx 取消时全部提前退出
+func solve(ctx context.Context, seed, difficulty string, configList []interface{}, workers int) (powResult, error) {
+	if workers <= 0 {
+		workers = 1
+	}
+	tpl := newPowTemplate(seed, difficulty, configList)
+
+	var (
+		found      atomic.Bool
+		iterations atomic.Int64
+		answer     string
+		answerOnce sync.Once
+		wg         sync.WaitGroup
+	)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(start int) {
+			defer wg.Done()
+			n, hit := tpl.search(ctx, start, workers, &found)
+			iterations.Add(n)
+			if hit != "" {
+				answerOnce.Do(func() { answer = hit })
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	result := powResult{Iterations: iterations.Load()}
+	if answer != "" {
+		result.Answer, result.Solved = answer, true
+		return result, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
+	result.Answer = "wQ8Lk5FbGpA2NcR9dShT6gYjU7VxZ4D" +
+		base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf(`"%s"`, seed)))
+	return result, nil
+}
+
+// search 单个 worker 的搜索循环，返回执行的迭代次数和命中的答案（未命中为空）
+func (t *powTemplate) search(ctx context.Context, start, step int, found *atomic.Bool) (int64, string) {
+	diffLen := len(t.diff)
+	jsonBuf := make([]byte, 0, t.maxJSON)
+	b64Buf := make([]byte, t.maxB64)
+	hashBuf := make([]byte, 0, 64)
+	h := sha3.New512()
+
+	var n int64
+	for i := start; i < maxIteration; i += step {
+		if n%powCheckInterval == 0 && n > 0 {
+			if found.Load() || ctx.Err() != nil {
+				return n, ""
+			}
+		}
+		n++
 
-	for i := 0; i < maxIteration; i++ {
 		// 复用 jsonBuf，直接 AppendInt 避免中间分配
 		jsonBuf = jsonBuf[:0]
-		jsonBuf = append(jsonBuf, staticPart1...)
+		jsonBuf = append(jsonBuf, t.part1...)
 		jsonBuf = strconv.AppendInt(jsonBuf, int64(i), 10)
-		jsonBuf = append(jsonBuf, staticPart2...)
+		jsonBuf = append(jsonBuf, t.part2...)
 		jsonBuf = strconv.AppendInt(jsonBuf, int64(i>>1), 10)
-		jsonBuf = append(jsonBuf, staticPart3...)
+		jsonBuf = append(jsonBuf, t.part3...)
 
 		b64Len := base64.StdEncoding.EncodedLen(len(jsonBuf))
 		if b64Len > len(b64Buf) {
@@ -128,19 +184,19 @@ func solve(seed, difficulty string, configList []interface{}) (string, bool) {
 		}
 		base64.StdEncoding.Encode(b64Buf[:b64Len], jsonBuf)
 
-		h := sha3.New512()
-		h.Write(seedBytes)
+		h.Reset()
+		h.Write(t.seed)
 		h.Write(b64Buf[:b64Len])
-		hash := h.Sum(nil)
+		hashBuf = h.Sum(hashBuf[:0])
 
-		if bytesLessOrEqual(hash[:diffLen], diffBytes) {
-			return string(b64Buf[:b64Len]), true
+		if bytesLessOrEqual(hashBuf[:diffLen], t.diff) {
+			if found.CompareAndSwap(false, true) {
+				return n, string(b64Buf[:b64Len])
+			}
+			return n, ""
 		}
 	}
-
-	errorToken := "wQ8Lk5FbGpA2NcR9dShT6gYjU7VxZ4D" +
-		base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf(`"%s"`, seed)))
-	return errorToken, false
+	return n, ""
 }
 
 func bytesLessOrEqual(a, b []byte) bool {
@@ -160,16 +216,49 @@ func mustJSONStr(s string) string {
 	return string(b)
 }
 
+// PowStats PoW 计算累计统计
+type PowStats struct {
+	Solves     int64 // 计算次数
+	Failures   int64 // 未在迭代上限内命中的次数
+	Iterations int64 // 累计迭代次数
+}
+
+// PowStats 返回该客户端的 PoW 计算累计统计
+func (c *Client) PowStats() PowStats {
+	return PowStats{
+		Solves:     c.powSolves.Load(),
+		Failures:   c.powFailures.Load(),
+		Iterations: c.powIterations.Load(),
+	}
+}
+
+// solvePow 使用客户端配置的并发数计算 PoW，并累计统计
+func (c *Client) solvePow(ctx context.Context, seed, difficulty string, configList []interface{}) (string, error) {
+	result, err := solve(ctx, seed, difficulty, configList, c.powWorkers)
+	c.powIterations.Add(result.Iterations)
+	if err != nil {
+		return "", err
+	}
+	c.powSolves.Add(1)
+	if !result.Solved {
+		c.powFailures.Add(1)
+	}
+	return result.Answer, nil
+}
+
 // getPowToken 生成初始 PoW token（使用 Client 实例的 rand）
-func (c *Client) getPowToken(userAgent string) string {
+func (c *Client) getPowToken(ctx context.Context, userAgent string) (string, error) {
 	configList := c.getConfig(userAgent)
 	seed := strconv.FormatFloat(c.randFloat64(), 'f', -1, 64)
-	solution, _ := solve(seed, "0fffff", configList)
-	return "gAAAAAC" + solution
+	solution, err := c.solvePow(ctx, seed, "0fffff", configList)
+	if err != nil {
+		return "", err
+	}
+	return "gAAAAAC" + solution, nil
 }
 
 // buildSentinelToken 从 sentinel/req 响应构建最终的 sentinel token
-func (c *Client) buildSentinelToken(flow, reqID, powToken string, resp map[string]interface{}, userAgent string) string {
+func (c *Client) buildSentinelToken(ctx context.Context, flow, reqID, powToken string, resp map[string]interface{}, userAgent string) (string, error) {
 	finalPowToken := powToken
 
 	if proofofwork, ok := resp["proofofwork"].(map[string]interface{}); ok {
@@ -178,7 +267,10 @@ func (c *Client) buildSentinelToken(flow, reqID, powToken string, resp map[strin
 			difficulty, _ := proofofwork["difficulty"].(string)
 			if seed != "" && difficulty != "" {
 				configList := c.getConfig(userAgent)
-				solution, _ := solve(seed, difficulty, configList)
+				solution, err := c.solvePow(ctx, seed, difficulty, configList)
+				if err != nil {
+					return "", err
+				}
 				finalPowToken = "gAAAAAB" + solution
 			}
 		}
@@ -202,5 +294,5 @@ func (c *Client) buildSentinelToken(flow, reqID, powToken string, resp map[strin
 		mustJSONStr(reqID),
 		mustJSONStr(flow),
 	)
-	return result
+	return result, nil
 }
@@ -0,0 +1,121 @@
+package sora
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+)
+
+// powUnsolvable 实际不可能命中的难度（前 4 字节全为 0），用于跑满 maxIteration
+const powUnsolvable = "00000000"
+
+func testPowConfig(t testing.TB) []interface{} {
+	t.Helper()
+	c, err := New("")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return c.getConfig("Mozilla/5.0 (soratest)")
+}
+
+func TestSolveIterations(t *testing.T) {
+	cfg := testPowConfig(t)
+
+	tests := []struct {
+		name       string
+		difficulty string
+		workers    int
+		solved     bool
+		iterations int64
+	}{
+		{"首次命中", "ff", 1, true, 1},
+		{"单 worker 未命中", powUnsolvable, 1, false, maxIteration},
+		{"多 worker 未命中", powUnsolvable, 4, false, maxIteration},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := solve(context.Background(), "0.42", tt.difficulty, cfg, tt.workers)
+			if err != nil {
+				t.Fatalf("solve: %v", err)
+			}
+			if result.Solved != tt.solved {
+				t.Errorf("Solved = %v, want %v", result.Solved, tt.solved)
+			}
+			if result.Iterations != tt.iterations {
+				t.Errorf("Iterations = %d, want %d", result.Iterations, tt.iterations)
+			}
+			if result.Answer == "" {
+				t.Error("Answer 为空")
+			}
+		})
+	}
+}
+
+func TestSolveCancel(t *testing.T) {
+	cfg := testPowConfig(t)
+
+	for _, workers := range []int{1, 4} {
+		t.Run(fmt.Sprintf("workers=%d", workers), func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			result, err := solve(ctx, "0.42", powUnsolvable, cfg, workers)
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("err = %v, want context.Canceled", err)
+			}
+			if result.Solved {
+				t.Error("取消后不应命中")
+			}
+			// 每个 worker 在第一个检查点发现取消后退出
+			if want := int64(workers * powCheckInterval); result.Iterations != want {
+				t.Errorf("Iterations = %d, want %d", result.Iterations, want)
+			}
+		})
+	}
+}
+
+func TestPowStats(t *testing.T) {
+	c, err := New("", WithPowWorkers(1))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	cfg := c.getConfig("Mozilla/5.0 (soratest)")
+	ctx := context.Background()
+
+	if _, err := c.solvePow(ctx, "0.1", "ff", cfg); err != nil {
+		t.Fatalf("solvePow: %v", err)
+	}
+	if _, err := c.solvePow(ctx, "0.2", powUnsolvable, cfg); err != nil {
+		t.Fatalf("solvePow: %v", err)
+	}
+
+	want := PowStats{Solves: 2, Failures: 1, Iterations: 1 + maxIteration}
+	if got := c.PowStats(); got != want {
+		t.Errorf("PowStats = %+v, want %+v", got, want)
+	}
+}
+
+// BenchmarkSolve 对比单 worker 与多 worker 跑满 maxIteration 的吞吐
+func BenchmarkSolve(b *testing.B) {
+	cfg := testPowConfig(b)
+
+	workerCounts := []int{1}
+	if n := runtime.NumCPU(); n > 1 {
+		workerCounts = append(workerCounts, n)
+	}
+	for _, workers := range workerCounts {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			var iterations int64
+			for i := 0; i < b.N; i++ {
+				result, err := solve(context.Background(), "0.42", powUnsolvable, cfg, workers)
+				if err != nil {
+					b.Fatalf("solve: %v", err)
+				}
+				iterations += result.Iterations
+			}
+			b.ReportMetric(float64(iterations)/b.Elapsed().Seconds(), "hashes/s")
+		})
+	}
+}
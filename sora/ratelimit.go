@@ -0,0 +1,107 @@
+package sora
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateBucketIdleTTL 令牌桶空闲超过该时长后被清理（此时桶早已回满，清理不影响限流）
+const rateBucketIdleTTL = 10 * time.Minute
+
+// RateLimiter 按 access token 分桶的令牌桶限流器，同一账号的请求速率不超过设定值
+// 可在多个 Client 间共享（WithRateLimiter），nil 或速率 <=0 时不限流
+type RateLimiter struct {
+	mu        sync.Mutex
+	rate      float64 // 每秒补充的令牌数
+	burst     int     // 桶容量
+	buckets   map[string]*rateBucket
+	lastSweep time.Time
+}
+
+// rateBucket 单个 access token 的令牌桶
+type rateBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter 创建限流器：每个 access token 每秒最多 rps 个请求，允许 burst 个突发（<1 按 1 处理）
+func NewRateLimiter(rps float64, burst int) *RateLimiter {
+	l := &RateLimiter{buckets: make(map[string]*rateBucket)}
+	l.SetLimit(rps, burst)
+	return l
+}
+
+// WithRateLimiter 使用指定的限流器（多个 Client 共享同一限流器时按账号合并计数）
+func WithRateLimiter(l *RateLimiter) Option {
+	return func(c *Client) { c.limiter = l }
+}
+
+// SetLimit 调整速率和突发容量，rps <=0 关闭限流；已有令牌桶保留当前令牌数
+func (l *RateLimiter) SetLimit(rps float64, burst int) {
+	if burst < 1 {
+		burst = 1
+	}
+	l.mu.Lock()
+	l.rate = rps
+	l.burst = burst
+	l.mu.Unlock()
+}
+
+// Wait 等待 key 对应的令牌桶取得一个令牌，key 为空或未启用限流时立即返回
+func (l *RateLimiter) Wait(ctx context.Context, key string) error {
+	if l == nil || key == "" {
+		return nil
+	}
+	for {
+		wait := l.reserve(key)
+		if wait <= 0 {
+			return nil
+		}
+		if err := sleepWithContext(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// reserve 尝试取得一个令牌，成功返回 0，否则返回需要等待的时长
+func (l *RateLimiter) reserve(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.rate <= 0 {
+		return 0
+	}
+	now := time.Now()
+	l.sweepLocked(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &rateBucket{tokens: float64(l.burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > float64(l.burst) {
+		b.tokens = float64(l.burst)
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// sweepLocked 定期清理长时间未使用的令牌桶
+func (l *RateLimiter) sweepLocked(now time.Time) {
+	if now.Sub(l.lastSweep) < rateBucketIdleTTL {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > rateBucketIdleTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
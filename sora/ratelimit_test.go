@@ -0,0 +1,104 @@
+package sora
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	tests := []struct {
+		name     string
+		rps      float64
+		burst    int
+		keys     []string
+		wantWait []bool // 每次 reserve 是否需要等待
+	}{
+		{"突发内不等待", 10, 3, []string{"a", "a", "a"}, []bool{false, false, false}},
+		{"超过突发需等待", 10, 2, []string{"a", "a", "a"}, []bool{false, false, true}},
+		{"按 key 分桶", 10, 1, []string{"a", "b", "a", "b"}, []bool{false, false, true, true}},
+		{"burst<1 按 1 处理", 10, 0, []string{"a", "a"}, []bool{false, true}},
+		{"速率为 0 不限流", 0, 1, []string{"a", "a", "a"}, []bool{false, false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewRateLimiter(tt.rps, tt.burst)
+			for i, key := range tt.keys {
+				wait := l.reserve(key)
+				if (wait > 0) != tt.wantWait[i] {
+					t.Fatalf("第 %d 次 reserve(%q) = %v, want wait=%v", i+1, key, wait, tt.wantWait[i])
+				}
+				if tt.rps > 0 && wait > time.Duration(float64(time.Second)/tt.rps) {
+					t.Errorf("等待 %v 超过一个令牌的补充时间", wait)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterRefill(t *testing.T) {
+	l := NewRateLimiter(100, 1)
+	if wait := l.reserve("a"); wait != 0 {
+		t.Fatalf("首个令牌需等待 %v", wait)
+	}
+
+	start := time.Now()
+	if err := l.Wait(context.Background(), "a"); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 5*time.Millisecond {
+		t.Errorf("桶为空时 Wait 只等待了 %v", elapsed)
+	}
+}
+
+func TestRateLimiterWaitCanceled(t *testing.T) {
+	l := NewRateLimiter(0.1, 1)
+	l.reserve("a")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx, "a"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	var nilLimiter *RateLimiter
+	if err := nilLimiter.Wait(context.Background(), "a"); err != nil {
+		t.Errorf("nil 限流器 Wait = %v", err)
+	}
+
+	l := NewRateLimiter(0.1, 1)
+	l.reserve("a")
+	if err := l.Wait(context.Background(), ""); err != nil {
+		t.Errorf("空 key Wait = %v", err)
+	}
+
+	l.SetLimit(0, 1)
+	if wait := l.reserve("a"); wait != 0 {
+		t.Errorf("关闭限流后 reserve = %v", wait)
+	}
+}
+
+func TestRateLimiterSweep(t *testing.T) {
+	l := NewRateLimiter(10, 1)
+	l.reserve("idle")
+	l.reserve("active")
+
+	now := time.Now()
+	l.mu.Lock()
+	l.buckets["idle"].last = now.Add(-2 * rateBucketIdleTTL)
+	l.lastSweep = now.Add(-2 * rateBucketIdleTTL)
+	l.sweepLocked(now)
+	_, idle := l.buckets["idle"]
+	_, active := l.buckets["active"]
+	l.mu.Unlock()
+
+	if idle {
+		t.Error("空闲令牌桶未被清理")
+	}
+	if !active {
+		t.Error("活跃令牌桶被误清理")
+	}
+}
@@ -0,0 +1,166 @@
+package sora
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	http "github.com/bogdanfinn/fhttp"
+)
+
+// RetryPolicy API 请求的重试策略
+//
+// 只有 Methods 中的请求方法会被重试；创建任务、发布帖子等提交类请求即使方法在 Methods 中，
+// 也仅在 RetrySubmissions 为 true 时重试（上游可能已受理，重试会产生重复生成）。
+// 流式上传的请求体无法重放，从不重试
+type RetryPolicy struct {
+	MaxAttempts      int                  // 最大尝试次数（含首次），<=1 不重试
+	BaseDelay        time.Duration        // 首次重试前的等待，之后按 2^n 增长并加随机抖动
+	MaxDelay         time.Duration        // 单次等待上限；上游要求的 Retry-After 超过该值时不再重试
+	Methods          []string             // 可重试的 HTTP 方法，空为 GET、HEAD
+	RetrySubmissions bool                 // 是否重试提交类请求
+	Retryable        func(err error) bool // 判断错误是否可重试，nil 使用 DefaultRetryable
+}
+
+// DefaultRetryPolicy 默认重试策略：GET/HEAD 最多尝试 3 次，退避 500ms 起、上限 10s，提交类请求不重试
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    10 * time.Second,
+	}
+}
+
+// WithRetryPolicy 设置 API 请求的重试策略，默认 DefaultRetryPolicy()；传入零值关闭重试
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) { c.retry = p }
+}
+
+// DefaultRetryable 默认的可重试判断：网络错误、429 和 5xx 网关类错误可重试
+func DefaultRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+// retryMode 请求的重试方式
+type retryMode int
+
+const (
+	retryByMethod retryMode = iota // 按 RetryPolicy.Methods 判断
+	retrySubmit                    // 提交类请求，另需 RetryPolicy.RetrySubmissions
+	retryNever                     // 请求体不可重放，从不重试
+)
+
+// maxAttempts 返回请求的最大尝试次数
+func (p RetryPolicy) maxAttempts(method string, mode retryMode) int {
+	if p.MaxAttempts <= 1 || mode == retryNever || (mode == retrySubmit && !p.RetrySubmissions) {
+		return 1
+	}
+	methods := p.Methods
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet, http.MethodHead}
+	}
+	for _, m := range methods {
+		if strings.EqualFold(m, method) {
+			return p.MaxAttempts
+		}
+	}
+	return 1
+}
+
+// delay 返回第 attempt 次失败后的等待时间，错误不可重试或 Retry-After 超过上限时返回 false
+// jitter 为 [0, 1) 的随机数，实际等待在 [d/2, d) 之间
+func (p RetryPolicy) delay(err error, attempt int, jitter float64) (time.Duration, bool) {
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = DefaultRetryable
+	}
+	if !retryable(err) {
+		return 0, false
+	}
+
+	maxDelay := p.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = DefaultRetryPolicy().MaxDelay
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.ResetsIn() > 0 {
+		wait := apiErr.ResetsIn()
+		return wait, wait <= maxDelay
+	}
+
+	d := backoff(p.BaseDelay, attempt-1, maxDelay)
+	return d/2 + time.Duration(jitter*float64(d/2)), true
+}
+
+// do 发送请求：按 access token 限流，失败时按重试策略重试
+// newReq 每次尝试都重新构造请求（请求体需可重放）；返回的非 2xx 响应由调用方处理
+func (c *Client) do(ctx context.Context, mode retryMode, newReq func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		req, err := newReq()
+		if err != nil {
+			return nil, err
+		}
+		if err := c.limiter.Wait(ctx, bearerToken(req)); err != nil {
+			return nil, err
+		}
+
+		done := c.observe(ctx, requestInfo(ctx, req.Method, req.URL.Path, bearerToken(req), attempt))
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			done(0, err)
+			err = fmt.Errorf("请求失败: %w", err)
+		} else {
+			done(resp.StatusCode, nil)
+		}
+		if attempt >= c.retry.maxAttempts(req.Method, mode) || ctx.Err() != nil {
+			return resp, err
+		}
+
+		cause := err
+		if err == nil {
+			if resp.StatusCode < 400 {
+				return resp, nil
+			}
+			// 读出响应体以判断是否重试，放弃重试时原样交给调用方
+			buf, readErr := readAll(resp.Body)
+			closeBody(resp)
+			if readErr != nil {
+				return nil, readErr
+			}
+			resp.Body = io.NopCloser(bytes.NewReader(buf))
+			cause = newAPIError(resp, buf)
+		}
+
+		wait, ok := c.retry.delay(cause, attempt, c.randFloat64())
+		if !ok {
+			return resp, err
+		}
+		c.log().Warn("请求失败，稍后重试", "method", req.Method, "path", pathTemplate(req.URL.Path),
+			"err", cause, "wait", wait.Round(time.Millisecond), "attempt", attempt)
+		if err := sleepWithContext(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// bearerToken 从 Authorization 头取出 access token，没有时返回空字符串
+func bearerToken(req *http.Request) string {
+	token, _ := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	return token
+}
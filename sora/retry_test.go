@@ -0,0 +1,112 @@
+package sora
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	http "github.com/bogdanfinn/fhttp"
+)
+
+func TestRetryPolicyMaxAttempts(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy RetryPolicy
+		method string
+		mode   retryMode
+		want   int
+	}{
+		{"默认 GET", DefaultRetryPolicy(), http.MethodGet, retryByMethod, 3},
+		{"默认 HEAD", DefaultRetryPolicy(), http.MethodHead, retryByMethod, 3},
+		{"默认 POST", DefaultRetryPolicy(), http.MethodPost, retryByMethod, 1},
+		{"自定义方法", RetryPolicy{MaxAttempts: 4, Methods: []string{"post"}}, http.MethodPost, retryByMethod, 4},
+		{"提交类默认不重试", RetryPolicy{MaxAttempts: 4, Methods: []string{"POST"}}, http.MethodPost, retrySubmit, 1},
+		{"提交类显式开启", RetryPolicy{MaxAttempts: 4, Methods: []string{"POST"}, RetrySubmissions: true}, http.MethodPost, retrySubmit, 4},
+		{"流式上传从不重试", RetryPolicy{MaxAttempts: 4, Methods: []string{"POST"}, RetrySubmissions: true}, http.MethodPost, retryNever, 1},
+		{"零值关闭重试", RetryPolicy{}, http.MethodGet, retryByMethod, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.maxAttempts(tt.method, tt.mode); got != tt.want {
+				t.Errorf("maxAttempts = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	unavailable := &APIError{StatusCode: http.StatusServiceUnavailable}
+
+	tests := []struct {
+		name    string
+		err     error
+		attempt int
+		jitter  float64
+		want    time.Duration
+		ok      bool
+	}{
+		{"首次重试无抖动", unavailable, 1, 0, 50 * time.Millisecond, true},
+		{"首次重试半抖动", unavailable, 1, 0.5, 75 * time.Millisecond, true},
+		{"指数增长", unavailable, 3, 0, 200 * time.Millisecond, true},
+		{"不超过上限", unavailable, 10, 0.999, time.Second, true},
+		{"网络错误可重试", errors.New("connection reset"), 1, 0, 50 * time.Millisecond, true},
+		{"Retry-After 优先", &APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 800 * time.Millisecond}, 1, 0, 800 * time.Millisecond, true},
+		{"access_resets_in_seconds 优先于 Retry-After", &APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 5 * time.Second, ResetsInSec: 1}, 1, 0, time.Second, true},
+		{"Retry-After 超过上限放弃", &APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 30 * time.Second}, 1, 0, 30 * time.Second, false},
+		{"400 不重试", &APIError{StatusCode: http.StatusBadRequest}, 1, 0, 0, false},
+		{"401 不重试", &APIError{StatusCode: http.StatusUnauthorized}, 1, 0, 0, false},
+		{"ctx 取消不重试", fmt.Errorf("请求失败: %w", context.Canceled), 1, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := p.delay(tt.err, tt.attempt, tt.jitter)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && got.Round(time.Millisecond) != tt.want {
+				t.Errorf("delay = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyCustomRetryable(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 3, BaseDelay: 10 * time.Millisecond, Retryable: func(err error) bool {
+		return errors.Is(err, ErrNotFound)
+	}}
+	if _, ok := p.delay(&APIError{StatusCode: http.StatusNotFound}, 1, 0); !ok {
+		t.Error("自定义判断应重试 404")
+	}
+	if _, ok := p.delay(&APIError{StatusCode: http.StatusServiceUnavailable}, 1, 0); ok {
+		t.Error("自定义判断不应重试 503")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		min   time.Duration
+		max   time.Duration
+	}{
+		{"空", "", 0, 0},
+		{"秒数", "3", 3 * time.Second, 3 * time.Second},
+		{"带空格", " 7 ", 7 * time.Second, 7 * time.Second},
+		{"零", "0", 0, 0},
+		{"负数", "-5", 0, 0},
+		{"无效值", "soon", 0, 0},
+		{"HTTP 日期", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat), 55 * time.Second, time.Minute},
+		{"已过去的日期", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseRetryAfter(tt.value)
+			if got < tt.min || got > tt.max {
+				t.Errorf("parseRetryAfter(%q) = %v, want [%v, %v]", tt.value, got, tt.min, tt.max)
+			}
+		})
+	}
+}
@@ -0,0 +1,779 @@
+// Package soratest 提供基于 httptest 的 Sora 上游模拟服务，用于离线测试 sora.Client、
+// server/service 以及 server/handler。
+//
+// 用法：
+//
+//	srv := soratest.NewServer()
+//	defer srv.Close()
+//	client, _ := sora.New("", srv.ClientOptions()...)
+//
+// 每个新建任务按顺序消费 EnqueueLifecycle 入队的生命周期脚本，未入队时使用 DefaultLifecycle。
+// 每次轮询（/nf/pending/v2 或 /v2/recent_tasks）都会让所有未结束的任务推进一次。
+package soratest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/DouDOU-start/go-sora2api/sora"
+)
+
+// 任务状态常量（与上游保持一致）
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Step 生命周期中的一个阶段
+type Step struct {
+	Status        string  // 上游状态：queued / running / succeeded / failed
+	Progress      float64 // 进度（0-1）
+	FailureReason string  // 失败原因（Status 为 failed 时返回）
+	Polls         int     // 该阶段持续的轮询次数，<=0 视为 1
+}
+
+// Lifecycle 任务生命周期脚本
+type Lifecycle struct {
+	Steps           []Step // 依次经历的阶段，最后一个阶段为终态
+	ViolationReason string // 非空时 drafts 中返回内容违规
+}
+
+// DefaultLifecycle 默认生命周期：queued → running(50%) → succeeded
+func DefaultLifecycle() Lifecycle {
+	return Lifecycle{Steps: []Step{
+		{Status: StatusQueued, Progress: 0},
+		{Status: StatusRunning, Progress: 0.5},
+		{Status: StatusSucceeded, Progress: 1},
+	}}
+}
+
+// FailedLifecycle 运行一次后失败的生命周期
+func FailedLifecycle(reason string) Lifecycle {
+	return Lifecycle{Steps: []Step{
+		{Status: StatusRunning, Progress: 0.3},
+		{Status: StatusFailed, FailureReason: reason},
+	}}
+}
+
+// ViolationLifecycle 生成成功但在 drafts 中被判定为内容违规的生命周期
+func ViolationLifecycle(reason string) Lifecycle {
+	l := DefaultLifecycle()
+	l.ViolationReason = reason
+	return l
+}
+
+// Task 模拟服务中的任务快照
+type Task struct {
+	ID            string
+	Kind          string                 // video / image / storyboard
+	GenerationID  string                 // 第一个 generation ID
+	GenerationIDs []string               // 全部 generation ID（图片任务按 n_variants 生成多个）
+	Payload       map[string]interface{} // 创建任务时的请求体
+	Status        string
+	Progress      float64
+	CreatedAt     time.Time
+}
+
+// Request 记录的请求
+type Request struct {
+	Method string
+	Path   string
+	Query  string
+	Header http.Header
+	Body   []byte
+}
+
+// JSON 将请求体解析为 map（非 JSON 请求体返回 nil）
+func (r Request) JSON() map[string]interface{} {
+	var m map[string]interface{}
+	if err := json.Unmarshal(r.Body, &m); err != nil {
+		return nil
+	}
+	return m
+}
+
+type task struct {
+	Task
+	lifecycle Lifecycle
+	step      int
+	polls     int
+}
+
+type injectedError struct {
+	method string
+	path   string
+	status int
+	body   string
+}
+
+type cameo struct {
+	id          string
+	polls       int
+	characterID string
+	username    string
+	displayName string
+	visibility  string
+	deleted     bool
+}
+
+func (c *cameo) characterJSON(s *Server) map[string]interface{} {
+	return map[string]interface{}{
+		"character_id":        c.characterID,
+		"cameo_id":            c.id,
+		"username":            c.username,
+		"display_name":        c.displayName,
+		"profile_picture_url": s.FileURL(c.id + ".webp"),
+		"visibility":          c.visibility,
+	}
+}
+
+// Server 模拟的 Sora 上游
+type Server struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	seq        int
+	tasks      []*task // 按创建顺序，最新在后
+	lifecycles []Lifecycle
+	errors     []injectedError
+	requests   []Request
+	cameos     map[string]*cameo
+
+	// 以下字段可在发起请求前直接修改
+	FileContent       []byte // /files/ 下返回的文件内容
+	CameoPolls        int    // 角色处理需要的轮询次数
+	RemainingCount    int    // /nf/check 返回的剩余次数
+	RateLimitReached  bool   // /nf/check 返回的限流标记
+	AccessResetsInSec int    // /nf/check 返回的重置时间
+	Email             string // /me 返回的邮箱
+	PlanTitle         string // /billing/subscriptions 返回的套餐名称
+}
+
+// NewServer 启动模拟服务，使用完毕后需调用 Close
+func NewServer() *Server {
+	s := &Server{
+		cameos:         make(map[string]*cameo),
+		FileContent:    []byte("soratest-media"),
+		CameoPolls:     1,
+		RemainingCount: 30,
+		Email:          "soratest@example.com",
+		PlanTitle:      "ChatGPT Plus",
+	}
+	s.Server = httptest.NewServer(s.routes())
+	return s
+}
+
+// ClientOptions 返回指向本服务的 sora.Option 列表
+func (s *Server) ClientOptions() []sora.Option {
+	return []sora.Option{
+		sora.WithSoraBaseURL(s.URL + "/backend"),
+		sora.WithChatGPTBaseURL(s.URL),
+		sora.WithAuthBaseURL(s.URL),
+	}
+}
+
+// NewClient 创建指向本服务的 sora.Client
+func (s *Server) NewClient() (*sora.Client, error) {
+	return sora.New("", s.ClientOptions()...)
+}
+
+// AddCharacter 直接添加一个已定稿的私密角色（模拟账号上已有、并非通过 API 创建的角色），返回 character ID
+func (s *Server) AddCharacter(username, displayName string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := &cameo{
+		id:          s.nextIDLocked("cameo"),
+		username:    username,
+		displayName: displayName,
+		visibility:  "private",
+	}
+	c.characterID = s.nextIDLocked("ch")
+	s.cameos[c.id] = c
+	return c.characterID
+}
+
+// EnqueueLifecycle 为接下来创建的任务依次指定生命周期
+func (s *Server) EnqueueLifecycle(ls ...Lifecycle) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lifecycles = append(s.lifecycles, ls...)
+}
+
+// InjectError 让下一次匹配 method + path 的请求返回指定错误（仅生效一次）
+// path 为不含查询参数的完整路径，如 "/backend/nf/create"
+func (s *Server) InjectError(method, path string, status int, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors = append(s.errors, injectedError{method: method, path: path, status: status, body: body})
+}
+
+// Tasks 返回当前所有任务的快照（按创建顺序）
+func (s *Server) Tasks() []Task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Task, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		out = append(out, t.Task)
+	}
+	return out
+}
+
+// Requests 返回已收到的请求（按到达顺序）
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// RequestsTo 返回指定路径收到的请求
+func (s *Server) RequestsTo(method, path string) []Request {
+	var out []Request
+	for _, r := range s.Requests() {
+		if r.Method == method && r.Path == path {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// FileURL 返回本服务上某个文件的下载地址
+func (s *Server) FileURL(name string) string {
+	return s.URL + "/files/" + name
+}
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /backend-api/sentinel/req", s.handleSentinel)
+	mux.HandleFunc("POST /oauth/token", s.handleToken)
+
+	mux.HandleFunc("POST /backend/uploads", s.handleUpload)
+	mux.HandleFunc("POST /backend/nf/create", s.handleCreate("video"))
+	mux.HandleFunc("POST /backend/nf/create/storyboard", s.handleCreate("storyboard"))
+	mux.HandleFunc("POST /backend/video_gen", s.handleCreate("image"))
+	mux.HandleFunc("GET /backend/nf/pending/v2", s.handlePending)
+	mux.HandleFunc("GET /backend/v2/recent_tasks", s.handleRecentTasks)
+	mux.HandleFunc("GET /backend/project_y/profile/drafts", s.handleDrafts)
+	mux.HandleFunc("POST /backend/editor/enhance_prompt", s.handleEnhancePrompt)
+
+	mux.HandleFunc("GET /backend/nf/check", s.handleCheck)
+	mux.HandleFunc("GET /backend/me", s.handleMe)
+	mux.HandleFunc("GET /backend/billing/subscriptions", s.handleSubscriptions)
+
+	mux.HandleFunc("POST /backend/characters/upload", s.handleCharacterUpload)
+	mux.HandleFunc("GET /backend/project_y/cameos/in_progress/{id}", s.handleCameoStatus)
+	mux.HandleFunc("POST /backend/project_y/file/upload", s.handleFileUpload)
+	mux.HandleFunc("POST /backend/characters/finalize", s.handleFinalize)
+	mux.HandleFunc("POST /backend/project_y/cameos/by_id/{id}/update_v2", s.handleCameoUpdate)
+	mux.HandleFunc("GET /backend/project_y/characters", s.handleListCharacters)
+	mux.HandleFunc("GET /backend/project_y/characters/{id}", s.handleGetCharacter)
+	mux.HandleFunc("DELETE /backend/project_y/characters/{id}", s.handleDeleteCharacter)
+
+	mux.HandleFunc("POST /backend/project_y/post", s.handlePublish)
+	mux.HandleFunc("GET /backend/project_y/post/{id}", s.handleGetPost)
+	mux.HandleFunc("DELETE /backend/project_y/post/{id}", s.handleNoContent)
+
+	mux.HandleFunc("GET /files/{name}", s.handleFile)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_ = r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		s.mu.Lock()
+		s.requests = append(s.requests, Request{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Query:  r.URL.RawQuery,
+			Header: r.Header.Clone(),
+			Body:   body,
+		})
+		for i, e := range s.errors {
+			if e.method == r.Method && e.path == r.URL.Path {
+				s.errors = append(s.errors[:i], s.errors[i+1:]...)
+				s.mu.Unlock()
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(e.status)
+				_, _ = io.WriteString(w, e.body)
+				return
+			}
+		}
+		s.mu.Unlock()
+
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// nextIDLocked 生成带前缀的自增 ID（调用方需持有锁）
+func (s *Server) nextIDLocked(prefix string) string {
+	s.seq++
+	return fmt.Sprintf("%s_%08x", prefix, s.seq)
+}
+
+// advanceLocked 所有未结束的任务推进一次轮询（调用方需持有锁）
+func (s *Server) advanceLocked() {
+	for _, t := range s.tasks {
+		if t.step >= len(t.lifecycle.Steps)-1 {
+			continue
+		}
+		t.polls++
+		polls := t.lifecycle.Steps[t.step].Polls
+		if polls <= 0 {
+			polls = 1
+		}
+		if t.polls >= polls {
+			t.step++
+			t.polls = 0
+			t.applyStep()
+		}
+	}
+}
+
+func (t *task) applyStep() {
+	step := t.lifecycle.Steps[t.step]
+	t.Status = step.Status
+	t.Progress = step.Progress
+}
+
+func (t *task) failureReason() string {
+	return t.lifecycle.Steps[t.step].FailureReason
+}
+
+// encodings 返回视频的全部编码链接（均指向 /files/ 下的模拟文件）
+func (s *Server) encodings(id string) map[string]interface{} {
+	return map[string]interface{}{
+		"source":    map[string]interface{}{"path": s.FileURL(id + ".mp4")},
+		"md":        map[string]interface{}{"path": s.FileURL(id + "_md.mp4")},
+		"ld":        map[string]interface{}{"path": s.FileURL(id + "_ld.mp4")},
+		"thumbnail": map[string]interface{}{"path": s.FileURL(id + "_thumb.webp")},
+		"gif":       map[string]interface{}{"path": s.FileURL(id + ".gif")},
+	}
+}
+
+// paginate 按 limit 和游标参数分页，游标为上一页最后一项的 idKey 值
+// 返回当前页和下一页游标（没有更多时为空）
+func paginate(r *http.Request, items []map[string]interface{}, idKey, cursorParam string) ([]map[string]interface{}, string) {
+	if cursor := r.URL.Query().Get(cursorParam); cursor != "" {
+		rest := []map[string]interface{}{}
+		for i, item := range items {
+			if item[idKey] == cursor {
+				rest = items[i+1:]
+				break
+			}
+		}
+		items = rest
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit >= len(items) {
+		return items, ""
+	}
+	return items[:limit], items[limit-1][idKey].(string)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (s *Server) handleSentinel(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"token":       "soratest-sentinel",
+		"proofofwork": map[string]interface{}{"required": false},
+		"turnstile":   map[string]interface{}{"dx": ""},
+	})
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	at := s.nextIDLocked("at")
+	rt := s.nextIDLocked("rt")
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token":  at,
+		"refresh_token": rt,
+		"expires_in":    3600,
+	})
+}
+
+func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	id := s.nextIDLocked("media")
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{"id": id})
+}
+
+func (s *Server) handleCreate(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"error": map[string]interface{}{"message": "invalid json"},
+			})
+			return
+		}
+
+		s.mu.Lock()
+		lc := DefaultLifecycle()
+		if len(s.lifecycles) > 0 {
+			lc = s.lifecycles[0]
+			s.lifecycles = s.lifecycles[1:]
+		}
+		if len(lc.Steps) == 0 {
+			lc.Steps = DefaultLifecycle().Steps
+		}
+		variants := 1
+		if n, ok := payload["n_variants"].(float64); ok && n > 1 {
+			variants = int(n)
+		}
+		t := &task{
+			Task: Task{
+				ID:        s.nextIDLocked("task"),
+				Kind:      kind,
+				Payload:   payload,
+				CreatedAt: time.Now(),
+			},
+			lifecycle: lc,
+		}
+		for i := 0; i < variants; i++ {
+			t.GenerationIDs = append(t.GenerationIDs, s.nextIDLocked("gen"))
+		}
+		t.GenerationID = t.GenerationIDs[0]
+		t.applyStep()
+		s.tasks = append(s.tasks, t)
+		id := t.ID
+		s.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{"id": id})
+	}
+}
+
+func (s *Server) handlePending(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.advanceLocked()
+	items := []map[string]interface{}{}
+	for _, t := range s.tasks {
+		if t.Kind == "image" || t.Status == StatusSucceeded {
+			continue
+		}
+		items = append(items, map[string]interface{}{
+			"id":             t.ID,
+			"status":         t.Status,
+			"failure_reason": t.failureReason(),
+			"progress_pct":   t.Progress,
+		})
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (s *Server) handleRecentTasks(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.advanceLocked()
+	items := []map[string]interface{}{}
+	for i := len(s.tasks) - 1; i >= 0; i-- {
+		t := s.tasks[i]
+		generations := []map[string]interface{}{}
+		if t.Status == StatusSucceeded {
+			for _, genID := range t.GenerationIDs {
+				generations = append(generations, map[string]interface{}{
+					"id":  genID,
+					"url": s.FileURL(genID + ".png"),
+				})
+			}
+		}
+		items = append(items, map[string]interface{}{
+			"id":             t.ID,
+			"status":         t.Status,
+			"failure_reason": t.failureReason(),
+			"progress_pct":   t.Progress,
+			"generations":    generations,
+		})
+	}
+	s.mu.Unlock()
+	items, next := paginate(r, items, "id", "before")
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"task_responses": items,
+		"last_id":        next,
+		"has_more":       next != "",
+	})
+}
+
+func (s *Server) handleDrafts(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	items := []map[string]interface{}{}
+	for i := len(s.tasks) - 1; i >= 0; i-- {
+		t := s.tasks[i]
+		if t.Kind == "image" || t.Status != StatusSucceeded {
+			continue
+		}
+		item := map[string]interface{}{
+			"task_id":       t.ID,
+			"generation_id": t.GenerationID,
+			"kind":          "sora_draft",
+		}
+		if t.lifecycle.ViolationReason != "" {
+			item["kind"] = "sora_content_violation"
+			item["reason_str"] = t.lifecycle.ViolationReason
+		} else {
+			item["downloadable_url"] = s.FileURL(t.ID + ".mp4")
+			item["width"] = 1280
+			item["height"] = 720
+			item["duration_s"] = 10.0
+			item["encodings"] = s.encodings(t.ID)
+		}
+		items = append(items, item)
+	}
+	s.mu.Unlock()
+	items, next := paginate(r, items, "task_id", "cursor")
+	var cursor interface{}
+	if next != "" {
+		cursor = next
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"items": items, "cursor": cursor})
+}
+
+func (s *Server) handleEnhancePrompt(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Prompt string `json:"prompt"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&payload)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"enhanced_prompt": payload.Prompt + " (enhanced)",
+	})
+}
+
+func (s *Server) handleCheck(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	info := map[string]interface{}{
+		"estimated_num_videos_remaining": s.RemainingCount,
+		"rate_limit_reached":             s.RateLimitReached,
+		"access_resets_in_seconds":       s.AccessResetsInSec,
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{"rate_limit_and_credit_balance": info})
+}
+
+func (s *Server) handleMe(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	email := s.Email
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{"email": email, "name": "soratest"})
+}
+
+func (s *Server) handleSubscriptions(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	title := s.PlanTitle
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": []map[string]interface{}{{
+			"plan":   map[string]interface{}{"id": "chatgptplusplan", "title": title},
+			"end_ts": time.Now().Add(30 * 24 * time.Hour).Unix(),
+		}},
+	})
+}
+
+func (s *Server) handleCharacterUpload(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	c := &cameo{id: s.nextIDLocked("cameo")}
+	s.cameos[c.id] = c
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{"id": c.id})
+}
+
+func (s *Server) handleCameoStatus(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	c, ok := s.cameos[r.PathValue("id")]
+	if !ok {
+		s.mu.Unlock()
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{
+			"error": map[string]interface{}{"message": "cameo not found"},
+		})
+		return
+	}
+	c.polls++
+	done := c.polls > s.CameoPolls
+	s.mu.Unlock()
+
+	resp := map[string]interface{}{
+		"status":            "processing",
+		"display_name_hint": "Soratest",
+		"username_hint":     "soratest",
+	}
+	if done {
+		resp["status"] = "finalized"
+		resp["profile_asset_url"] = s.FileURL(c.id + ".webp")
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleFileUpload(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	id := s.nextIDLocked("file")
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{"asset_pointer": "sediment://" + id})
+}
+
+func (s *Server) handleFinalize(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		CameoID     string `json:"cameo_id"`
+		Username    string `json:"username"`
+		DisplayName string `json:"display_name"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&payload)
+
+	s.mu.Lock()
+	c, ok := s.cameos[payload.CameoID]
+	if ok && c.characterID == "" {
+		c.characterID = s.nextIDLocked("ch")
+		c.username = payload.Username
+		c.displayName = payload.DisplayName
+		c.visibility = "private"
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{
+			"error": map[string]interface{}{"message": "cameo not found"},
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"character": map[string]interface{}{"character_id": c.characterID},
+	})
+}
+
+func (s *Server) handlePublish(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.seq++
+	id := fmt.Sprintf("s_%032x", s.seq)
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{"post": map[string]interface{}{"id": id}})
+}
+
+func (s *Server) handleGetPost(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"post": map[string]interface{}{
+			"id":        id,
+			"text":      "soratest post",
+			"permalink": "https://sora.chatgpt.com/p/" + id,
+			"attachments": []map[string]interface{}{{
+				"kind":       "sora",
+				"width":      1280,
+				"height":     720,
+				"duration_s": 10.0,
+				"encodings":  s.encodings(id),
+			}},
+		},
+	})
+}
+
+func (s *Server) handleFile(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	content := s.FileContent
+	s.mu.Unlock()
+	http.ServeContent(w, r, r.PathValue("name"), time.Time{}, bytes.NewReader(content))
+}
+
+func (s *Server) handleCameoUpdate(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Visibility  string `json:"visibility"`
+		Username    string `json:"username"`
+		DisplayName string `json:"display_name"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&payload)
+
+	s.mu.Lock()
+	c, ok := s.cameos[r.PathValue("id")]
+	if ok {
+		if payload.Visibility != "" {
+			c.visibility = payload.Visibility
+		}
+		if payload.Username != "" {
+			c.username = payload.Username
+		}
+		if payload.DisplayName != "" {
+			c.displayName = payload.DisplayName
+		}
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{
+			"error": map[string]interface{}{"message": "cameo not found"},
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Server) handleListCharacters(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	cameos := make([]*cameo, 0, len(s.cameos))
+	for _, c := range s.cameos {
+		if c.characterID != "" && !c.deleted {
+			cameos = append(cameos, c)
+		}
+	}
+	sort.Slice(cameos, func(i, j int) bool { return cameos[i].id > cameos[j].id })
+	items := make([]map[string]interface{}, 0, len(cameos))
+	for _, c := range cameos {
+		items = append(items, c.characterJSON(s))
+	}
+	s.mu.Unlock()
+	items, next := paginate(r, items, "character_id", "cursor")
+	var cursor interface{}
+	if next != "" {
+		cursor = next
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"items": items, "cursor": cursor})
+}
+
+func (s *Server) handleGetCharacter(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	c := s.findCharacterLocked(r.PathValue("id"))
+	var item map[string]interface{}
+	if c != nil {
+		item = c.characterJSON(s)
+	}
+	s.mu.Unlock()
+	if item == nil {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{
+			"error": map[string]interface{}{"message": "character not found"},
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, item)
+}
+
+func (s *Server) handleDeleteCharacter(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	if c := s.findCharacterLocked(r.PathValue("id")); c != nil {
+		c.deleted = true
+	}
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// findCharacterLocked 按 character ID 查找未删除的角色
+func (s *Server) findCharacterLocked(characterID string) *cameo {
+	for _, c := range s.cameos {
+		if c.characterID == characterID && !c.deleted {
+			return c
+		}
+	}
+	return nil
+}
+
+func (s *Server) handleNoContent(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
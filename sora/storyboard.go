@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -15,32 +16,64 @@ func IsStoryboardPrompt(prompt string) bool {
 	return len(matches) >= 1
 }
 
-// FormatStoryboardPrompt 将分镜格式的提示词转换为 API 所需格式
+// StoryboardShot 分镜中的单个镜头
+type StoryboardShot struct {
+	Duration float64 // 镜头时长（秒）
+	Scene    string  // 场景描述
+
+	durationText string // 解析自提示词的原始时长文本，格式化时原样使用
+}
+
+// ParseStoryboardPrompt 解析分镜格式的提示词，返回镜头列表和非分镜部分（整体说明）
 // 输入: "总体描述\n[5.0s]场景1 [5.0s]场景2"
-// 输出: "current timeline:\nShot 1:\nduration: 5.0sec\nScene: 场景1\n..."
-func FormatStoryboardPrompt(prompt string) string {
+func ParseStoryboardPrompt(prompt string) (shots []StoryboardShot, instructions string) {
 	re := regexp.MustCompile(`\[(\d+(?:\.\d+)?)s\]([^[\]]+)`)
 	matches := re.FindAllStringSubmatch(prompt, -1)
 	if len(matches) == 0 {
-		return prompt
+		return nil, prompt
+	}
+
+	for _, match := range matches {
+		duration, _ := strconv.ParseFloat(match[1], 64)
+		shots = append(shots, StoryboardShot{
+			Duration:     duration,
+			Scene:        strings.TrimSpace(match[2]),
+			durationText: match[1],
+		})
 	}
 
 	// 提取非分镜部分作为 instructions
-	instructions := re.ReplaceAllString(prompt, "")
-	instructions = strings.TrimSpace(instructions)
+	instructions = strings.TrimSpace(re.ReplaceAllString(prompt, ""))
+	return shots, instructions
+}
+
+// FormatStoryboardPrompt 将分镜格式的提示词转换为 API 所需格式
+// 输入: "总体描述\n[5.0s]场景1 [5.0s]场景2"
+// 输出: "current timeline:\nShot 1:\nduration: 5.0sec\nScene: 场景1\n..."
+func FormatStoryboardPrompt(prompt string) string {
+	shots, instructions := ParseStoryboardPrompt(prompt)
+	if len(shots) == 0 {
+		return prompt
+	}
+	return formatStoryboardShots(shots, instructions)
+}
 
+// formatStoryboardShots 将镜头列表和整体说明格式化为 API 所需的 timeline 文本
+func formatStoryboardShots(shots []StoryboardShot, instructions string) string {
 	var b strings.Builder
 	b.WriteString("current timeline:\n")
 
-	for i, match := range matches {
-		duration := match[1]
-		scene := strings.TrimSpace(match[2])
+	for i, shot := range shots {
 		fmt.Fprintf(&b, "Shot %d:\n", i+1)
+		duration := shot.durationText
+		if duration == "" {
+			duration = strconv.FormatFloat(shot.Duration, 'f', -1, 64)
+		}
 		fmt.Fprintf(&b, "duration: %ssec\n", duration)
-		fmt.Fprintf(&b, "Scene: %s\n\n", scene)
+		fmt.Fprintf(&b, "Scene: %s\n\n", shot.Scene)
 	}
 
-	if instructions != "" {
+	if instructions = strings.TrimSpace(instructions); instructions != "" {
 		b.WriteString("instructions:\n")
 		b.WriteString(instructions)
 	}
@@ -49,52 +82,16 @@ func FormatStoryboardPrompt(prompt string) string {
 }
 
 // CreateStoryboardTask 创建分镜视频任务
-// prompt 应为分镜格式（会自动调用 FormatStoryboardPrompt 转换）
+// prompt 应为分镜格式（会自动解析为镜头列表），如需指定模型/尺寸请使用 CreateVideo
 func (c *Client) CreateStoryboardTask(ctx context.Context, accessToken, sentinelToken, prompt, orientation string, nFrames int, mediaID, styleID string) (string, error) {
-	headers := c.sentinelHeaders(accessToken, sentinelToken)
-
-	// 格式化分镜提示词
-	formattedPrompt := FormatStoryboardPrompt(prompt)
-
-	inpaintItems := []interface{}{}
-	if mediaID != "" {
-		inpaintItems = []interface{}{
-			map[string]interface{}{
-				"kind":      "upload",
-				"upload_id": mediaID,
-			},
-		}
-	}
-
-	payload := map[string]interface{}{
-		"kind":               "video",
-		"prompt":             formattedPrompt,
-		"title":              "Draft your video",
-		"orientation":        orientation,
-		"size":               "small",
-		"n_frames":           nFrames,
-		"storyboard_id":      nil,
-		"inpaint_items":      inpaintItems,
-		"remix_target_id":    nil,
-		"model":              "sy_8",
-		"metadata":           nil,
-		"style_id":           nilIfEmpty(styleID),
-		"cameo_ids":          nil,
-		"cameo_replacements": nil,
-		"audio_caption":      nil,
-		"audio_transcript":   nil,
-		"video_caption":      nil,
-	}
-
-	resp, err := c.doPost(ctx, soraBaseURL+"/nf/create/storyboard", headers, payload)
-	if err != nil {
-		return "", fmt.Errorf("创建分镜任务失败: %w", err)
-	}
-
-	taskID, ok := resp["id"].(string)
-	if !ok || taskID == "" {
-		return "", fmt.Errorf("响应中无 task_id: %v", resp)
-	}
-
-	return taskID, nil
+	shots, instructions := ParseStoryboardPrompt(prompt)
+	return c.CreateVideo(ctx, accessToken, sentinelToken, VideoRequest{
+		Prompt:      instructions,
+		Orientation: orientation,
+		NFrames:     nFrames,
+		StyleID:     styleID,
+		MediaIDs:    []string{mediaID},
+		Storyboard:  true,
+		Shots:       shots,
+	})
 }
@@ -0,0 +1,44 @@
+package sora
+
+import "testing"
+
+func TestFormatStoryboardPrompt(t *testing.T) {
+	tests := []struct {
+		name   string
+		prompt string
+		want   string
+	}{
+		{
+			name:   "非分镜原样返回",
+			prompt: "a cat",
+			want:   "a cat",
+		},
+		{
+			name:   "整数与两位小数时长原样保留",
+			prompt: "整体说明\n[5s]场景1 [5.25s]场景2 [5.0s]场景3",
+			want: "current timeline:\n" +
+				"Shot 1:\nduration: 5sec\nScene: 场景1\n\n" +
+				"Shot 2:\nduration: 5.25sec\nScene: 场景2\n\n" +
+				"Shot 3:\nduration: 5.0sec\nScene: 场景3\n\n" +
+				"instructions:\n整体说明",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatStoryboardPrompt(tt.prompt); got != tt.want {
+				t.Errorf("FormatStoryboardPrompt =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFormatStoryboardShots 代码构造的镜头按最短形式格式化时长，不做舍入
+func TestFormatStoryboardShots(t *testing.T) {
+	shots := []StoryboardShot{{Duration: 5, Scene: "a"}, {Duration: 2.25, Scene: "b"}}
+	want := "current timeline:\n" +
+		"Shot 1:\nduration: 5sec\nScene: a\n\n" +
+		"Shot 2:\nduration: 2.25sec\nScene: b\n\n"
+	if got := formatStoryboardShots(shots, " "); got != want {
+		t.Errorf("formatStoryboardShots =\n%q\nwant\n%q", got, want)
+	}
+}
@@ -3,64 +3,13 @@ package sora
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"mime/multipart"
-	"net/textproto"
-	"path/filepath"
-	"strings"
 )
 
 // UploadImage 上传图片，返回 mediaID，用于图生图/图生视频
-// imageData 为图片二进制数据，filename 为文件名（如 "image.png"）
+// imageData 为图片二进制数据，filename 为文件名（如 "image.png"），大文件建议使用 UploadImageReader
 func (c *Client) UploadImage(ctx context.Context, accessToken string, imageData []byte, filename string) (string, error) {
-	headers := c.baseHeaders(accessToken)
-
-	// 检测 MIME 类型
-	mimeType := "image/png"
-	ext := strings.ToLower(filepath.Ext(filename))
-	switch ext {
-	case ".jpg", ".jpeg":
-		mimeType = "image/jpeg"
-	case ".webp":
-		mimeType = "image/webp"
-	}
-
-	// 构造 multipart body
-	var buf bytes.Buffer
-	writer := multipart.NewWriter(&buf)
-
-	// 添加文件部分
-	partHeader := make(textproto.MIMEHeader)
-	partHeader.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, filename))
-	partHeader.Set("Content-Type", mimeType)
-	part, err := writer.CreatePart(partHeader)
-	if err != nil {
-		return "", err
-	}
-	if _, err := part.Write(imageData); err != nil {
-		return "", err
-	}
-
-	// 添加 file_name 字段
-	if err := writer.WriteField("file_name", filename); err != nil {
-		return "", err
-	}
-	if err := writer.Close(); err != nil {
-		return "", err
-	}
-
-	resp, err := c.doPostMultipart(ctx, soraBaseURL+"/uploads", headers, &buf, writer.FormDataContentType())
-	if err != nil {
-		return "", fmt.Errorf("上传图片失败: %w", err)
-	}
-
-	mediaID, ok := resp["id"].(string)
-	if !ok || mediaID == "" {
-		return "", fmt.Errorf("响应中无 media_id: %v", resp)
-	}
-
-	return mediaID, nil
+	return c.UploadImageReader(ctx, accessToken, bytes.NewReader(imageData), filename)
 }
 
 // CreateVideoTask 创建视频生成任务（文生视频）
@@ -78,40 +27,15 @@ func (c *Client) CreateVideoTaskWithImage(ctx context.Context, accessToken, sent
 // mediaID 为空表示文生视频，非空表示图生视频
 // styleID 为空表示无风格，可选值见 ValidStyles
 func (c *Client) CreateVideoTaskWithOptions(ctx context.Context, accessToken, sentinelToken, prompt, orientation string, nFrames int, model, size, mediaID, styleID string) (string, error) {
-	headers := c.sentinelHeaders(accessToken, sentinelToken)
-
-	inpaintItems := []interface{}{}
-	if mediaID != "" {
-		inpaintItems = []interface{}{
-			map[string]interface{}{
-				"kind":      "upload",
-				"upload_id": mediaID,
-			},
-		}
-	}
-
-	payload := map[string]interface{}{
-		"kind":          "video",
-		"prompt":        prompt,
-		"orientation":   orientation,
-		"size":          size,
-		"n_frames":      nFrames,
-		"model":         model,
-		"inpaint_items": inpaintItems,
-		"style_id":      nilIfEmpty(styleID),
-	}
-
-	resp, err := c.doPost(ctx, soraBaseURL+"/nf/create", headers, payload)
-	if err != nil {
-		return "", fmt.Errorf("创建任务失败: %w", err)
-	}
-
-	taskID, ok := resp["id"].(string)
-	if !ok || taskID == "" {
-		return "", fmt.Errorf("响应中无 task_id: %v", resp)
-	}
-
-	return taskID, nil
+	return c.CreateVideo(ctx, accessToken, sentinelToken, VideoRequest{
+		Prompt:      prompt,
+		Orientation: orientation,
+		NFrames:     nFrames,
+		Model:       model,
+		Size:        size,
+		StyleID:     styleID,
+		MediaIDs:    []string{mediaID},
+	})
 }
 
 // CreateImageTask 创建图片生成任务（文生图）
@@ -121,76 +45,27 @@ func (c *Client) CreateImageTask(ctx context.Context, accessToken, sentinelToken
 }
 
 // CreateImageTaskWithImage 创建图生图任务
-// mediaID 为空表示文生图，非空表示图生图
+// mediaID 为空表示文生图，非空表示图生图；如需多张结果请使用 CreateImage
 func (c *Client) CreateImageTaskWithImage(ctx context.Context, accessToken, sentinelToken, prompt string, width, height int, mediaID string) (string, error) {
-	headers := c.sentinelHeaders(accessToken, sentinelToken)
-
-	operation := "simple_compose"
-	inpaintItems := []interface{}{}
-	if mediaID != "" {
-		operation = "remix"
-		inpaintItems = []interface{}{
-			map[string]interface{}{
-				"type":            "image",
-				"frame_index":     0,
-				"upload_media_id": mediaID,
-			},
-		}
-	}
-
-	payload := map[string]interface{}{
-		"type":          "image_gen",
-		"operation":     operation,
-		"prompt":        prompt,
-		"width":         width,
-		"height":        height,
-		"n_variants":    1,
-		"n_frames":      1,
-		"inpaint_items": inpaintItems,
-	}
-
-	resp, err := c.doPost(ctx, soraBaseURL+"/video_gen", headers, payload)
-	if err != nil {
-		return "", fmt.Errorf("创建图片任务失败: %w", err)
-	}
-
-	taskID, ok := resp["id"].(string)
-	if !ok || taskID == "" {
-		return "", fmt.Errorf("响应中无 task_id: %v", resp)
-	}
-
-	return taskID, nil
+	return c.CreateImage(ctx, accessToken, sentinelToken, ImageRequest{
+		Prompt:  prompt,
+		Width:   width,
+		Height:  height,
+		MediaID: mediaID,
+	})
 }
 
 // RemixVideo 基于已有视频创建 Remix 任务
 // remixTargetID 为 Sora 分享链接中的视频 ID，格式: s_[hex32]
+// 如需指定模型/尺寸请使用 CreateVideo
 func (c *Client) RemixVideo(ctx context.Context, accessToken, sentinelToken, remixTargetID, prompt, orientation string, nFrames int, styleID string) (string, error) {
-	headers := c.sentinelHeaders(accessToken, sentinelToken)
-
-	payload := map[string]interface{}{
-		"kind":               "video",
-		"prompt":             prompt,
-		"inpaint_items":      []interface{}{},
-		"remix_target_id":    remixTargetID,
-		"cameo_ids":          []interface{}{},
-		"cameo_replacements": map[string]interface{}{},
-		"model":              "sy_8",
-		"orientation":        orientation,
-		"n_frames":           nFrames,
-		"style_id":           nilIfEmpty(styleID),
-	}
-
-	resp, err := c.doPost(ctx, soraBaseURL+"/nf/create", headers, payload)
-	if err != nil {
-		return "", fmt.Errorf("创建 Remix 任务失败: %w", err)
-	}
-
-	taskID, ok := resp["id"].(string)
-	if !ok || taskID == "" {
-		return "", fmt.Errorf("响应中无 task_id: %v", resp)
-	}
-
-	return taskID, nil
+	return c.CreateVideo(ctx, accessToken, sentinelToken, VideoRequest{
+		Prompt:        prompt,
+		Orientation:   orientation,
+		NFrames:       nFrames,
+		StyleID:       styleID,
+		RemixTargetID: remixTargetID,
+	})
 }
 
 // EnhancePrompt 使用 Sora 的提示词优化 API 增强提示词
@@ -205,7 +80,7 @@ func (c *Client) EnhancePrompt(ctx context.Context, accessToken, prompt, expansi
 		"duration_s":      durationSec,
 	}
 
-	resp, err := c.doPost(ctx, soraBaseURL+"/editor/enhance_prompt", headers, payload)
+	resp, err := c.doPost(ctx, c.soraBaseURL+"/editor/enhance_prompt", headers, payload)
 	if err != nil {
 		return "", fmt.Errorf("提示词优化失败: %w", err)
 	}
@@ -240,7 +115,7 @@ func (c *Client) RefreshAccessToken(ctx context.Context, refreshToken, clientID
 		"refresh_token": refreshToken,
 	}
 
-	resp, err := c.doPost(ctx, "https://auth.openai.com/oauth/token", headers, payload)
+	resp, err := c.doPost(ctx, c.authBaseURL+"/oauth/token", headers, payload)
 	if err != nil {
 		return "", "", fmt.Errorf("刷新 token 失败: %w", err)
 	}
@@ -258,57 +133,15 @@ func (c *Client) RefreshAccessToken(ctx context.Context, refreshToken, clientID
 // 需要使用 RefreshAccessToken 获取的 token，普通 ChatGPT access_token 不支持
 // videoID 为 Sora 分享链接中的视频 ID，也可以传入完整链接（自动提取 ID）
 func (c *Client) GetWatermarkFreeURL(ctx context.Context, accessToken, videoID string) (string, error) {
-	// 如果传入的是完整链接，自动提取 ID
-	if extracted := ExtractVideoID(videoID); extracted != "" {
-		videoID = extracted
-	}
-
-	userAgent := mobileUserAgents[c.randIntn(len(mobileUserAgents))]
-
-	headers := map[string]string{
-		"Authorization":    "Bearer " + accessToken,
-		"User-Agent":       userAgent,
-		"Accept":           "application/json",
-		"oai-package-name": "com.openai.sora",
-	}
-
-	body, err := c.doGet(ctx, soraBaseURL+"/project_y/post/"+videoID, headers)
+	post, err := c.GetPost(ctx, accessToken, videoID)
 	if err != nil {
-		return "", fmt.Errorf("获取视频信息失败: %w", err)
-	}
-
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return "", fmt.Errorf("解析响应失败: %w", err)
-	}
-
-	// 提取 post.attachments[0].encodings.source.path
-	post, _ := result["post"].(map[string]interface{})
-	if post == nil {
-		return "", fmt.Errorf("响应中无 post 字段: %v", result)
+		return "", err
 	}
-
-	attachments, _ := post["attachments"].([]interface{})
-	if len(attachments) == 0 {
+	if len(post.Attachments) == 0 {
 		return "", fmt.Errorf("响应中无 attachments")
 	}
 
-	attachment, _ := attachments[0].(map[string]interface{})
-	if attachment == nil {
-		return "", fmt.Errorf("无法解析 attachment")
-	}
-
-	encodings, _ := attachment["encodings"].(map[string]interface{})
-	if encodings == nil {
-		return "", fmt.Errorf("响应中无 encodings")
-	}
-
-	source, _ := encodings["source"].(map[string]interface{})
-	if source == nil {
-		return "", fmt.Errorf("响应中无 source encoding")
-	}
-
-	path, _ := source["path"].(string)
+	path := post.Attachments[0].Encodings.Source.Path
 	if path == "" {
 		return "", fmt.Errorf("响应中无下载链接")
 	}
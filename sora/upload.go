@@ -0,0 +1,205 @@
+package sora
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"path/filepath"
+	"strings"
+
+	http "github.com/bogdanfinn/fhttp"
+)
+
+// DefaultMaxUploadSize 默认单个上传文件大小上限（100 MB）
+const DefaultMaxUploadSize int64 = 100 << 20
+
+// sniffLen 嗅探内容类型读取的字节数（与 http.DetectContentType 一致）
+const sniffLen = 512
+
+// WithMaxUploadSize 设置单个上传文件大小上限（字节），<=0 表示不限制，默认 DefaultMaxUploadSize
+func WithMaxUploadSize(n int64) Option {
+	return func(c *Client) { c.maxUploadSize = n }
+}
+
+// UploadImageReader 流式上传图片，返回 mediaID，用于图生图/图生视频
+// 内容类型根据前 512 字节嗅探，非图片时报错；filename 无扩展名时按嗅探结果补全
+// 超过大小上限时返回 ErrUploadTooLarge
+func (c *Client) UploadImageReader(ctx context.Context, accessToken string, r io.Reader, filename string) (string, error) {
+	contentType, body, err := sniffContent(r)
+	if err != nil {
+		return "", fmt.Errorf("上传图片失败: %w", err)
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		return "", fmt.Errorf("上传图片失败: 不支持的文件类型 %s", contentType)
+	}
+	filename = withSniffedExt(filename, "image", contentType)
+
+	resp, err := c.postMultipartStream(ctx, c.soraBaseURL+"/uploads", c.baseHeaders(accessToken),
+		multipartFile{filename: filename, contentType: contentType, r: body},
+		[][2]string{{"file_name", filename}})
+	if err != nil {
+		return "", fmt.Errorf("上传图片失败: %w", err)
+	}
+
+	mediaID, ok := resp["id"].(string)
+	if !ok || mediaID == "" {
+		return "", fmt.Errorf("响应中无 media_id: %v", resp)
+	}
+	return mediaID, nil
+}
+
+// UploadCharacterVideoReader 流式上传角色视频，返回 cameoID，采样窗口默认 0-3 秒
+// 内容类型根据前 512 字节嗅探，无法识别时按 mp4 处理；超过大小上限时返回 ErrUploadTooLarge
+func (c *Client) UploadCharacterVideoReader(ctx context.Context, accessToken string, r io.Reader) (string, error) {
+	return c.UploadCharacterVideoWithOptions(ctx, accessToken, r, CharacterVideoOptions{})
+}
+
+// UploadCharacterVideoWithOptions 流式上传角色视频并指定采样窗口，返回 cameoID
+func (c *Client) UploadCharacterVideoWithOptions(ctx context.Context, accessToken string, r io.Reader, opts CharacterVideoOptions) (string, error) {
+	timestamps, err := opts.timestamps()
+	if err != nil {
+		return "", fmt.Errorf("上传角色视频失败: %w", err)
+	}
+
+	contentType, body, err := sniffContent(r)
+	if err != nil {
+		return "", fmt.Errorf("上传角色视频失败: %w", err)
+	}
+	switch {
+	case strings.HasPrefix(contentType, "video/"):
+	case contentType == "application/octet-stream":
+		contentType = "video/mp4"
+	default:
+		return "", fmt.Errorf("上传角色视频失败: 不支持的文件类型 %s", contentType)
+	}
+
+	resp, err := c.postMultipartStream(ctx, c.soraBaseURL+"/characters/upload", c.baseHeaders(accessToken),
+		multipartFile{filename: withSniffedExt("video", "video", contentType), contentType: contentType, r: body},
+		[][2]string{{"timestamps", timestamps}})
+	if err != nil {
+		return "", fmt.Errorf("上传角色视频失败: %w", err)
+	}
+
+	cameoID, ok := resp["id"].(string)
+	if !ok || cameoID == "" {
+		return "", fmt.Errorf("响应中无 cameo_id: %v", resp)
+	}
+	return cameoID, nil
+}
+
+// multipartFile 流式 multipart 请求中的文件部分（字段名固定为 file）
+type multipartFile struct {
+	filename    string
+	contentType string
+	r           io.Reader
+}
+
+// postMultipartStream 通过 io.Pipe 边读边发送 multipart 请求，不在内存中拼接完整请求体
+// 先写文件部分，再按顺序写普通字段；读取超过大小上限时中止并返回 ErrUploadTooLarge
+func (c *Client) postMultipartStream(ctx context.Context, url string, headers map[string]string, file multipartFile, fields [][2]string) (map[string]interface{}, error) {
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	src := &sizeLimitReader{r: file.r, limit: c.maxUploadSize}
+
+	writeDone := make(chan error, 1)
+	go func() {
+		err := writeMultipart(writer, file, src, fields)
+		pw.CloseWithError(err)
+		writeDone <- err
+	}()
+
+	resp, err := c.doPostMultipart(ctx, url, headers, pr, writer.FormDataContentType())
+	// 上游提前返回时关闭读端，确保写入协程退出
+	_ = pr.Close()
+	if writeErr := <-writeDone; writeErr != nil && !errors.Is(writeErr, io.ErrClosedPipe) {
+		return nil, writeErr
+	}
+	return resp, err
+}
+
+// writeMultipart 写入文件部分和普通字段
+func writeMultipart(writer *multipart.Writer, file multipartFile, src io.Reader, fields [][2]string) error {
+	partHeader := make(textproto.MIMEHeader)
+	partHeader.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, file.filename))
+	partHeader.Set("Content-Type", file.contentType)
+	part, err := writer.CreatePart(partHeader)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, src); err != nil {
+		return err
+	}
+	for _, f := range fields {
+		if err := writer.WriteField(f[0], f[1]); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// sniffContent 读取前 512 字节嗅探内容类型，返回不含参数的 MIME 类型和包含已读字节的完整 reader
+func sniffContent(r io.Reader) (string, io.Reader, error) {
+	br := bufio.NewReaderSize(r, sniffLen)
+	head, err := br.Peek(sniffLen)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", nil, fmt.Errorf("读取文件失败: %w", err)
+	}
+	if len(head) == 0 {
+		return "", nil, fmt.Errorf("文件内容为空")
+	}
+	contentType, _, _ := strings.Cut(http.DetectContentType(head), ";")
+	return strings.TrimSpace(contentType), br, nil
+}
+
+// withSniffedExt filename 为空时使用 fallback，无扩展名时按内容类型补全
+func withSniffedExt(filename, fallback, contentType string) string {
+	if filename == "" {
+		filename = fallback
+	}
+	if filepath.Ext(filename) != "" {
+		return filename
+	}
+	return filename + extForContentType(contentType)
+}
+
+// extForContentType 内容类型对应的文件扩展名
+func extForContentType(contentType string) string {
+	switch contentType {
+	case "image/png":
+		return ".png"
+	case "image/jpeg":
+		return ".jpg"
+	case "image/webp":
+		return ".webp"
+	case "image/gif":
+		return ".gif"
+	case "video/mp4":
+		return ".mp4"
+	case "video/webm":
+		return ".webm"
+	}
+	if _, sub, ok := strings.Cut(contentType, "/"); ok && sub != "" {
+		return "." + sub
+	}
+	return ".bin"
+}
+
+// sizeLimitReader 累计读取超过 limit 字节时返回 ErrUploadTooLarge，limit<=0 不限制
+type sizeLimitReader struct {
+	r     io.Reader
+	limit int64
+	read  int64
+}
+
+func (l *sizeLimitReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	if l.limit > 0 && l.read > l.limit {
+		return n, fmt.Errorf("%w（上限 %d 字节）", ErrUploadTooLarge, l.limit)
+	}
+	return n, err
+}
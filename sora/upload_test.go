@@ -0,0 +1,174 @@
+package sora
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var (
+	pngHeader  = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	jpegHeader = []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00")
+	mp4Header  = []byte("\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00mp42isom")
+)
+
+func TestSniffContent(t *testing.T) {
+	long := append(append([]byte{}, pngHeader...), bytes.Repeat([]byte{0x42}, 2*sniffLen)...)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    string
+		wantErr bool
+	}{
+		{"PNG", pngHeader, "image/png", false},
+		{"JPEG", jpegHeader, "image/jpeg", false},
+		{"MP4", mp4Header, "video/mp4", false},
+		{"文本去掉参数", []byte("hello world"), "text/plain", false},
+		{"超过嗅探长度", long, "image/png", false},
+		{"空文件", nil, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contentType, r, err := sniffContent(bytes.NewReader(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("期望返回错误")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("sniffContent: %v", err)
+			}
+			if contentType != tt.want {
+				t.Errorf("contentType = %q, want %q", contentType, tt.want)
+			}
+			// 返回的 reader 需包含已嗅探的字节
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if !bytes.Equal(got, tt.data) {
+				t.Errorf("读回 %d 字节，与原始 %d 字节不一致", len(got), len(tt.data))
+			}
+		})
+	}
+}
+
+func TestSizeLimitReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		limit   int64
+		wantErr bool
+	}{
+		{"不限制", 4096, 0, false},
+		{"未超过", 100, 200, false},
+		{"恰好等于上限", 200, 200, false},
+		{"超过上限", 201, 200, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &sizeLimitReader{r: bytes.NewReader(make([]byte, tt.size)), limit: tt.limit}
+			_, err := io.Copy(io.Discard, r)
+			if tt.wantErr != errors.Is(err, ErrUploadTooLarge) {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("io.Copy: %v", err)
+			}
+		})
+	}
+}
+
+func TestWithSniffedExt(t *testing.T) {
+	tests := []struct {
+		filename, fallback, contentType, want string
+	}{
+		{"", "image", "image/png", "image.png"},
+		{"photo", "image", "image/jpeg", "photo.jpg"},
+		{"photo.jpeg", "image", "image/png", "photo.jpeg"},
+		{"clip", "video", "video/quicktime", "clip.quicktime"},
+		{"blob", "file", "", "blob.bin"},
+	}
+	for _, tt := range tests {
+		if got := withSniffedExt(tt.filename, tt.fallback, tt.contentType); got != tt.want {
+			t.Errorf("withSniffedExt(%q, %q, %q) = %q, want %q", tt.filename, tt.fallback, tt.contentType, got, tt.want)
+		}
+	}
+}
+
+// uploadServer 记录上传的文件名、内容类型和大小
+type uploadServer struct {
+	filename    string
+	contentType string
+	size        int
+}
+
+func (s *uploadServer) ServeHTTP(w nethttp.ResponseWriter, r *nethttp.Request) {
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		w.WriteHeader(nethttp.StatusBadRequest)
+		return
+	}
+	data, _ := io.ReadAll(file)
+	s.filename = header.Filename
+	s.contentType = header.Header.Get("Content-Type")
+	s.size = len(data)
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = io.WriteString(w, `{"id":"media_1"}`)
+}
+
+func TestUploadImageReader(t *testing.T) {
+	image := append(append([]byte{}, pngHeader...), bytes.Repeat([]byte{0x42}, 1000)...)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		maxSize int64
+		wantErr error
+		wantMsg string
+	}{
+		{name: "上传 PNG", data: image},
+		{name: "超过大小上限", data: image, maxSize: 100, wantErr: ErrUploadTooLarge},
+		{name: "非图片", data: []byte("plain text"), wantMsg: "不支持的文件类型 text/plain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &uploadServer{}
+			ts := httptest.NewServer(srv)
+			defer ts.Close()
+			c, err := New("", WithSoraBaseURL(ts.URL), WithMaxUploadSize(tt.maxSize))
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+
+			mediaID, err := c.UploadImageReader(context.Background(), "at", bytes.NewReader(tt.data), "ref")
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantMsg != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantMsg) {
+					t.Fatalf("err = %v, want 包含 %q", err, tt.wantMsg)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("UploadImageReader: %v", err)
+				}
+				if mediaID != "media_1" {
+					t.Errorf("mediaID = %q", mediaID)
+				}
+				if srv.filename != "ref.png" || srv.contentType != "image/png" || srv.size != len(tt.data) {
+					t.Errorf("上传文件 = %q %q %d 字节", srv.filename, srv.contentType, srv.size)
+				}
+			}
+		})
+	}
+}
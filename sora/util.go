@@ -5,7 +5,6 @@ import (
 	"encoding/base64"
 	"fmt"
 	"io"
-	"log"
 	"math/rand"
 	"net/url"
 	"path"
@@ -31,16 +30,27 @@ func randSessionID() string {
 //   - http://username:password@ip:port (原样返回)
 //   - socks5://username:password@ip:port (原样返回)
 //   - 空字符串 -> ""
+//
+// URL 中的 %s 占位符会被替换为随机 session ID
 func ParseProxy(proxy string) string {
+	return ParseProxyWithSession(proxy, "")
+}
+
+// ParseProxyWithSession 解析代理字符串，使用指定的 session ID 替换 %s 占位符
+// （sessionID 为空时使用随机值），相同 sessionID 可在代理商处保持同一出口 IP
+func ParseProxyWithSession(proxy, sessionID string) string {
 	proxy = strings.TrimSpace(proxy)
 	if proxy == "" {
 		return ""
 	}
 
 	if strings.HasPrefix(proxy, "http://") || strings.HasPrefix(proxy, "https://") || strings.HasPrefix(proxy, "socks5://") {
-		// 替换 %s 占位符为随机 session ID（兼容代理商的粘性会话格式）
+		// 替换 %s 占位符为 session ID（兼容代理商的粘性会话格式）
 		if strings.Contains(proxy, "%s") {
-			proxy = strings.ReplaceAll(proxy, "%s", randSessionID())
+			if sessionID == "" {
+				sessionID = randSessionID()
+			}
+			proxy = strings.ReplaceAll(proxy, "%s", sessionID)
 		}
 		return proxy
 	}
@@ -71,11 +81,6 @@ func readAll(r io.Reader) ([]byte, error) {
 	return io.ReadAll(r)
 }
 
-// DownloadFile 下载 URL 内容并返回字节数据
-func (c *Client) DownloadFile(ctx context.Context, fileURL string) ([]byte, error) {
-	return c.doGet(ctx, fileURL, nil)
-}
-
 // TestConnectivity 测试代理连通性，向目标 URL 发送 GET 请求，只要收到响应即视为成功
 func (c *Client) TestConnectivity(ctx context.Context, targetURL string) (statusCode int, err error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", targetURL, nil)
@@ -84,13 +89,16 @@ func (c *Client) TestConnectivity(ctx context.Context, targetURL string) (status
 	}
 	req.Header.Set("User-Agent", desktopUserAgents[c.randIntn(len(desktopUserAgents))])
 
+	done := c.observe(ctx, requestInfo(ctx, req.Method, req.URL.Path, "", 1))
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
+		done(0, err)
 		return 0, fmt.Errorf("请求失败: %w", err)
 	}
+	done(resp.StatusCode, nil)
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
-			log.Printf("[sora] close response body failed: %v", err)
+			c.log().Warn("关闭响应体失败", "err", err)
 		}
 	}()
 	return resp.StatusCode, nil
@@ -101,24 +109,40 @@ func IsDataURI(s string) bool {
 	return strings.HasPrefix(s, "data:")
 }
 
-// ParseDataURI 解析 data URI，返回二进制数据和对应的文件扩展名
-// 支持格式: data:image/png;base64,iVBOR... 或 data:video/mp4;base64,AAAA...
-func ParseDataURI(dataURI string) (data []byte, ext string, err error) {
-	// 格式: data:[<mediatype>][;base64],<data>
+// splitDataURI 拆分 data URI 的元信息和 base64 数据部分
+// 格式: data:[<mediatype>][;base64],<data>
+func splitDataURI(dataURI string) (meta, payload string, err error) {
 	if !strings.HasPrefix(dataURI, "data:") {
-		return nil, "", fmt.Errorf("不是有效的 data URI")
+		return "", "", fmt.Errorf("不是有效的 data URI")
 	}
 
 	commaIdx := strings.Index(dataURI, ",")
 	if commaIdx < 0 {
-		return nil, "", fmt.Errorf("data URI 格式错误: 缺少逗号分隔符")
+		return "", "", fmt.Errorf("data URI 格式错误: 缺少逗号分隔符")
 	}
 
-	meta := dataURI[5:commaIdx] // 跳过 "data:"
-	payload := dataURI[commaIdx+1:]
-
+	meta = dataURI[5:commaIdx] // 跳过 "data:"
 	if !strings.Contains(meta, "base64") {
-		return nil, "", fmt.Errorf("仅支持 base64 编码的 data URI")
+		return "", "", fmt.Errorf("仅支持 base64 编码的 data URI")
+	}
+	return meta, dataURI[commaIdx+1:], nil
+}
+
+// OpenDataURI 返回 data URI 的流式解码 reader，避免一次性解码出完整副本
+func OpenDataURI(dataURI string) (io.Reader, error) {
+	_, payload, err := splitDataURI(dataURI)
+	if err != nil {
+		return nil, err
+	}
+	return base64.NewDecoder(base64.StdEncoding, strings.NewReader(payload)), nil
+}
+
+// ParseDataURI 解析 data URI，返回二进制数据和对应的文件扩展名
+// 支持格式: data:image/png;base64,iVBOR... 或 data:video/mp4;base64,AAAA...
+func ParseDataURI(dataURI string) (data []byte, ext string, err error) {
+	meta, payload, err := splitDataURI(dataURI)
+	if err != nil {
+		return nil, "", err
 	}
 
 	data, err = base64.StdEncoding.DecodeString(payload)
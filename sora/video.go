@@ -0,0 +1,173 @@
+package sora
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// 视频请求默认值
+const (
+	DefaultVideoModel       = "sy_8"
+	DefaultVideoSize        = "small"
+	DefaultVideoOrientation = "landscape"
+	DefaultVideoFrames      = 300
+)
+
+// VideoRequest 视频生成请求，零值字段使用默认值
+type VideoRequest struct {
+	Prompt      string // 提示词（分镜模式下作为整体说明 instructions）
+	Orientation string // landscape / portrait，默认 landscape
+	NFrames     int    // 帧数（150=5s、300=10s、450=15s、750=25s），默认 300
+	Model       string // sy_8（标准）/ sy_ore（Pro），默认 sy_8
+	Size        string // small / large（HD），默认 small
+	StyleID     string // 风格 ID，可选值见 ValidStyles
+
+	MediaIDs      []string               // UploadImage 返回的 mediaID（图生视频）
+	RemixTargetID string                 // Remix 目标视频 ID（s_xxx）
+	Storyboard    bool                   // 使用分镜接口（Shots 非空时自动启用）
+	Shots         []StoryboardShot       // 分镜镜头，为空时 Prompt 原样提交
+	CameoIDs      []string               // 出镜角色 ID（FinalizeCharacter 返回的 characterID）
+	Metadata      map[string]interface{} // 附加元数据，原样透传
+
+	// CameoReplacements Remix 时替换原视频中的角色：原角色 ID → 新角色 ID
+	CameoReplacements map[string]string
+}
+
+// isStoryboard 是否走分镜接口
+func (r VideoRequest) isStoryboard() bool {
+	return r.Storyboard || len(r.Shots) > 0
+}
+
+// CreateVideo 按请求创建视频任务，返回 Sora 任务 ID
+// 分镜模式提交到 /nf/create/storyboard，否则提交到 /nf/create
+func (c *Client) CreateVideo(ctx context.Context, accessToken, sentinelToken string, req VideoRequest) (string, error) {
+	headers := c.sentinelHeaders(accessToken, sentinelToken)
+	payload := req.payload()
+
+	url := c.soraBaseURL + "/nf/create"
+	action := "创建任务"
+	switch {
+	case req.isStoryboard():
+		url = c.soraBaseURL + "/nf/create/storyboard"
+		action = "创建分镜任务"
+	case req.RemixTargetID != "":
+		action = "创建 Remix 任务"
+	}
+
+	resp, err := c.doSubmit(ctx, url, headers, payload)
+	if err != nil {
+		return "", fmt.Errorf("%s失败: %w", action, err)
+	}
+
+	taskID, ok := resp["id"].(string)
+	if !ok || taskID == "" {
+		return "", fmt.Errorf("响应中无 task_id: %v", resp)
+	}
+
+	return taskID, nil
+}
+
+// payload 构造 /nf/create 或 /nf/create/storyboard 请求体
+func (r VideoRequest) payload() map[string]interface{} {
+	orientation := r.Orientation
+	if orientation == "" {
+		orientation = DefaultVideoOrientation
+	}
+	nFrames := r.NFrames
+	if nFrames <= 0 {
+		nFrames = DefaultVideoFrames
+	}
+	model := r.Model
+	if model == "" {
+		model = DefaultVideoModel
+	}
+	size := r.Size
+	if size == "" {
+		size = DefaultVideoSize
+	}
+
+	inpaintItems := []interface{}{}
+	for _, mediaID := range r.MediaIDs {
+		if mediaID == "" {
+			continue
+		}
+		inpaintItems = append(inpaintItems, map[string]interface{}{
+			"kind":      "upload",
+			"upload_id": mediaID,
+		})
+	}
+
+	prompt := r.Prompt
+	if len(r.Shots) > 0 {
+		prompt = formatStoryboardShots(r.Shots, r.Prompt)
+	}
+
+	payload := map[string]interface{}{
+		"kind":          "video",
+		"prompt":        prompt,
+		"orientation":   orientation,
+		"size":          size,
+		"n_frames":      nFrames,
+		"model":         model,
+		"inpaint_items": inpaintItems,
+		"style_id":      nilIfEmpty(r.StyleID),
+	}
+
+	// Remix / 出镜角色需要携带 cameo 字段
+	if r.RemixTargetID != "" || len(r.CameoIDs) > 0 || len(r.CameoReplacements) > 0 {
+		cameoIDs := []interface{}{}
+		for _, id := range r.CameoIDs {
+			if id != "" {
+				cameoIDs = append(cameoIDs, id)
+			}
+		}
+		replacements := map[string]interface{}{}
+		for from, to := range r.CameoReplacements {
+			replacements[from] = to
+		}
+		payload["cameo_ids"] = cameoIDs
+		payload["cameo_replacements"] = replacements
+	}
+	if r.RemixTargetID != "" {
+		payload["remix_target_id"] = r.RemixTargetID
+	}
+	if len(r.Metadata) > 0 {
+		payload["metadata"] = r.Metadata
+	}
+
+	if r.isStoryboard() {
+		payload["title"] = "Draft your video"
+		payload["storyboard_id"] = nil
+		payload["remix_target_id"] = nilIfEmpty(r.RemixTargetID)
+		if _, ok := payload["metadata"]; !ok {
+			payload["metadata"] = nil
+		}
+		if _, ok := payload["cameo_ids"]; !ok {
+			payload["cameo_ids"] = nil
+			payload["cameo_replacements"] = nil
+		}
+		payload["audio_caption"] = nil
+		payload["audio_transcript"] = nil
+		payload["video_caption"] = nil
+	}
+
+	return payload
+}
+
+// MentionCameos 确保提示词中 @ 提及了所有出镜角色的用户名，未提及的追加到开头
+// Sora 依据提示词中的 @username 决定角色出镜位置
+func MentionCameos(prompt string, usernames ...string) string {
+	var missing []string
+	for _, name := range usernames {
+		name = strings.TrimPrefix(strings.TrimSpace(name), "@")
+		if name == "" || strings.Contains(prompt, "@"+name) {
+			continue
+		}
+		missing = append(missing, "@"+name)
+	}
+	if len(missing) == 0 {
+		return prompt
+	}
+	return strings.Join(missing, " ") + " " + prompt
+}
@@ -6,6 +6,7 @@ import Login from './pages/Login'
 import Dashboard from './pages/Dashboard'
 import AccountList from './pages/AccountList'
 import GroupList from './pages/GroupList'
+import ProxyList from './pages/ProxyList'
 import APIKeyList from './pages/APIKeyList'
 import TaskList from './pages/TaskList'
 import TaskDetail from './pages/TaskDetail'
@@ -49,6 +50,7 @@ export default function App() {
           <Route path="/" element={<DefaultRedirect />} />
           <Route path="/accounts" element={<AdminRoute><AccountList /></AdminRoute>} />
           <Route path="/groups" element={<AdminRoute><GroupList /></AdminRoute>} />
+          <Route path="/proxies" element={<AdminRoute><ProxyList /></AdminRoute>} />
           <Route path="/api-keys" element={<AdminRoute><APIKeyList /></AdminRoute>} />
           <Route path="/tasks" element={<TaskList />} />
           <Route path="/tasks/:id" element={<TaskDetail />} />
@@ -1,6 +1,7 @@
 import client from './client'
 import type { SoraAccount, CreateAccountRequest, BatchImportRequest, BatchImportResult } from '../types/account'
 import type { PageResponse } from '../types/api'
+import type { CharacterSyncResult } from '../types/character'
 
 export function listAccounts(params?: { page?: number; page_size?: number; status?: string; group_id?: number | null; keyword?: string }) {
   return client.get<PageResponse<SoraAccount>>('/admin/accounts', { params })
@@ -30,6 +31,10 @@ export function revealAccountTokens(accountId: number) {
   return client.get<{ access_token: string; refresh_token: string }>(`/admin/accounts/${accountId}/tokens`)
 }
 
+export function importAccountCharacters(accountId: number) {
+  return client.post<CharacterSyncResult>(`/admin/accounts/${accountId}/characters/import`)
+}
+
 export function batchImportAccounts(data: BatchImportRequest) {
   return client.post<BatchImportResult>('/admin/accounts/batch', data)
 }
@@ -0,0 +1,23 @@
+import client from './client'
+import type { SoraProxy, CreateProxyRequest } from '../types/account'
+import type { ProxyTestResult } from './settings'
+
+export function listProxies() {
+  return client.get<SoraProxy[]>('/admin/proxies')
+}
+
+export function createProxy(data: CreateProxyRequest) {
+  return client.post<SoraProxy>('/admin/proxies', data)
+}
+
+export function updateProxy(id: number, data: CreateProxyRequest) {
+  return client.put<SoraProxy>(`/admin/proxies/${id}`, data)
+}
+
+export function deleteProxy(id: number) {
+  return client.delete(`/admin/proxies/${id}`)
+}
+
+export function checkProxy(id: number) {
+  return client.post<ProxyTestResult>(`/admin/proxies/${id}/test`)
+}
@@ -2,9 +2,23 @@ import client from './client'
 
 export interface SystemSettings {
   proxy_url: string
+  proxy_check_interval: string
   token_refresh_interval: string
+  token_refresh_ahead: string
+  token_expiring_soon: string
   credit_sync_interval: string
   subscription_sync_interval: string
+  sentinel_pool_size: string
+  sentinel_ttl: string
+  fingerprint_profiles: string
+  max_upload_size: string
+  max_download_size: string
+  media_connect_timeout: string
+  media_read_timeout: string
+  account_rate_limit: string
+  account_rate_burst: string
+  submit_workers: string
+  submit_max_attempts: string
 }
 
 export const getSettings = () => client.get<SystemSettings>('/admin/settings')
@@ -12,6 +26,37 @@ export const getSettings = () => client.get<SystemSettings>('/admin/settings')
 export const updateSettings = (data: Partial<Record<string, string>>) =>
   client.put<SystemSettings>('/admin/settings', data)
 
+export interface SentinelPoolStats {
+  pool_size: number
+  ttl: string
+  pooled: number
+  accounts: number
+  hits: number
+  misses: number
+  hit_rate: number
+  refills: number
+  refill_failures: number
+  expired: number
+  invalidated: number
+}
+
+export const getSentinelPoolStats = () => client.get<SentinelPoolStats>('/admin/sentinel-pool')
+
+export interface ClientPoolStats {
+  clients: number
+  idle_ttl: string
+  hits: number
+  misses: number
+  hit_rate: number
+  evictions: number
+  cold_requests: number
+  cold_avg_ms: number
+  reused_requests: number
+  reused_avg_ms: number
+}
+
+export const getClientPoolStats = () => client.get<ClientPoolStats>('/admin/client-pool')
+
 export interface ProxyTestResult {
   success: boolean
   status_code?: number
@@ -20,7 +65,7 @@ export interface ProxyTestResult {
 }
 
 export const testProxy = (proxyUrl: string) =>
-  client.post<ProxyTestResult>('/admin/proxy-test', { proxy_url: proxyUrl })
+  client.post<ProxyTestResult>('/admin/proxies/test', { proxy_url: proxyUrl })
 
 export interface VersionInfo {
   current: string
@@ -8,6 +8,7 @@ const allNavItems = [
   { path: '/', label: '概览', icon: BarChartIcon, adminOnly: true },
   { path: '/accounts', label: '账号', icon: UserIcon, adminOnly: true },
   { path: '/groups', label: '分组', icon: FolderIcon, adminOnly: true },
+  { path: '/proxies', label: '代理', icon: GlobeIcon, adminOnly: true },
   { path: '/api-keys', label: '密钥', icon: KeyIcon, adminOnly: true },
   { path: '/tasks', label: '任务', icon: ListIcon, adminOnly: false },
   { path: '/characters', label: '角色', icon: CharacterIcon, adminOnly: false },
@@ -393,6 +394,16 @@ function FolderIcon({ active }: { active?: boolean }) {
   )
 }
 
+function GlobeIcon({ active }: { active?: boolean }) {
+  return (
+    <svg width="18" height="18" viewBox="0 0 24 24" fill="none" stroke={active ? 'var(--accent)' : 'currentColor'} strokeWidth="1.8" strokeLinecap="round" strokeLinejoin="round">
+      <circle cx="12" cy="12" r="10" />
+      <line x1="2" y1="12" x2="22" y2="12" />
+      <path d="M12 2a15.3 15.3 0 014 10 15.3 15.3 0 01-4 10 15.3 15.3 0 01-4-10 15.3 15.3 0 014-10z" />
+    </svg>
+  )
+}
+
 function KeyIcon({ active }: { active?: boolean }) {
   return (
     <svg width="18" height="18" viewBox="0 0 24 24" fill="none" stroke={active ? 'var(--accent)' : 'currentColor'} strokeWidth="1.8" strokeLinecap="round" strokeLinejoin="round">
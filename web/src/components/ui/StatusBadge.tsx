@@ -5,12 +5,15 @@ interface Props {
 
 const statusConfig: Record<string, { bg: string; color: string; dotColor: string; label: string }> = {
   active:          { bg: 'var(--success-soft)', color: 'var(--success)', dotColor: 'var(--success)', label: '正常' },
+  expiring_soon:   { bg: 'var(--warning-soft)', color: 'var(--warning)', dotColor: 'var(--warning)', label: '即将过期' },
   token_expired:   { bg: 'var(--danger-soft)',  color: 'var(--danger)',  dotColor: 'var(--danger)',  label: 'Token 过期' },
   quota_exhausted: { bg: 'var(--warning-soft)', color: 'var(--warning)', dotColor: 'var(--warning)', label: '额度耗尽' },
   queued:          { bg: 'var(--info-soft)',    color: 'var(--info)',    dotColor: 'var(--info)',    label: '排队中' },
+  submitting:      { bg: 'var(--info-soft)',    color: 'var(--info)',    dotColor: 'var(--info)',    label: '提交中' },
   in_progress:     { bg: 'var(--warning-soft)', color: 'var(--warning)', dotColor: 'var(--warning)', label: '进行中' },
   completed:       { bg: 'var(--success-soft)', color: 'var(--success)', dotColor: 'var(--success)', label: '已完成' },
   failed:          { bg: 'var(--danger-soft)',  color: 'var(--danger)',  dotColor: 'var(--danger)',  label: '失败' },
+  missing:         { bg: 'var(--bg-inset)',     color: 'var(--text-tertiary)', dotColor: 'var(--text-tertiary)', label: '已失效' },
 }
 
 export default function StatusBadge({ status, className = '' }: Props) {
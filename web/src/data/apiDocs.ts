@@ -91,6 +91,7 @@ API Key 可在管理后台的「密钥」页面创建和管理。
           { name: 'prompt', type: 'string', required: true, description: '视频生成提示词' },
           { name: 'input_reference', type: 'string', required: false, description: '参考图片 URL 或 base64 data URI（图生视频，支持 PNG/JPEG/WebP）' },
           { name: 'style', type: 'string', required: false, description: '视频风格（如 anime, retro, comic 等，见模型速查表）' },
+          { name: 'characters', type: 'string[]', required: false, description: '出镜角色（char_xxx 或角色用户名），任务会路由到角色所属账号' },
         ],
         responseExample: `{
   "id": "task_a1b2c3d4",
@@ -107,7 +108,7 @@ API Key 可在管理后台的「密钥」页面创建和管理。
         method: 'GET',
         path: '/v1/videos/:id',
         title: '查询视频任务状态',
-        description: '根据任务 ID 查询当前状态和进度。轮询此接口直到 status 变为 completed 或 failed。',
+        description: '根据任务 ID 查询当前状态和进度。轮询此接口直到 status 变为 completed 或 failed。完成后返回 encodings：各清晰度（source/md/ld）、缩略图和 GIF 预览的上游链接（会过期），以及宽高和时长。',
         params: [
           { name: 'id', type: 'string', required: true, description: '任务 ID（如 task_a1b2c3d4）' },
         ],
@@ -119,7 +120,17 @@ API Key 可在管理后台的「密钥」页面创建和管理。
   "status": "completed",
   "progress": 100,
   "created_at": 1709251234,
-  "size": "1280x720"
+  "size": "1280x720",
+  "encodings": {
+    "source": "https://videos.openai.com/.../source.mp4",
+    "md": "https://videos.openai.com/.../md.mp4",
+    "ld": "https://videos.openai.com/.../ld.mp4",
+    "thumbnail": "https://videos.openai.com/.../thumbnail.webp",
+    "gif": "https://videos.openai.com/.../preview.gif",
+    "width": 1280,
+    "height": 720,
+    "duration": 10
+  }
 }
 
 // 失败
@@ -146,6 +157,18 @@ API Key 可在管理后台的「密钥」页面创建和管理。
         responseExample: `// Content-Type: video/mp4
 // 返回视频二进制流`,
       },
+      {
+        id: 'download-thumbnail',
+        method: 'GET',
+        path: '/v1/videos/:id/thumbnail',
+        title: '下载视频缩略图',
+        description: '下载已完成任务的视频封面缩略图。仅当 status 为 completed 时可用，链接过期时服务端自动刷新。',
+        params: [
+          { name: 'id', type: 'string', required: true, description: '任务 ID（如 task_a1b2c3d4）' },
+        ],
+        responseExample: `// Content-Type: image/webp
+// 返回图片二进制流`,
+      },
     ],
   },
   // ── Remix 视频 ──
@@ -167,6 +190,7 @@ API Key 可在管理后台的「密钥」页面创建和管理。
           { name: 'prompt', type: 'string', required: true, description: '重新创作的提示词' },
           { name: 'remix_target', type: 'string', required: true, description: 'Sora 分享链接或 s_xxx 格式视频 ID' },
           { name: 'style', type: 'string', required: false, description: '视频风格' },
+          { name: 'characters', type: 'string[]', required: false, description: '出镜角色（char_xxx 或角色用户名），任务会路由到角色所属账号' },
         ],
         responseExample: `{
   "id": "task_e5f6g7h8",
@@ -203,6 +227,7 @@ API Key 可在管理后台的「密钥」页面创建和管理。
           { name: 'prompt', type: 'string', required: true, description: '分镜格式提示词，如 [5.0s]场景1 [5.0s]场景2' },
           { name: 'input_reference', type: 'string', required: false, description: '参考图片 URL 或 base64 data URI' },
           { name: 'style', type: 'string', required: false, description: '视频风格' },
+          { name: 'characters', type: 'string[]', required: false, description: '出镜角色（char_xxx 或角色用户名），任务会路由到角色所属账号' },
         ],
         responseExample: `{
   "id": "task_i9j0k1l2",
@@ -234,6 +259,7 @@ API Key 可在管理后台的「密钥」页面创建和管理。
           { name: 'prompt', type: 'string', required: true, description: '图片生成提示词' },
           { name: 'size', type: 'string', required: false, description: '图片尺寸：1792x1024（横屏，默认）、1024x1024（方形）、1024x1792（竖屏）' },
           { name: 'input_reference', type: 'string', required: false, description: '参考图片 URL 或 base64 data URI（图生图）' },
+          { name: 'n', type: 'number', required: false, description: '生成数量 1-4，默认 1' },
         ],
         responseExample: `{
   "id": "task_a1b2c3d4",
@@ -249,7 +275,7 @@ API Key 可在管理后台的「密钥」页面创建和管理。
         method: 'GET',
         path: '/v1/images/:id',
         title: '查询图片任务状态',
-        description: '根据任务 ID 查询图片任务状态。完成后返回 image_url。',
+        description: '根据任务 ID 查询图片任务状态。完成后返回 image_url（第一张）和 images（全部结果）。',
         params: [
           { name: 'id', type: 'string', required: true, description: '任务 ID' },
         ],
@@ -260,7 +286,11 @@ API Key 可在管理后台的「密钥」页面创建和管理。
   "progress": 100,
   "created_at": 1709251234,
   "size": "1792x1024",
-  "image_url": "https://..."
+  "image_url": "https://...",
+  "images": [
+    { "index": 0, "url": "https://...", "generation_id": "gen_xxx" },
+    { "index": 1, "url": "https://...", "generation_id": "gen_yyy" }
+  ]
 }`,
       },
       {
@@ -268,10 +298,13 @@ API Key 可在管理后台的「密钥」页面创建和管理。
         method: 'GET',
         path: '/v1/images/:id/content',
         title: '下载图片',
-        description: '下载已完成的图片文件。仅当 status 为 completed 时可用。',
+        description: '下载已完成的图片文件。仅当 status 为 completed 时可用。多张结果时通过 index 指定。',
         params: [
           { name: 'id', type: 'string', required: true, description: '任务 ID' },
         ],
+        queryParams: [
+          { name: 'index', type: 'number', required: false, description: '图片序号（对应 images[].index），默认 0' },
+        ],
         responseExample: `// Content-Type: image/png 或 image/webp
 // 返回图片二进制流`,
       },
@@ -290,11 +323,17 @@ API Key 可在管理后台的「密钥」页面创建和管理。
         path: '/v1/characters',
         title: '创建角色',
         dangerWarning: '此操作会上传视频并创建角色，确认发送？',
-        description: '上传包含人物的视频创建角色。后台自动完成：上传 → 处理 → 定稿。轮询查询接口获取最终状态。',
+        description: '上传包含人物的视频创建角色。后台自动完成：上传 → 处理 → 定稿 → 设置可见性。轮询查询接口获取最终状态。支持 JSON 和 multipart/form-data。',
         bodyParams: [
           { name: 'video_url', type: 'string', required: true, description: '角色视频 URL 或 base64 data URI（mp4 格式）' },
           { name: 'username', type: 'string', required: false, description: '角色用户名（不传则使用系统推荐值）' },
           { name: 'display_name', type: 'string', required: false, description: '角色显示名称（不传则使用系统推荐值）' },
+          { name: 'sample_start', type: 'number', required: false, description: '采样窗口起点（秒），默认 0' },
+          { name: 'sample_end', type: 'number', required: false, description: '采样窗口终点（秒），默认起点 + 3' },
+          { name: 'instruction_set', type: 'string', required: false, description: '角色指令（如说话风格、行为描述）' },
+          { name: 'safety_instruction_set', type: 'string', required: false, description: '安全指令（限制角色的使用方式）' },
+          { name: 'profile_image', type: 'string', required: false, description: '自定义头像 URL 或 base64 data URI（multipart/form-data 请求可直接上传同名文件），不传则使用系统推荐头像' },
+          { name: 'visibility', type: 'string', required: false, description: '初始可见性：public（默认）/ private' },
         ],
         responseExample: `{
   "id": "char_a1b2c3d4",
@@ -514,6 +553,7 @@ prompt 中使用 \`[Ns]\` 标记自动进入分镜模式。示例：
 | 400 | 请求参数错误（如模型名无效） |
 | 401 | 认证失败（API Key 无效或已禁用） |
 | 404 | 资源不存在 |
+| 413 | 角色视频超过上传大小上限（视频/图片任务的参考图超限时任务标记为 failed） |
 | 500 | 服务内部错误（如 Sora API 调用失败） |
 | 503 | 无可用账号 |
 
@@ -521,7 +561,8 @@ prompt 中使用 \`[Ns]\` 标记自动进入分镜模式。示例：
 
 | 状态 | 说明 |
 |------|------|
-| queued | 已提交，等待处理 |
+| queued | 已入队，等待后台选取账号并提交 |
+| submitting | 正在提交到上游 |
 | in_progress | 生成中 |
 | completed | 已完成，可下载 |
 | failed | 失败 |
@@ -1,7 +1,8 @@
 import { useCallback, useEffect, useRef, useState } from 'react'
-import { listAccounts, createAccount, updateAccount, deleteAccount, refreshAccountToken, getAccountStatus, revealAccountTokens, batchImportAccounts } from '../api/account'
+import { listAccounts, createAccount, updateAccount, deleteAccount, refreshAccountToken, getAccountStatus, revealAccountTokens, batchImportAccounts, importAccountCharacters } from '../api/account'
 import { listGroups } from '../api/group'
-import type { SoraAccount, CreateAccountRequest, SoraAccountGroup, BatchImportResult } from '../types/account'
+import { listProxies } from '../api/proxy'
+import type { SoraAccount, CreateAccountRequest, SoraAccountGroup, SoraProxy, BatchImportResult } from '../types/account'
 import GlassCard from '../components/ui/GlassCard'
 import StatusBadge from '../components/ui/StatusBadge'
 import LoadingState from '../components/ui/LoadingState'
@@ -18,7 +19,7 @@ function timeAgo(ts: string | null) {
   return formatDistanceToNow(new Date(ts), { addSuffix: true, locale: zhCN })
 }
 
-const emptyForm: CreateAccountRequest = { name: '', access_token: '', refresh_token: '', group_id: null }
+const emptyForm: CreateAccountRequest = { name: '', access_token: '', refresh_token: '', group_id: null, proxy_id: null, max_concurrent: 0 }
 
 const inputStyle = {
   background: 'var(--bg-inset)',
@@ -38,6 +39,7 @@ const inputBlur = (e: React.FocusEvent<HTMLElement>) => {
 const statusFilters = [
   { label: '全部', value: '' },
   { label: '正常', value: 'active' },
+  { label: '即将过期', value: 'expiring_soon' },
   { label: 'Token 过期', value: 'token_expired' },
   { label: '额度耗尽', value: 'quota_exhausted' },
 ]
@@ -48,6 +50,7 @@ export default function AccountList() {
   const [accounts, setAccounts] = useState<SoraAccount[]>([])
   const [total, setTotal] = useState(0)
   const [groups, setGroups] = useState<SoraAccountGroup[]>([])
+  const [proxies, setProxies] = useState<SoraProxy[]>([])
   const [loading, setLoading] = useState(true)
 
   // 筛选 & 分页
@@ -77,14 +80,16 @@ export default function AccountList() {
   const mountedRef = useRef(true)
 
   const fetchData = useCallback(async () => {
-    const [aRes, gRes] = await Promise.all([
+    const [aRes, gRes, pRes] = await Promise.all([
       listAccounts({ page, page_size: PAGE_SIZE, status: status || undefined, group_id: groupId ? Number(groupId) : undefined, keyword: keyword || undefined }),
       listGroups(),
+      listProxies(),
     ])
     return {
       accounts: aRes.data.list ?? [],
       total: aRes.data.total,
       groups: gRes.data ?? [],
+      proxies: pRes.data ?? [],
     }
   }, [page, status, groupId, keyword])
 
@@ -98,6 +103,7 @@ export default function AccountList() {
           setAccounts(data.accounts)
           setTotal(data.total)
           setGroups(data.groups)
+          setProxies(data.proxies)
         }
       } catch {
         // ignore
@@ -116,6 +122,7 @@ export default function AccountList() {
         setAccounts(data.accounts)
         setTotal(data.total)
         setGroups(data.groups)
+        setProxies(data.proxies)
       }
     } catch {
       // ignore
@@ -151,7 +158,7 @@ export default function AccountList() {
 
   const handleEdit = (acc: SoraAccount) => {
     setEditId(acc.id)
-    setForm({ name: acc.name, access_token: '', refresh_token: '', group_id: acc.group_id })
+    setForm({ name: acc.name, access_token: '', refresh_token: '', group_id: acc.group_id, proxy_id: acc.proxy_id, max_concurrent: acc.max_concurrent ?? 0 })
     setShowForm(true)
   }
 
@@ -183,6 +190,17 @@ export default function AccountList() {
     setActionLoading(prev => ({ ...prev, [`refresh-${id}`]: false }))
   }
 
+  const handleImportCharacters = async (id: number) => {
+    setActionLoading(prev => ({ ...prev, [`chars-${id}`]: true }))
+    try {
+      const { data } = await importAccountCharacters(id)
+      toast.success(`角色同步完成：新增 ${data.imported}，更新 ${data.updated}，失效 ${data.missing}`)
+    } catch (err) {
+      toast.error(getErrorMessage(err, '导入角色失败'))
+    }
+    setActionLoading(prev => ({ ...prev, [`chars-${id}`]: false }))
+  }
+
   const handleRevealTokens = async (id: number) => {
     try {
       const res = await revealAccountTokens(id)
@@ -426,7 +444,14 @@ export default function AccountList() {
                     color={acc.remaining_count === 0 ? 'var(--danger)' : undefined}
                     bold
                   />
+                  <InfoItem label="并发" value={concurrencyText(acc, groups)} />
                   <InfoItem label="最后使用" value={timeAgo(acc.last_used_at)} />
+                  {acc.proxy_name && <InfoItem label="代理" value={acc.proxy_name} />}
+                  <InfoItem
+                    label="Token 过期"
+                    value={timeAgo(acc.token_expires_at)}
+                    color={acc.status === 'expiring_soon' || acc.status === 'token_expired' ? 'var(--danger)' : undefined}
+                  />
                 </div>
 
                 {/* Token 详情 */}
@@ -488,6 +513,12 @@ export default function AccountList() {
                     loading={actionLoading[`sync-${acc.id}`]}
                     onClick={() => handleSync(acc.id)}
                   />
+                  <ActionBtn
+                    label="导入角色"
+                    title="从账号同步 Sora 角色库"
+                    loading={actionLoading[`chars-${acc.id}`]}
+                    onClick={() => handleImportCharacters(acc.id)}
+                  />
                   <ActionBtn
                     label={revealedTokens[acc.id] ? '隐藏 Token' : '查看 Token'}
                     onClick={() => revealedTokens[acc.id] ? handleHideTokens(acc.id) : handleRevealTokens(acc.id)}
@@ -568,6 +599,39 @@ export default function AccountList() {
               </select>
             </div>
           </div>
+          <div>
+            <label className="block text-[13px] font-medium mb-1.5" style={{ color: 'var(--text-secondary)' }}>
+              代理 <span style={{ color: 'var(--text-tertiary)', fontWeight: 400 }}>（可选）</span>
+            </label>
+            <select
+              value={form.proxy_id ?? ''}
+              onChange={(e) => setForm({ ...form, proxy_id: e.target.value ? Number(e.target.value) : null })}
+              className="w-full px-3 py-2.5 text-sm outline-none transition-all"
+              style={inputStyle}
+              onFocus={inputFocus}
+              onBlur={inputBlur}
+            >
+              <option value="">自动分配（分组代理 / 默认代理）</option>
+              {proxies.map(p => (
+                <option key={p.id} value={p.id}>{p.name}{p.healthy ? '' : '（不可用）'}</option>
+              ))}
+            </select>
+          </div>
+          <div>
+            <label className="block text-[13px] font-medium mb-1.5" style={{ color: 'var(--text-secondary)' }}>
+              最大并发任务数 <span style={{ color: 'var(--text-tertiary)', fontWeight: 400 }}>（0 为使用分组默认值）</span>
+            </label>
+            <input
+              type="number"
+              min={0}
+              value={form.max_concurrent ?? 0}
+              onChange={(e) => setForm({ ...form, max_concurrent: Math.max(0, Number(e.target.value) || 0) })}
+              className="w-full px-3 py-2.5 text-sm outline-none transition-all"
+              style={inputStyle}
+              onFocus={inputFocus}
+              onBlur={inputBlur}
+            />
+          </div>
           <div>
             <label className="block text-[13px] font-medium mb-1.5" style={{ color: 'var(--text-secondary)' }}>Access Token</label>
             <input
@@ -758,6 +822,12 @@ export default function AccountList() {
   )
 }
 
+// 进行中任务数 / 最大并发（账号未设置时取分组默认值）
+function concurrencyText(acc: SoraAccount, groups: SoraAccountGroup[]) {
+  const limit = acc.max_concurrent || groups.find(g => g.id === acc.group_id)?.max_concurrent || 0
+  return limit > 0 ? `${acc.in_flight ?? 0}/${limit}` : `${acc.in_flight ?? 0}/不限`
+}
+
 function InfoItem({ label, value, color, bold }: { label: string; value: string; color?: string; bold?: boolean }) {
   return (
     <div style={{ color: 'var(--text-tertiary)' }}>
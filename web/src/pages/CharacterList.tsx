@@ -17,6 +17,7 @@ const statusFilters = [
   { label: '就绪', value: 'ready' },
   { label: '处理中', value: 'processing' },
   { label: '失败', value: 'failed' },
+  { label: '已失效', value: 'missing' },
 ]
 
 const visibilityFilters = [
@@ -30,12 +31,23 @@ const statusMap: Record<string, string> = {
   processing: 'in_progress',
   ready: 'completed',
   failed: 'failed',
+  missing: 'missing',
+}
+
+// 处理中角色的下一步（按最后完成的步骤显示）
+const stepLabel: Record<string, string> = {
+  uploaded: '等待视频处理',
+  cameo_ready: '下载头像',
+  avatar_downloaded: '上传头像',
+  avatar_uploaded: '定稿',
+  finalized: '设置可见性',
 }
 
 const statusLabel: Record<string, string> = {
   processing: '处理中',
   ready: '就绪',
   failed: '失败',
+  missing: '已失效',
 }
 
 export default function CharacterList() {
@@ -365,6 +377,9 @@ export default function CharacterList() {
                       <DetailRow label="关联账号" value={selectedChar.account_email} />
                     )}
                     <DetailRow label="状态" value={statusLabel[selectedChar.status] || selectedChar.status} />
+                    {selectedChar.status === 'processing' && selectedChar.step && stepLabel[selectedChar.step] && (
+                      <DetailRow label="当前步骤" value={stepLabel[selectedChar.step]} />
+                    )}
                     {selectedChar.status === 'ready' && isAdmin && (
                       <div className="flex items-center justify-between gap-3">
                         <span className="text-xs font-medium flex-shrink-0" style={{ color: 'var(--text-tertiary)' }}>可见性</span>
@@ -28,7 +28,7 @@ export default function GroupList() {
   const [loading, setLoading] = useState(true)
   const [showForm, setShowForm] = useState(false)
   const [editId, setEditId] = useState<number | null>(null)
-  const [form, setForm] = useState({ name: '', description: '', enabled: true })
+  const [form, setForm] = useState({ name: '', description: '', enabled: true, max_concurrent: 0 })
   const [submitting, setSubmitting] = useState(false)
   const [refreshKey, setRefreshKey] = useState(0)
   const [confirmState, setConfirmState] = useState<{ open: boolean; id: number }>({ open: false, id: 0 })
@@ -38,7 +38,7 @@ export default function GroupList() {
   const closeForm = () => {
     setShowForm(false)
     setEditId(null)
-    setForm({ name: '', description: '', enabled: true })
+    setForm({ name: '', description: '', enabled: true, max_concurrent: 0 })
   }
 
   useEffect(() => {
@@ -106,7 +106,7 @@ export default function GroupList() {
           </p>
         </div>
         <button
-          onClick={() => { setEditId(null); setForm({ name: '', description: '', enabled: true }); setShowForm(true) }}
+          onClick={() => { setEditId(null); setForm({ name: '', description: '', enabled: true, max_concurrent: 0 }); setShowForm(true) }}
           className="px-4 py-2 rounded-xl text-sm font-medium text-white transition-all cursor-pointer"
           style={{ background: 'var(--accent)' }}
           onMouseEnter={(e) => e.currentTarget.style.background = 'var(--accent-hover)'}
@@ -139,7 +139,7 @@ export default function GroupList() {
                   <button
                     onClick={() => {
                       setEditId(g.id)
-                      setForm({ name: g.name, description: g.description, enabled: g.enabled })
+                      setForm({ name: g.name, description: g.description, enabled: g.enabled, max_concurrent: g.max_concurrent ?? 0 })
                       setShowForm(true)
                     }}
                     className="p-1.5 rounded-lg transition-colors cursor-pointer"
@@ -180,6 +180,12 @@ export default function GroupList() {
                 </svg>
                 {g.account_count} 个账号
               </div>
+              <div
+                className="inline-flex items-center gap-1.5 text-xs font-medium px-2.5 py-1 rounded-full ml-2"
+                style={{ background: 'var(--bg-inset)', color: 'var(--text-secondary)' }}
+              >
+                {g.max_concurrent > 0 ? `默认并发 ${g.max_concurrent}` : '并发不限'}
+              </div>
             </GlassCard>
           ))}
         </div>
@@ -217,6 +223,20 @@ export default function GroupList() {
               onBlur={inputBlur}
             />
           </div>
+          <div>
+            <label className="block text-[13px] font-medium mb-1.5" style={{ color: 'var(--text-secondary)' }}>默认最大并发任务数</label>
+            <input
+              type="number"
+              min={0}
+              value={form.max_concurrent}
+              onChange={(e) => setForm({ ...form, max_concurrent: Math.max(0, Number(e.target.value) || 0) })}
+              className="w-full px-3 py-2.5 text-sm outline-none transition-all"
+              style={inputStyle}
+              onFocus={inputFocus}
+              onBlur={inputBlur}
+            />
+            <p className="text-xs mt-1" style={{ color: 'var(--text-tertiary)' }}>组内未单独设置的账号使用此值，0 为不限制</p>
+          </div>
           <div className="flex justify-end gap-2 pt-2">
             <button
               type="button"
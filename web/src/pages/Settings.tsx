@@ -1,5 +1,5 @@
 import { useEffect, useState } from 'react'
-import { getSettings, updateSettings, testProxy, getVersion, triggerUpgrade, type ProxyTestResult, type VersionInfo } from '../api/settings'
+import { getSettings, updateSettings, testProxy, getVersion, triggerUpgrade, getSentinelPoolStats, getClientPoolStats, type ProxyTestResult, type VersionInfo, type SentinelPoolStats, type ClientPoolStats } from '../api/settings'
 import GlassCard from '../components/ui/GlassCard'
 import LoadingState from '../components/ui/LoadingState'
 import { motion, AnimatePresence } from 'framer-motion'
@@ -22,9 +22,25 @@ const inputBlur = (e: React.FocusEvent<HTMLInputElement>) => {
 
 export default function Settings() {
   const [proxyUrl, setProxyUrl] = useState('')
+  const [proxyCheckInterval, setProxyCheckInterval] = useState('')
   const [tokenRefreshInterval, setTokenRefreshInterval] = useState('')
+  const [tokenRefreshAhead, setTokenRefreshAhead] = useState('')
+  const [tokenExpiringSoon, setTokenExpiringSoon] = useState('')
   const [creditSyncInterval, setCreditSyncInterval] = useState('')
   const [subscriptionSyncInterval, setSubscriptionSyncInterval] = useState('')
+  const [sentinelPoolSize, setSentinelPoolSize] = useState('')
+  const [sentinelTTL, setSentinelTTL] = useState('')
+  const [fingerprintProfiles, setFingerprintProfiles] = useState('')
+  const [maxUploadSize, setMaxUploadSize] = useState('')
+  const [maxDownloadSize, setMaxDownloadSize] = useState('')
+  const [mediaConnectTimeout, setMediaConnectTimeout] = useState('')
+  const [mediaReadTimeout, setMediaReadTimeout] = useState('')
+  const [accountRateLimit, setAccountRateLimit] = useState('')
+  const [accountRateBurst, setAccountRateBurst] = useState('')
+  const [submitWorkers, setSubmitWorkers] = useState('')
+  const [submitMaxAttempts, setSubmitMaxAttempts] = useState('')
+  const [sentinelStats, setSentinelStats] = useState<SentinelPoolStats | null>(null)
+  const [clientStats, setClientStats] = useState<ClientPoolStats | null>(null)
   const [loading, setLoading] = useState(true)
   const [saving, setSaving] = useState(false)
   const [testing, setTesting] = useState(false)
@@ -37,15 +53,29 @@ export default function Settings() {
     let canceled = false
     void (async () => {
       try {
-        const [settingsResult, versionResult] = await Promise.allSettled([getSettings(), getVersion()])
+        const [settingsResult, versionResult, sentinelResult, clientResult] = await Promise.allSettled([getSettings(), getVersion(), getSentinelPoolStats(), getClientPoolStats()])
         if (canceled) return
 
         if (settingsResult.status === 'fulfilled') {
           const data = settingsResult.value.data
           setProxyUrl(data.proxy_url || '')
+          setProxyCheckInterval(data.proxy_check_interval || '5m')
           setTokenRefreshInterval(data.token_refresh_interval || '30m')
+          setTokenRefreshAhead(data.token_refresh_ahead || '1h')
+          setTokenExpiringSoon(data.token_expiring_soon || '24h')
           setCreditSyncInterval(data.credit_sync_interval || '10m')
           setSubscriptionSyncInterval(data.subscription_sync_interval || '6h')
+          setSentinelPoolSize(data.sentinel_pool_size || '2')
+          setSentinelTTL(data.sentinel_ttl || '5m')
+          setFingerprintProfiles(data.fingerprint_profiles || '')
+          setMaxUploadSize(data.max_upload_size || '100')
+          setMaxDownloadSize(data.max_download_size || '500')
+          setMediaConnectTimeout(data.media_connect_timeout || '30s')
+          setMediaReadTimeout(data.media_read_timeout || '60s')
+          setAccountRateLimit(data.account_rate_limit || '0')
+          setAccountRateBurst(data.account_rate_burst || '5')
+          setSubmitWorkers(data.submit_workers || '4')
+          setSubmitMaxAttempts(data.submit_max_attempts || '3')
         } else {
           setMessage({ type: 'error', text: '加载设置失败' })
         }
@@ -53,6 +83,14 @@ export default function Settings() {
         if (versionResult.status === 'fulfilled') {
           setVersionInfo(versionResult.value.data)
         }
+
+        if (sentinelResult.status === 'fulfilled') {
+          setSentinelStats(sentinelResult.value.data)
+        }
+
+        if (clientResult.status === 'fulfilled') {
+          setClientStats(clientResult.value.data)
+        }
       } finally {
         if (!canceled) setLoading(false)
       }
@@ -77,13 +115,28 @@ export default function Settings() {
     try {
       await updateSettings({
         proxy_url: proxyUrl,
+        proxy_check_interval: proxyCheckInterval,
         token_refresh_interval: tokenRefreshInterval,
+        token_refresh_ahead: tokenRefreshAhead,
+        token_expiring_soon: tokenExpiringSoon,
         credit_sync_interval: creditSyncInterval,
         subscription_sync_interval: subscriptionSyncInterval,
+        sentinel_pool_size: sentinelPoolSize,
+        sentinel_ttl: sentinelTTL,
+        fingerprint_profiles: fingerprintProfiles,
+        max_upload_size: maxUploadSize,
+        max_download_size: maxDownloadSize,
+        media_connect_timeout: mediaConnectTimeout,
+        media_read_timeout: mediaReadTimeout,
+        account_rate_limit: accountRateLimit,
+        account_rate_burst: accountRateBurst,
+        submit_workers: submitWorkers,
+        submit_max_attempts: submitMaxAttempts,
       })
       setMessage({ type: 'success', text: '设置已保存' })
-    } catch {
-      setMessage({ type: 'error', text: '保存失败' })
+    } catch (err: unknown) {
+      const msg = (err as { response?: { data?: { error?: string } } })?.response?.data?.error
+      setMessage({ type: 'error', text: msg || '保存失败' })
     }
     setSaving(false)
   }
@@ -214,7 +267,7 @@ export default function Settings() {
               <div>
                 <h3 className="text-sm font-semibold" style={{ color: 'var(--text-primary)' }}>代理地址</h3>
                 <p className="text-xs mt-0.5" style={{ color: 'var(--text-tertiary)' }}>
-                  全局 HTTP/SOCKS5 代理，所有 Sora API 请求通过此代理。留空直连。
+                  全局 HTTP/SOCKS5 代理，代理池中没有可用代理时使用。留空直连。%s 占位符按账号替换为固定 session。
                 </p>
               </div>
             </div>
@@ -267,6 +320,12 @@ export default function Settings() {
                 )}
               </AnimatePresence>
             </div>
+
+            {clientStats && (
+              <p className="text-xs mt-3" style={{ color: 'var(--text-tertiary)' }}>
+                客户端复用 {clientStats.hits} · 新建 {clientStats.misses} · 复用率 {(clientStats.hit_rate * 100).toFixed(1)}% · 缓存 {clientStats.clients} 个 · 首个请求平均 {clientStats.cold_avg_ms.toFixed(0)}ms · 复用连接平均 {clientStats.reused_avg_ms.toFixed(0)}ms
+              </p>
+            )}
           </div>
         </GlassCard>
 
@@ -295,7 +354,7 @@ export default function Settings() {
             <div className="grid grid-cols-1 sm:grid-cols-3 gap-4">
               <div>
                 <label className="block text-[13px] font-medium mb-1.5" style={{ color: 'var(--text-secondary)' }}>
-                  Token 刷新
+                  Token 刷新（兜底）
                 </label>
                 <input
                   type="text"
@@ -339,6 +398,304 @@ export default function Settings() {
                 />
               </div>
             </div>
+
+            <div className="grid grid-cols-1 sm:grid-cols-2 gap-4 mt-4">
+              <div>
+                <label className="block text-[13px] font-medium mb-1.5" style={{ color: 'var(--text-secondary)' }}>
+                  过期前刷新
+                </label>
+                <input
+                  type="text"
+                  value={tokenRefreshAhead}
+                  onChange={(e) => setTokenRefreshAhead(e.target.value)}
+                  placeholder="1h"
+                  className="w-full px-3.5 py-2.5 text-sm outline-none transition-all"
+                  style={inputStyle}
+                  onFocus={inputFocus}
+                  onBlur={inputBlur}
+                />
+                <p className="text-xs mt-1" style={{ color: 'var(--text-tertiary)' }}>有 RT 的账号在 AT 过期前多久刷新（另加随机抖动）</p>
+              </div>
+              <div>
+                <label className="block text-[13px] font-medium mb-1.5" style={{ color: 'var(--text-secondary)' }}>
+                  即将过期提醒
+                </label>
+                <input
+                  type="text"
+                  value={tokenExpiringSoon}
+                  onChange={(e) => setTokenExpiringSoon(e.target.value)}
+                  placeholder="24h"
+                  className="w-full px-3.5 py-2.5 text-sm outline-none transition-all"
+                  style={inputStyle}
+                  onFocus={inputFocus}
+                  onBlur={inputBlur}
+                />
+                <p className="text-xs mt-1" style={{ color: 'var(--text-tertiary)' }}>仅有 AT 的账号在过期前多久标记为即将过期</p>
+              </div>
+              <div>
+                <label className="block text-[13px] font-medium mb-1.5" style={{ color: 'var(--text-secondary)' }}>
+                  代理健康检查
+                </label>
+                <input
+                  type="text"
+                  value={proxyCheckInterval}
+                  onChange={(e) => setProxyCheckInterval(e.target.value)}
+                  placeholder="5m"
+                  className="w-full px-3.5 py-2.5 text-sm outline-none transition-all"
+                  style={inputStyle}
+                  onFocus={inputFocus}
+                  onBlur={inputBlur}
+                />
+                <p className="text-xs mt-1" style={{ color: 'var(--text-tertiary)' }}>代理池连通性检查间隔，最短 30s</p>
+              </div>
+            </div>
+          </div>
+        </GlassCard>
+
+        {/* Sentinel 预热池 */}
+        <GlassCard delay={3} className="overflow-hidden">
+          <div className="p-5 sm:p-6">
+            <div className="flex items-start gap-3 mb-4">
+              <div
+                className="w-9 h-9 rounded-xl flex items-center justify-center flex-shrink-0 mt-0.5"
+                style={{ background: 'var(--accent-soft)' }}
+              >
+                <svg width="16" height="16" viewBox="0 0 24 24" fill="none" stroke="var(--accent)" strokeWidth="2" strokeLinecap="round" strokeLinejoin="round">
+                  <polygon points="13 2 3 14 12 14 11 22 21 10 12 10 13 2" />
+                </svg>
+              </div>
+              <div>
+                <h3 className="text-sm font-semibold" style={{ color: 'var(--text-primary)' }}>Sentinel 预热池</h3>
+                <p className="text-xs mt-0.5" style={{ color: 'var(--text-tertiary)' }}>
+                  后台为每个账号预先生成 Sentinel Token，提交任务时直接取用。数量设为 0 关闭。
+                </p>
+              </div>
+            </div>
+
+            <div className="grid grid-cols-1 sm:grid-cols-2 gap-4">
+              <div>
+                <label className="block text-[13px] font-medium mb-1.5" style={{ color: 'var(--text-secondary)' }}>
+                  每账号预热数量
+                </label>
+                <input
+                  type="text"
+                  value={sentinelPoolSize}
+                  onChange={(e) => setSentinelPoolSize(e.target.value)}
+                  placeholder="2"
+                  className="w-full px-3.5 py-2.5 text-sm outline-none transition-all"
+                  style={inputStyle}
+                  onFocus={inputFocus}
+                  onBlur={inputBlur}
+                />
+              </div>
+              <div>
+                <label className="block text-[13px] font-medium mb-1.5" style={{ color: 'var(--text-secondary)' }}>
+                  有效期
+                </label>
+                <input
+                  type="text"
+                  value={sentinelTTL}
+                  onChange={(e) => setSentinelTTL(e.target.value)}
+                  placeholder="5m"
+                  className="w-full px-3.5 py-2.5 text-sm outline-none transition-all"
+                  style={inputStyle}
+                  onFocus={inputFocus}
+                  onBlur={inputBlur}
+                />
+              </div>
+            </div>
+
+            {sentinelStats && (
+              <p className="text-xs mt-3" style={{ color: 'var(--text-tertiary)' }}>
+                命中 {sentinelStats.hits} · 未命中 {sentinelStats.misses} · 命中率 {(sentinelStats.hit_rate * 100).toFixed(1)}% · 池中 {sentinelStats.pooled} 个（{sentinelStats.accounts} 个账号）· 已作废 {sentinelStats.invalidated}
+              </p>
+            )}
+          </div>
+        </GlassCard>
+
+        {/* 上传/下载与限流 */}
+        <GlassCard delay={4} className="overflow-hidden">
+          <div className="p-5 sm:p-6">
+            <div className="flex items-start gap-3 mb-4">
+              <div
+                className="w-9 h-9 rounded-xl flex items-center justify-center flex-shrink-0 mt-0.5"
+                style={{ background: 'var(--success-soft)' }}
+              >
+                <svg width="16" height="16" viewBox="0 0 24 24" fill="none" stroke="var(--success)" strokeWidth="2" strokeLinecap="round" strokeLinejoin="round">
+                  <path d="M21 15v4a2 2 0 01-2 2H5a2 2 0 01-2-2v-4" />
+                  <polyline points="17 8 12 3 7 8" />
+                  <line x1="12" y1="3" x2="12" y2="15" />
+                </svg>
+              </div>
+              <div>
+                <h3 className="text-sm font-semibold" style={{ color: 'var(--text-primary)' }}>上游传输与限流</h3>
+                <p className="text-xs mt-0.5" style={{ color: 'var(--text-tertiary)' }}>
+                  素材上传和媒体下载均以流式传输并走账号的代理，单个文件超过上限时返回 413，上限为 0 表示不限制。
+                  连接超时为等待响应头的时长，读取超时为两次收到数据的最大间隔（超时后断点续传）。
+                  单账号每秒请求数限制同一账号请求上游的速率（超出时排队），0 为不限制。
+                  任务提交后先入队，由后台按提交并发数选取账号并提交到上游。
+                  提交因 Token 失效、限流或 Sentinel 错误失败时自动换账号重试，最多尝试账号数次。
+                </p>
+              </div>
+            </div>
+            <div className="grid grid-cols-1 sm:grid-cols-2 gap-4">
+              <div>
+                <label className="block text-[13px] font-medium mb-1.5" style={{ color: 'var(--text-secondary)' }}>
+                  上传大小上限（MB）
+                </label>
+                <input
+                  type="text"
+                  value={maxUploadSize}
+                  onChange={(e) => setMaxUploadSize(e.target.value)}
+                  placeholder="100"
+                  className="w-full px-3.5 py-2.5 text-sm outline-none transition-all"
+                  style={inputStyle}
+                  onFocus={inputFocus}
+                  onBlur={inputBlur}
+                />
+              </div>
+              <div>
+                <label className="block text-[13px] font-medium mb-1.5" style={{ color: 'var(--text-secondary)' }}>
+                  下载大小上限（MB）
+                </label>
+                <input
+                  type="text"
+                  value={maxDownloadSize}
+                  onChange={(e) => setMaxDownloadSize(e.target.value)}
+                  placeholder="500"
+                  className="w-full px-3.5 py-2.5 text-sm outline-none transition-all"
+                  style={inputStyle}
+                  onFocus={inputFocus}
+                  onBlur={inputBlur}
+                />
+              </div>
+              <div>
+                <label className="block text-[13px] font-medium mb-1.5" style={{ color: 'var(--text-secondary)' }}>
+                  下载连接超时
+                </label>
+                <input
+                  type="text"
+                  value={mediaConnectTimeout}
+                  onChange={(e) => setMediaConnectTimeout(e.target.value)}
+                  placeholder="30s"
+                  className="w-full px-3.5 py-2.5 text-sm outline-none transition-all"
+                  style={inputStyle}
+                  onFocus={inputFocus}
+                  onBlur={inputBlur}
+                />
+              </div>
+              <div>
+                <label className="block text-[13px] font-medium mb-1.5" style={{ color: 'var(--text-secondary)' }}>
+                  下载读取超时
+                </label>
+                <input
+                  type="text"
+                  value={mediaReadTimeout}
+                  onChange={(e) => setMediaReadTimeout(e.target.value)}
+                  placeholder="60s"
+                  className="w-full px-3.5 py-2.5 text-sm outline-none transition-all"
+                  style={inputStyle}
+                  onFocus={inputFocus}
+                  onBlur={inputBlur}
+                />
+              </div>
+            </div>
+
+            <div className="grid grid-cols-1 sm:grid-cols-3 gap-4 mt-4">
+              <div>
+                <label className="block text-[13px] font-medium mb-1.5" style={{ color: 'var(--text-secondary)' }}>
+                  单账号每秒请求数
+                </label>
+                <input
+                  type="text"
+                  value={accountRateLimit}
+                  onChange={(e) => setAccountRateLimit(e.target.value)}
+                  placeholder="0"
+                  className="w-full px-3.5 py-2.5 text-sm outline-none transition-all"
+                  style={inputStyle}
+                  onFocus={inputFocus}
+                  onBlur={inputBlur}
+                />
+              </div>
+              <div>
+                <label className="block text-[13px] font-medium mb-1.5" style={{ color: 'var(--text-secondary)' }}>
+                  单账号突发请求数
+                </label>
+                <input
+                  type="text"
+                  value={accountRateBurst}
+                  onChange={(e) => setAccountRateBurst(e.target.value)}
+                  placeholder="5"
+                  className="w-full px-3.5 py-2.5 text-sm outline-none transition-all"
+                  style={inputStyle}
+                  onFocus={inputFocus}
+                  onBlur={inputBlur}
+                />
+              </div>
+              <div>
+                <label className="block text-[13px] font-medium mb-1.5" style={{ color: 'var(--text-secondary)' }}>
+                  后台提交并发数
+                </label>
+                <input
+                  type="text"
+                  value={submitWorkers}
+                  onChange={(e) => setSubmitWorkers(e.target.value)}
+                  placeholder="4"
+                  className="w-full px-3.5 py-2.5 text-sm outline-none transition-all"
+                  style={inputStyle}
+                  onFocus={inputFocus}
+                  onBlur={inputBlur}
+                />
+              </div>
+              <div>
+                <label className="block text-[13px] font-medium mb-1.5" style={{ color: 'var(--text-secondary)' }}>
+                  提交最多尝试账号数
+                </label>
+                <input
+                  type="text"
+                  value={submitMaxAttempts}
+                  onChange={(e) => setSubmitMaxAttempts(e.target.value)}
+                  placeholder="3"
+                  className="w-full px-3.5 py-2.5 text-sm outline-none transition-all"
+                  style={inputStyle}
+                  onFocus={inputFocus}
+                  onBlur={inputBlur}
+                />
+              </div>
+            </div>
+          </div>
+        </GlassCard>
+
+        {/* PoW 指纹 */}
+        <GlassCard delay={5} className="overflow-hidden">
+          <div className="p-5 sm:p-6">
+            <div className="flex items-start gap-3 mb-4">
+              <div
+                className="w-9 h-9 rounded-xl flex items-center justify-center flex-shrink-0 mt-0.5"
+                style={{ background: 'var(--info-soft)' }}
+              >
+                <svg width="16" height="16" viewBox="0 0 24 24" fill="none" stroke="var(--info)" strokeWidth="2" strokeLinecap="round" strokeLinejoin="round">
+                  <rect x="3" y="4" width="18" height="12" rx="2" />
+                  <line x1="8" y1="20" x2="16" y2="20" />
+                  <line x1="12" y1="16" x2="12" y2="20" />
+                </svg>
+              </div>
+              <div>
+                <h3 className="text-sm font-semibold" style={{ color: 'var(--text-primary)' }}>PoW 浏览器指纹</h3>
+                <p className="text-xs mt-0.5" style={{ color: 'var(--text-tertiary)' }}>
+                  JSON 数组，字段：name、weight、scripts、dpl、navigator_keys、screens、cores、utc_offset、timezone_name。
+                  每个账号按权重固定分配一个 profile，空字段使用内置默认值。留空使用内置指纹。
+                </p>
+              </div>
+            </div>
+            <textarea
+              value={fingerprintProfiles}
+              onChange={(e) => setFingerprintProfiles(e.target.value)}
+              placeholder='[{"name": "chrome-a", "weight": 2, "dpl": ["prod-xxx"]}]'
+              rows={6}
+              className="w-full px-3.5 py-2.5 text-sm font-mono outline-none transition-all"
+              style={inputStyle}
+            />
           </div>
         </GlassCard>
       </div>
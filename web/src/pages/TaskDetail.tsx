@@ -28,7 +28,7 @@ export default function TaskDetail() {
         const res = await getTask(id)
         setTask(res.data)
         // 进行中的任务自动轮询
-        if (res.data.status === 'queued' || res.data.status === 'in_progress') {
+        if (res.data.status === 'queued' || res.data.status === 'submitting' || res.data.status === 'in_progress') {
           timer = setInterval(async () => {
             try {
               const r = await getTask(id)
@@ -304,8 +304,44 @@ export default function TaskDetail() {
             </GlassCard>
           )}
 
-          {task.error_message && (
+          {task.attempts && task.attempts.length > 0 && (
             <GlassCard delay={3} className="overflow-hidden">
+              <div className="p-5">
+                <h3 className="text-xs font-semibold uppercase tracking-wider mb-3" style={{ color: 'var(--text-tertiary)' }}>
+                  提交记录
+                </h3>
+                <div className="space-y-2">
+                  {task.attempts.map((a, i) => (
+                    <div
+                      key={i}
+                      className="text-[13px] p-3 rounded-xl"
+                      style={{ background: 'var(--bg-inset)', border: '1px solid var(--border-subtle)' }}
+                    >
+                      <div className="flex items-center justify-between gap-2">
+                        <span className="truncate" style={{ color: 'var(--text-secondary)' }}>
+                          #{a.account_id} {a.email}
+                        </span>
+                        <span className="flex-shrink-0" style={{ color: a.error ? 'var(--danger)' : 'var(--success)' }}>
+                          {a.error ? '失败' : '成功'}
+                        </span>
+                      </div>
+                      <div className="text-xs mt-1" style={{ color: 'var(--text-tertiary)' }}>
+                        {format(new Date(a.at), 'yyyy-MM-dd HH:mm:ss', { locale: zhCN })}
+                      </div>
+                      {a.error && (
+                        <div className="text-xs mt-1 break-all" style={{ color: 'var(--danger)' }}>
+                          {a.error}
+                        </div>
+                      )}
+                    </div>
+                  ))}
+                </div>
+              </div>
+            </GlassCard>
+          )}
+
+          {task.error_message && (
+            <GlassCard delay={4} className="overflow-hidden">
               <div className="p-5">
                 <h3 className="text-xs font-semibold uppercase tracking-wider mb-3" style={{ color: 'var(--danger)' }}>
                   错误信息
@@ -11,6 +11,7 @@ import { zhCN } from 'date-fns/locale'
 
 const statusFilters = [
   { label: '全部', value: '' },
+  { label: '排队中', value: 'queued' },
   { label: '进行中', value: 'in_progress' },
   { label: '已完成', value: 'completed' },
   { label: '失败', value: 'failed' },
@@ -52,7 +53,7 @@ export default function TaskList() {
 
   // 自动刷新
   useEffect(() => {
-    if (status === '' || status === 'in_progress') {
+    if (status === '' || status === 'queued' || status === 'in_progress') {
       const timer = setInterval(async () => {
         try {
           const res = await listTasks({ status: status || undefined, type: taskType || undefined, page, page_size: pageSize })
@@ -3,6 +3,7 @@ export interface SoraAccountGroup {
   name: string
   description: string
   enabled: boolean
+  max_concurrent: number
   account_count: number
   created_at: string
   updated_at: string
@@ -12,6 +13,8 @@ export interface SoraAccount {
   id: number
   group_id: number | null
   group_name: string
+  proxy_id: number | null
+  proxy_name?: string
   name: string
   email: string
   at_hint: string
@@ -23,7 +26,9 @@ export interface SoraAccount {
   rate_limit_reached: boolean
   rate_limit_resets_at: string | null
   enabled: boolean
-  status: 'active' | 'token_expired' | 'quota_exhausted'
+  status: 'active' | 'expiring_soon' | 'token_expired' | 'quota_exhausted'
+  max_concurrent: number
+  in_flight: number
   last_used_at: string | null
   last_error: string
   last_sync_at: string | null
@@ -36,13 +41,39 @@ export interface CreateAccountRequest {
   access_token?: string
   refresh_token?: string
   group_id?: number | null
+  proxy_id?: number | null
   enabled?: boolean
+  max_concurrent?: number
 }
 
 export interface CreateGroupRequest {
   name: string
   description?: string
   enabled?: boolean
+  max_concurrent?: number
+}
+
+export interface SoraProxy {
+  id: number
+  name: string
+  url: string
+  group_id: number | null
+  group_name?: string
+  enabled: boolean
+  healthy: boolean
+  latency_ms: number
+  last_error: string
+  last_check_at: string | null
+  account_count: number
+  created_at: string
+  updated_at: string
+}
+
+export interface CreateProxyRequest {
+  name: string
+  url: string
+  group_id?: number | null
+  enabled?: boolean
 }
 
 export interface SoraAPIKey {
@@ -1,4 +1,7 @@
-export type CharacterStatus = 'processing' | 'ready' | 'failed'
+export type CharacterStatus = 'processing' | 'ready' | 'failed' | 'missing'
+
+/** 处理流程最后完成的步骤 */
+export type CharacterStep = 'uploaded' | 'cameo_ready' | 'avatar_downloaded' | 'avatar_uploaded' | 'finalized' | 'visibility_set'
 
 export interface SoraCharacter {
   id: string
@@ -10,9 +13,17 @@ export interface SoraCharacter {
   username: string
   profile_url: string
   is_public: boolean
+  step?: CharacterStep
   error_message: string
   account_email: string
   created_at: string
   updated_at: string
   completed_at: string | null
 }
+
+export interface CharacterSyncResult {
+  total: number
+  imported: number
+  updated: number
+  missing: number
+}
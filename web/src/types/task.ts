@@ -1,4 +1,11 @@
-export type TaskStatus = 'queued' | 'in_progress' | 'completed' | 'failed'
+export type TaskStatus = 'queued' | 'submitting' | 'in_progress' | 'completed' | 'failed'
+
+export interface TaskAttempt {
+  account_id: number
+  email?: string
+  error?: string
+  at: string
+}
 
 export interface SoraTask {
   id: string
@@ -11,6 +18,8 @@ export interface SoraTask {
   progress: number
   error_message: string
   image_url: string
+  images?: { url: string; generation_id?: string }[]
+  attempts?: TaskAttempt[]
   created_at: string
   updated_at: string
   completed_at: string | null